@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
@@ -24,6 +27,32 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// ReloadRequest is called by the management API to trigger a reload of
+	// the configuration. Reloading via the API is not available if unset.
+	ReloadRequest func()
+
+	// running contains the units of the agent started by Run and is required
+	// to exchange plugins while the agent is running.
+	running    *runningUnits
+	reloadLock sync.Mutex
+
+	// pipelines are the agents of the running pipelines, managed by the API
+	// next to the top-level plugins
+	pipelines []*Agent
+
+	// parent is the agent running the top-level plugins if this agent runs
+	// a pipeline
+	parent *Agent
+}
+
+// runningUnits are the units of an agent started by Run.
+type runningUnits struct {
+	ctx       context.Context
+	startTime time.Time
+	inputs    *inputUnit
+	pipeline  *pipelineUnit
+	outputs   *outputUnit
 }
 
 // NewAgent returns an Agent for the given Config.
@@ -48,6 +77,22 @@ func NewAgent(cfg *config.Config) *Agent {
 type inputUnit struct {
 	dst    chan<- telegraf.Metric
 	inputs []*models.RunningInput
+
+	// Gather loops of the inputs, only used when running the agent
+	sync.Mutex
+	tasks  map[*models.RunningInput]*pluginTask
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// pluginTask is the loop of a single plugin which can be stopped individually.
+type pluginTask struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// Controls for the management API
+	flush  chan struct{}
+	paused atomic.Bool
 }
 
 //  ______     ┌───────────┐     ______
@@ -99,6 +144,48 @@ type aggregatorUnit struct {
 type outputUnit struct {
 	src     <-chan telegraf.Metric
 	outputs []*models.RunningOutput
+	router  *models.Router
+
+	// Flush loops of the outputs
+	sync.RWMutex
+	tasks  map[*models.RunningOutput]*pluginTask
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// stageUnit is the chain of processors and aggregators sitting between the
+// inputs and the outputs of a running agent. The stage writes all metrics
+// to its tail channel which is forwarded to the destination.
+
+//  ______     ┌───────────┐     ┌────────────┐     ┌───────────┐     ______
+// ()_____)──▶ │ Processor │──▶ │ Aggregator │──▶ │ Processor │──▶ ()_____)
+//             └───────────┘     └────────────┘     └───────────┘
+
+type stageUnit struct {
+	src  chan<- telegraf.Metric
+	tail <-chan telegraf.Metric
+	apu  []*processorUnit
+	au   *aggregatorUnit
+	pu   []*processorUnit
+	done chan struct{}
+}
+
+// pipelineUnit forwards the metrics of the inputs to the current stage and
+// the output of the stage(s) to the outputs. This allows to replace the stage
+// while the agent is running.
+
+//  ______     ┌───────┐     ______
+// ()_____)──▶ │ Stage │──▶ ()_____)
+//             └───────┘
+
+type pipelineUnit struct {
+	src <-chan telegraf.Metric
+	dst chan<- telegraf.Metric
+
+	sync.RWMutex
+	stage  *stageUnit
+	closed bool
+	wg     sync.WaitGroup
 }
 
 // Run starts and runs the Agent until the context is done.
@@ -135,78 +222,81 @@ func (a *Agent) Run(ctx context.Context) error {
 		}
 	}
 
+	// Open the management API early to fail before starting any plugin
+	var apiListener net.Listener
+	if a.Config.Agent.APIAddress != "" {
+		listener, err := listenAPI(a.Config.Agent.APIAddress, &a.Config.Agent.APIToken)
+		if err != nil {
+			return fmt.Errorf("starting management API failed: %w", err)
+		}
+		defer listener.Close()
+		apiListener = listener
+	}
+
 	startTime := time.Now()
 
-	log.Printf("D! [agent] Connecting outputs")
-	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	running, err := a.startUnits(ctx, startTime)
 	if err != nil {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		aggC := next
-		if len(a.Config.AggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
-			aggC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
+	// Start the independent pipelines next to the top-level plugins
+	pipelines := make([]*Agent, 0, len(a.Config.Pipelines))
+	pipelineUnits := make([]*runningUnits, 0, len(a.Config.Pipelines))
+	for _, p := range a.Config.Pipelines {
+		log.Printf("D! [agent] Starting pipeline %q", p.Name)
+		pa := a.pipelineAgent(p)
+		r, err := pa.startUnits(ctx, startTime)
+		if err != nil {
+			stopUnits(running)
+			for _, r := range pipelineUnits {
+				stopUnits(r)
 			}
+			return fmt.Errorf("starting pipeline %q failed: %w", p.Name, err)
 		}
-
-		next, au = a.startAggregators(aggC, next, a.Config.Aggregators)
+		pipelines = append(pipelines, pa)
+		pipelineUnits = append(pipelineUnits, r)
 	}
 
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
-	}
+	a.warnOrphanedDumps()
 
-	iu, err := a.startInputs(next, a.Config.Inputs)
-	if err != nil {
-		return err
+	a.reloadLock.Lock()
+	a.running = running
+	for i, pa := range pipelines {
+		pa.running = pipelineUnits[i]
 	}
+	a.pipelines = pipelines
+	a.reloadLock.Unlock()
 
 	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		a.runOutputs(ou)
-	}()
-
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(apu)
-		}()
+	a.runUnits(&wg, running)
+	for i, pa := range pipelines {
+		pa.runUnits(&wg, pipelineUnits[i])
+	}
 
+	if a.Config.Persister != nil && a.Config.Agent.StatefileCheckpointInterval > 0 {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			a.runAggregators(startTime, au)
+			a.checkpointLoop(ctx, time.Duration(a.Config.Agent.StatefileCheckpointInterval))
 		}()
 	}
 
-	if pu != nil {
+	if apiListener != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			a.runProcessors(pu)
+			a.serveAPI(ctx, apiListener)
 		}()
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		a.runInputs(ctx, startTime, iu)
-	}()
-
 	wg.Wait()
 
+	a.reloadLock.Lock()
+	a.running = nil
+	a.pipelines = nil
+	a.reloadLock.Unlock()
+
 	if a.Config.Persister != nil {
 		log.Printf("D! [agent] Persisting plugin states")
 		if err := a.Config.Persister.Store(); err != nil {
@@ -218,6 +308,89 @@ func (a *Agent) Run(ctx context.Context) error {
 	return err
 }
 
+// startUnits connects the outputs and starts the processors, aggregators and
+// inputs of the agent.
+func (a *Agent) startUnits(ctx context.Context, startTime time.Time) (*runningUnits, error) {
+	log.Printf("D! [agent] Connecting outputs")
+	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	stage, err := a.startStage(a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators)
+	if err != nil {
+		stopRunningOutputs(ou.outputs)
+		return nil, err
+	}
+	src := make(chan telegraf.Metric, 100)
+	pu := &pipelineUnit{
+		src:   src,
+		dst:   next,
+		stage: stage,
+	}
+
+	iu, err := a.startInputs(src, a.Config.Inputs)
+	if err != nil {
+		stopRunningOutputs(ou.outputs)
+		return nil, err
+	}
+
+	return &runningUnits{
+		ctx:       ctx,
+		startTime: startTime,
+		inputs:    iu,
+		pipeline:  pu,
+		outputs:   ou,
+	}, nil
+}
+
+// runUnits runs the started units until the context is done
+func (a *Agent) runUnits(wg *sync.WaitGroup, r *runningUnits) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runOutputs(r.outputs)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runPipeline(r.startTime, r.pipeline)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runInputs(r.ctx, r.startTime, r.inputs)
+	}()
+}
+
+// stopUnits stops the service inputs and outputs of started units not
+// running yet.
+func stopUnits(r *runningUnits) {
+	stopRunningInputs(r.inputs.inputs)
+	stopRunningOutputs(r.outputs.outputs)
+}
+
+// checkpointLoop periodically writes the state of the plugins to the
+// state-file until the context is done.
+func (a *Agent) checkpointLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Printf("D! [agent] Checkpointing plugin states")
+			if err := a.Config.Persister.Store(); err != nil {
+				log.Printf("E! [agent] Checkpointing plugin states failed: %v", err)
+			}
+		}
+	}
+}
+
 // InitPlugins runs the Init function on plugins.
 func (a *Agent) InitPlugins() error {
 	for _, input := range a.Config.Inputs {
@@ -256,6 +429,34 @@ func (a *Agent) InitPlugins() error {
 			return fmt.Errorf("could not initialize output %s: %w", output.LogName(), err)
 		}
 	}
+	if a.Config.Router != nil {
+		if err := initRouter(a.Config.Router, a.Config.Outputs); err != nil {
+			return err
+		}
+	}
+	for _, p := range a.Config.Pipelines {
+		if err := a.pipelineAgent(p).InitPlugins(); err != nil {
+			return fmt.Errorf("pipeline %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// initRouter initializes the router and warns about routes to unknown outputs
+func initRouter(router *models.Router, outputs []*models.RunningOutput) error {
+	if err := router.Init(); err != nil {
+		return fmt.Errorf("could not initialize routing: %w", err)
+	}
+
+	known := make(map[string]bool, len(outputs))
+	for _, output := range outputs {
+		known[output.Config.Alias] = true
+	}
+	for alias := range router.Aliases() {
+		if !known[alias] {
+			log.Printf("W! [agent] Routing references output alias %q not used by any output", alias)
+		}
+	}
 	return nil
 }
 
@@ -265,6 +466,19 @@ func (a *Agent) initPersister() error {
 		return err
 	}
 
+	if err := a.registerPlugins(); err != nil {
+		return err
+	}
+	for _, p := range a.Config.Pipelines {
+		if err := a.pipelineAgent(p).registerPlugins(); err != nil {
+			return fmt.Errorf("pipeline %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// registerPlugins registers the stateful plugins at the persister.
+func (a *Agent) registerPlugins() error {
 	for _, input := range a.Config.Inputs {
 		plugin, ok := input.Input.(telegraf.StatefulPlugin)
 		if !ok {
@@ -349,45 +563,57 @@ func (*Agent) startInputs(dst chan<- telegraf.Metric, inputs []*models.RunningIn
 	}
 
 	for _, input := range inputs {
-		// Service input plugins are not normally subject to timestamp
-		// rounding except for when precision is set on the input plugin.
-		//
-		// This only applies to the accumulator passed to Start(), the
-		// Gather() accumulator does apply rounding according to the
-		// precision and interval agent/plugin settings.
-		var interval time.Duration
-		var precision time.Duration
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
-
-		acc := NewAccumulator(input, dst)
-		acc.SetPrecision(getPrecision(precision, interval))
-
-		if err := input.Start(acc); err != nil {
-			// If the model tells us to remove the plugin we do so without error
-			var fatalErr *internal.FatalError
-			if errors.As(err, &fatalErr) {
-				log.Printf("I! [agent] Failed to start %s, shutting down plugin: %s", input.LogName(), err)
-				continue
-			}
-
+		started, err := startInput(dst, input)
+		if err != nil {
 			stopRunningInputs(unit.inputs)
 
-			return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
+			return nil, err
 		}
-		if err := input.Probe(); err != nil {
-			// Probe failures are non-fatal to the agent but should only remove the plugin
-			log.Printf("I! [agent] Failed to probe %s, shutting down plugin: %s", input.LogName(), err)
-			input.Stop()
-			continue
+		if started {
+			unit.inputs = append(unit.inputs, input)
 		}
-		unit.inputs = append(unit.inputs, input)
 	}
 
 	return unit, nil
 }
 
+// startInput starts the given service input and probes the plugin if requested.
+// The function returns false if the plugin should be removed.
+func startInput(dst chan<- telegraf.Metric, input *models.RunningInput) (bool, error) {
+	// Service input plugins are not normally subject to timestamp
+	// rounding except for when precision is set on the input plugin.
+	//
+	// This only applies to the accumulator passed to Start(), the
+	// Gather() accumulator does apply rounding according to the
+	// precision and interval agent/plugin settings.
+	var interval time.Duration
+	var precision time.Duration
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	acc := NewAccumulator(input, dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	if err := input.Start(acc); err != nil {
+		// If the model tells us to remove the plugin we do so without error
+		var fatalErr *internal.FatalError
+		if errors.As(err, &fatalErr) {
+			log.Printf("I! [agent] Failed to start %s, shutting down plugin: %s", input.LogName(), err)
+			return false, nil
+		}
+
+		return false, fmt.Errorf("starting input %s: %w", input.LogName(), err)
+	}
+	if err := input.Probe(); err != nil {
+		// Probe failures are non-fatal to the agent but should only remove the plugin
+		log.Printf("I! [agent] Failed to probe %s, shutting down plugin: %s", input.LogName(), err)
+		input.Stop()
+		return false, nil
+	}
+	return true, nil
+}
+
 // runInputs starts and triggers the periodic gather for Inputs.
 //
 // When the context is done the timers are stopped and this function returns
@@ -397,58 +623,102 @@ func (a *Agent) runInputs(
 	startTime time.Time,
 	unit *inputUnit,
 ) {
-	var wg sync.WaitGroup
-	tickers := make([]Ticker, 0, len(unit.inputs))
+	unit.Lock()
+	unit.tasks = make(map[*models.RunningInput]*pluginTask, len(unit.inputs))
 	for _, input := range unit.inputs {
-		// Overwrite agent interval if this plugin has its own.
-		interval := time.Duration(a.Config.Agent.Interval)
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
+		a.startGatherLoop(ctx, startTime, unit, input)
+	}
+	unit.Unlock()
 
-		// Overwrite agent precision if this plugin has its own.
-		precision := time.Duration(a.Config.Agent.Precision)
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
+	<-ctx.Done()
 
-		// Overwrite agent collection_jitter if this plugin has its own.
-		jitter := time.Duration(a.Config.Agent.CollectionJitter)
-		if input.Config.CollectionJitter != 0 {
-			jitter = input.Config.CollectionJitter
-		}
+	unit.Lock()
+	unit.closed = true
+	unit.Unlock()
 
-		// Overwrite agent collection_offset if this plugin has its own.
-		offset := time.Duration(a.Config.Agent.CollectionOffset)
-		if input.Config.CollectionOffset != 0 {
-			offset = input.Config.CollectionOffset
-		}
+	log.Printf("D! [agent] Stopping service inputs")
+	unit.wg.Wait()
 
-		var ticker Ticker
-		if a.Config.Agent.RoundInterval {
-			ticker = NewAlignedTicker(startTime, interval, jitter, offset)
-		} else {
-			ticker = NewUnalignedTicker(interval, jitter, offset)
-		}
-		tickers = append(tickers, ticker)
+	close(unit.dst)
+	log.Printf("D! [agent] Input channel closed")
+}
 
-		acc := NewAccumulator(input, unit.dst)
-		acc.SetPrecision(getPrecision(precision, interval))
+// startGatherLoop starts the periodic gather for the given input and stops
+// the input once the gather loop finished. The unit must be locked by the
+// caller.
+func (a *Agent) startGatherLoop(
+	ctx context.Context,
+	startTime time.Time,
+	unit *inputUnit,
+	input *models.RunningInput,
+) {
+	// Overwrite agent interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.Interval)
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
 
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
-		}(input)
+	// Overwrite agent precision if this plugin has its own.
+	precision := time.Duration(a.Config.Agent.Precision)
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
 	}
-	defer stopTickers(tickers)
-	wg.Wait()
 
-	log.Printf("D! [agent] Stopping service inputs")
-	stopRunningInputs(unit.inputs)
+	// Overwrite agent collection_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.CollectionJitter)
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
 
-	close(unit.dst)
-	log.Printf("D! [agent] Input channel closed")
+	// Overwrite agent collection_offset if this plugin has its own.
+	offset := time.Duration(a.Config.Agent.CollectionOffset)
+	if input.Config.CollectionOffset != 0 {
+		offset = input.Config.CollectionOffset
+	}
+
+	var ticker Ticker
+	if a.Config.Agent.RoundInterval {
+		ticker = NewAlignedTicker(startTime, interval, jitter, offset)
+	} else {
+		ticker = NewUnalignedTicker(interval, jitter, offset)
+	}
+
+	acc := NewAccumulator(input, unit.dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	taskCtx, cancel := context.WithCancel(ctx)
+	task := &pluginTask{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	unit.tasks[input] = task
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(task.done)
+
+		a.gatherLoop(taskCtx, acc, input, ticker, interval, &task.paused)
+		ticker.Stop()
+		input.Stop()
+	}()
+}
+
+// stopGatherLoop stops the gather loop of the given input and removes the
+// input from the unit. The function returns after the input was stopped.
+func (*Agent) stopGatherLoop(unit *inputUnit, input *models.RunningInput) {
+	unit.Lock()
+	task, found := unit.tasks[input]
+	if !found || unit.closed {
+		unit.Unlock()
+		return
+	}
+	delete(unit.tasks, input)
+	unit.inputs = slices.DeleteFunc(unit.inputs, func(i *models.RunningInput) bool { return i == input })
+	unit.Unlock()
+
+	task.cancel()
+	<-task.done
 }
 
 // testStartInputs is a variation of startInputs for use in --test and --once mode.
@@ -568,10 +838,14 @@ func (a *Agent) gatherLoop(
 	input *models.RunningInput,
 	ticker Ticker,
 	interval time.Duration,
+	paused *atomic.Bool,
 ) {
 	for {
 		select {
 		case <-ticker.Elapsed():
+			if paused.Load() {
+				continue
+			}
 			err := a.gatherOnce(acc, input, ticker, interval)
 			if err != nil {
 				acc.AddError(err)
@@ -692,7 +966,7 @@ func (a *Agent) runAggregators(
 
 	// Before calling Add, initialize the aggregation window.  This ensures
 	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
 		agg.UpdateWindow(since, until)
 	}
@@ -703,7 +977,7 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 		for metric := range unit.src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			for _, agg := range unit.aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
@@ -718,7 +992,7 @@ func (a *Agent) runAggregators(
 		cancel()
 	}()
 
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		wg.Add(1)
 		go func(agg *models.RunningAggregator) {
 			defer wg.Done()
@@ -741,6 +1015,115 @@ func (a *Agent) runAggregators(
 	log.Printf("D! [agent] Aggregator channel closed")
 }
 
+// startStage sets up the processors and aggregators between the inputs and
+// the outputs and calls Start on all processors.
+func (a *Agent) startStage(
+	processors, aggProcessors models.RunningProcessors,
+	aggregators []*models.RunningAggregator,
+) (*stageUnit, error) {
+	tail := make(chan telegraf.Metric, 100)
+	unit := &stageUnit{
+		tail: tail,
+		done: make(chan struct{}),
+	}
+
+	var next chan<- telegraf.Metric = tail
+	if len(aggregators) != 0 {
+		aggC := next
+		if len(aggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
+			var err error
+			aggC, unit.apu, err = a.startProcessors(next, aggProcessors)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		next, unit.au = a.startAggregators(aggC, next, aggregators)
+	}
+
+	if len(processors) != 0 {
+		var err error
+		next, unit.pu, err = a.startProcessors(next, processors)
+		if err != nil {
+			for _, u := range unit.apu {
+				u.processor.Stop()
+			}
+			return nil, err
+		}
+	}
+	unit.src = next
+
+	return unit, nil
+}
+
+// runStage runs the processors and aggregators of the stage and forwards the
+// resulting metrics to the destination until the source channel of the stage
+// is closed and all metrics have been written.
+func (a *Agent) runStage(startTime time.Time, unit *stageUnit, dst chan<- telegraf.Metric) {
+	var wg sync.WaitGroup
+	if unit.au != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runProcessors(unit.apu)
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runAggregators(startTime, unit.au)
+		}()
+	}
+
+	if unit.pu != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runProcessors(unit.pu)
+		}()
+	}
+
+	for m := range unit.tail {
+		dst <- m
+	}
+	wg.Wait()
+}
+
+// runPipeline forwards the metrics of the inputs to the current stage until
+// the source channel is closed. Afterwards, the function waits for all stages
+// to finish and closes the destination channel.
+func (a *Agent) runPipeline(startTime time.Time, unit *pipelineUnit) {
+	unit.Lock()
+	a.runStageAsync(startTime, unit, unit.stage)
+	unit.Unlock()
+
+	for m := range unit.src {
+		unit.RLock()
+		unit.stage.src <- m
+		unit.RUnlock()
+	}
+
+	unit.Lock()
+	unit.closed = true
+	close(unit.stage.src)
+	unit.Unlock()
+
+	unit.wg.Wait()
+	close(unit.dst)
+	log.Printf("D! [agent] Pipeline channel closed")
+}
+
+// runStageAsync runs the given stage in the background. The unit must be
+// locked by the caller.
+func (a *Agent) runStageAsync(startTime time.Time, unit *pipelineUnit, stage *stageUnit) {
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(stage.done)
+		a.runStage(startTime, stage, unit.dst)
+	}()
+}
+
 func updateWindow(start time.Time, roundInterval bool, period time.Duration) (since, until time.Time) {
 	if roundInterval {
 		until = internal.AlignTime(start, period)
@@ -782,7 +1165,7 @@ func (a *Agent) startOutputs(
 	outputs []*models.RunningOutput,
 ) (chan<- telegraf.Metric, *outputUnit, error) {
 	src := make(chan telegraf.Metric, 100)
-	unit := &outputUnit{src: src}
+	unit := &outputUnit{src: src, router: a.Config.Router}
 	for _, output := range outputs {
 		if err := a.connectOutput(ctx, output); err != nil {
 			var fatalErr *internal.FatalError
@@ -802,6 +1185,11 @@ func (a *Agent) startOutputs(
 		unit.outputs = append(unit.outputs, output)
 	}
 
+	if err := a.restoreOutputs(unit.outputs); err != nil {
+		stopRunningOutputs(unit.outputs)
+		return nil, nil, err
+	}
+
 	return src, unit, nil
 }
 
@@ -829,65 +1217,126 @@ func (*Agent) connectOutput(ctx context.Context, output *models.RunningOutput) e
 func (a *Agent) runOutputs(
 	unit *outputUnit,
 ) {
-	var wg sync.WaitGroup
-
-	// Start flush loop
-	interval := time.Duration(a.Config.Agent.FlushInterval)
-	jitter := time.Duration(a.Config.Agent.FlushJitter)
-
-	ctx, cancel := context.WithCancel(context.Background())
-
+	unit.Lock()
+	unit.tasks = make(map[*models.RunningOutput]*pluginTask, len(unit.outputs))
 	for _, output := range unit.outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
-		}
-
-		jitter := jitter
-		// Overwrite agent flush_jitter if this plugin has its own.
-		if output.Config.FlushJitter != 0 {
-			jitter = output.Config.FlushJitter
-		}
-
-		wg.Add(1)
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
-
-			ticker := NewRollingTicker(interval, jitter)
-			defer ticker.Stop()
-
-			a.flushLoop(ctx, output, ticker)
-		}(output)
+		a.startFlushLoop(unit, output)
 	}
+	unit.Unlock()
 
 	for metric := range unit.src {
-		for i, output := range unit.outputs {
-			if i == len(unit.outputs)-1 {
+		unit.RLock()
+		receivers := unit.receivers(metric)
+		if len(receivers) == 0 {
+			metric.Drop()
+		}
+		for i, output := range receivers {
+			if i == len(receivers)-1 {
 				output.AddMetricNoCopy(metric)
 			} else {
 				output.AddMetric(metric)
 			}
 		}
+		unit.RUnlock()
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
-	cancel()
-	wg.Wait()
+	unit.Lock()
+	unit.closed = true
+	for _, task := range unit.tasks {
+		task.cancel()
+	}
+	unit.Unlock()
+	unit.wg.Wait()
+	a.dumpOutputs(unit.outputs)
 
 	log.Println("I! [agent] Stopping running outputs")
 	stopRunningOutputs(unit.outputs)
 }
 
+// receivers returns the outputs receiving the given metric. Outputs not
+// referenced in the routing table receive all metrics. The unit must be
+// locked by the caller.
+func (unit *outputUnit) receivers(metric telegraf.Metric) []*models.RunningOutput {
+	if unit.router == nil {
+		return unit.outputs
+	}
+
+	targets := unit.router.Route(metric)
+	receivers := make([]*models.RunningOutput, 0, len(unit.outputs))
+	for _, output := range unit.outputs {
+		alias := output.Config.Alias
+		if !unit.router.Routed(alias) || targets[alias] {
+			receivers = append(receivers, output)
+		}
+	}
+	return receivers
+}
+
+// startFlushLoop starts the periodic flush of the given output. The unit must
+// be locked by the caller.
+func (a *Agent) startFlushLoop(unit *outputUnit, output *models.RunningOutput) {
+	// Overwrite agent flush_interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.FlushInterval)
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+
+	// Overwrite agent flush_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.FlushJitter)
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	task := &pluginTask{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		flush:  make(chan struct{}, 1),
+	}
+	unit.tasks[output] = task
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(task.done)
+
+		ticker := NewRollingTicker(interval, jitter)
+		defer ticker.Stop()
+
+		a.flushLoop(ctx, output, ticker, task.flush)
+	}()
+}
+
+// stopFlushLoop removes the given output from the unit, flushes the output
+// one last time and closes the output.
+func (*Agent) stopFlushLoop(unit *outputUnit, output *models.RunningOutput) {
+	unit.Lock()
+	task, found := unit.tasks[output]
+	if !found || unit.closed {
+		unit.Unlock()
+		return
+	}
+	delete(unit.tasks, output)
+	unit.outputs = slices.DeleteFunc(unit.outputs, func(o *models.RunningOutput) bool { return o == output })
+	unit.Unlock()
+
+	task.cancel()
+	<-task.done
+	output.Close()
+}
+
 // flushLoop runs an output's flush function periodically until the context is
 // done.
 func (a *Agent) flushLoop(
 	ctx context.Context,
 	output *models.RunningOutput,
 	ticker Ticker,
+	flushTriggered <-chan struct{},
 ) {
 	logError := func(err error) {
-		if err != nil {
+		// Skipped writes of an open circuit breaker are logged by the output
+		if err != nil && !errors.Is(err, models.ErrCircuitOpen) {
 			log.Printf("E! [agent] Error writing to %s: %v", output.LogName(), err)
 		}
 	}
@@ -901,19 +1350,21 @@ func (a *Agent) flushLoop(
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.drainOutput(output, ticker))
 			return
 		default:
 		}
 
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.drainOutput(output, ticker))
 			return
 		case <-ticker.Elapsed():
 			logError(a.flushOnce(output, ticker, output.Write))
 		case <-flushRequested:
 			logError(a.flushOnce(output, ticker, output.Write))
+		case <-flushTriggered:
+			logError(a.flushOnce(output, ticker, output.Write))
 		case <-output.BatchReady:
 			logError(a.flushBatch(output, output.WriteBatch))
 		}
@@ -983,6 +1434,10 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 // outputC. After gathering pauses for the wait duration to allow service
 // inputs to run.
 func (a *Agent) runTest(ctx context.Context, wait time.Duration, outputC chan<- telegraf.Metric) error {
+	if len(a.Config.Pipelines) > 0 {
+		return a.runTestPipelines(ctx, wait, outputC)
+	}
+
 	// Set the default for processor skipping
 	if a.Config.Agent.SkipProcessorsAfterAggregators == nil {
 		msg := `The default value of 'skip_processors_after_aggregators' will change to 'true' with Telegraf v1.40.0! `
@@ -1078,6 +1533,11 @@ func (a *Agent) Once(ctx context.Context, wait time.Duration) error {
 	for _, output := range a.Config.Outputs {
 		unsent += output.BufferLength()
 	}
+	for _, p := range a.Config.Pipelines {
+		for _, output := range p.Outputs {
+			unsent += output.BufferLength()
+		}
+	}
 	if unsent != 0 {
 		return fmt.Errorf("output plugins unable to send %d metrics", unsent)
 	}
@@ -1088,6 +1548,12 @@ func (a *Agent) Once(ctx context.Context, wait time.Duration) error {
 // outputC. After gathering pauses for the wait duration to allow service
 // inputs to run.
 func (a *Agent) runOnce(ctx context.Context, wait time.Duration) error {
+	if len(a.Config.Pipelines) > 0 {
+		return a.runPipelines(func(pa *Agent) error {
+			return pa.runOnce(ctx, wait)
+		})
+	}
+
 	// Set the default for processor skipping
 	if a.Config.Agent.SkipProcessorsAfterAggregators == nil {
 		msg := `The default value of 'skip_processors_after_aggregators' will change to 'true' with Telegraf v1.40.0! `
@@ -257,3 +257,59 @@ func collect(ctx context.Context, a *Agent, wait time.Duration) ([]telegraf.Metr
 	}
 	return received, nil
 }
+
+func TestOutputUnitReceivers(t *testing.T) {
+	router := &models.Router{
+		Routes: []*models.Route{
+			{
+				Name:       "alerts",
+				MetricPass: `name == "alert"`,
+				Outputs:    []string{"pager"},
+			},
+		},
+		Default: []string{"archive"},
+	}
+	require.NoError(t, router.Init())
+
+	var outputs []*models.RunningOutput
+	for _, alias := range []string{"pager", "archive", "unrouted"} {
+		output := models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "nop", Alias: alias}, 0, 0)
+		outputs = append(outputs, output)
+	}
+	unit := &outputUnit{outputs: outputs, router: router}
+
+	m := testutil.MustMetric("alert", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.Equal(t, []*models.RunningOutput{outputs[0], outputs[2]}, unit.receivers(m))
+
+	m = testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.Equal(t, []*models.RunningOutput{outputs[1], outputs[2]}, unit.receivers(m))
+
+	unit.router = nil
+	require.Equal(t, outputs, unit.receivers(m))
+}
+
+func TestPipelines(t *testing.T) {
+	// Get parser to parse input and expected output
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+
+	expected, err := testutil.ParseMetricsFromFile("testdata/pipelines/expected.out", parser)
+	require.NoError(t, err)
+
+	cfg := config.NewConfig()
+	require.NoError(t, cfg.LoadAll("testdata/pipelines/telegraf.conf"))
+	require.Len(t, cfg.Pipelines, 2)
+
+	// Run the agent in "test" mode and collect the metrics of all pipelines
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	actual, err := collect(ctx, agent, 0)
+	require.NoError(t, err)
+
+	options := []cmp.Option{
+		testutil.IgnoreTags("host"),
+		testutil.SortMetrics(),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, options...)
+}
@@ -0,0 +1,377 @@
+package agent
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+)
+
+// apiPlugin describes a loaded plugin in the management API.
+type apiPlugin struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// apiPlugins is the list of loaded plugins in the management API.
+type apiPlugins struct {
+	Inputs      []apiPlugin `json:"inputs"`
+	Processors  []apiPlugin `json:"processors"`
+	Aggregators []apiPlugin `json:"aggregators"`
+	Outputs     []apiPlugin `json:"outputs"`
+}
+
+// apiInputStatus is the state and statistics of an input plugin.
+type apiInputStatus struct {
+	apiPlugin
+	Paused          bool  `json:"paused"`
+	MetricsGathered int64 `json:"metrics_gathered"`
+	GatherTimeNs    int64 `json:"gather_time_ns"`
+	GatherTimeouts  int64 `json:"gather_timeouts"`
+}
+
+// apiOutputStatus is the buffer state and statistics of an output plugin.
+type apiOutputStatus struct {
+	apiPlugin
+	BufferSize      int64 `json:"buffer_size"`
+	BufferLimit     int64 `json:"buffer_limit"`
+	MetricsAdded    int64 `json:"metrics_added"`
+	MetricsWritten  int64 `json:"metrics_written"`
+	MetricsRejected int64 `json:"metrics_rejected"`
+	MetricsDropped  int64 `json:"metrics_dropped"`
+	MetricsFiltered int64 `json:"metrics_filtered"`
+	WriteTimeNs     int64 `json:"write_time_ns"`
+}
+
+// listenAPI opens the listener for the management API. The address is either
+// given as "host:port" or as "unix:///path/to/socket". TCP addresses are only
+// allowed with a token as they are accessible to any local user or even
+// remotely.
+func listenAPI(address string, token *config.Secret) (net.Listener, error) {
+	if path, found := strings.CutPrefix(address, "unix://"); found {
+		// Remove stale sockets of previous runs
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("removing socket %q failed: %w", path, err)
+		}
+		return net.Listen("unix", path)
+	}
+	if token.Empty() {
+		return nil, fmt.Errorf("management API at TCP address %q requires an 'api_token'", address)
+	}
+	return net.Listen("tcp", address)
+}
+
+// serveAPI runs the management API on the given listener until the context
+// is done.
+func (a *Agent) serveAPI(ctx context.Context, listener net.Listener) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/plugins", a.handlePlugins)
+	mux.HandleFunc("GET /api/v1/inputs", a.handleInputs)
+	mux.HandleFunc("GET /api/v1/outputs", a.handleOutputs)
+	mux.HandleFunc("POST /api/v1/inputs/{id}/pause", a.handlePause(true))
+	mux.HandleFunc("POST /api/v1/inputs/{id}/resume", a.handlePause(false))
+	mux.HandleFunc("POST /api/v1/outputs/{id}/flush", a.handleFlush)
+	mux.HandleFunc("POST /api/v1/reload", a.handleReload)
+
+	var handler http.Handler = mux
+	if !a.Config.Agent.APIToken.Empty() {
+		handler = authenticateAPI(&a.Config.Agent.APIToken, mux)
+	}
+
+	server := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("E! [agent] Shutting down management API failed: %v", err)
+		}
+	}()
+
+	log.Printf("I! [agent] Starting management API at %s", listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("E! [agent] Management API failed: %v", err)
+	}
+	<-done
+}
+
+// authenticateAPI only passes requests with the given bearer token to the
+// handler. The token is resolved for each request to keep it in protected
+// memory and to follow changes in secret-stores.
+func authenticateAPI(token *config.Secret, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		provided, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !found {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		secret, err := token.Get()
+		if err != nil {
+			log.Printf("E! [agent] Getting management API token failed: %v", err)
+			http.Error(w, "getting token failed", http.StatusInternalServerError)
+			return
+		}
+		valid := subtle.ConstantTimeCompare([]byte(provided), secret.Bytes()) == 1
+		secret.Destroy()
+
+		if !valid {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func (a *Agent) handlePlugins(w http.ResponseWriter, _ *http.Request) {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	r := a.running
+	if r == nil {
+		http.Error(w, "agent is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	var plugins apiPlugins
+	for _, pa := range a.managedAgents() {
+		r := pa.running
+		r.inputs.Lock()
+		for _, input := range r.inputs.inputs {
+			plugins.Inputs = append(plugins.Inputs, apiPlugin{input.ID(), input.Config.Name, input.Config.Alias})
+		}
+		r.inputs.Unlock()
+		for _, processor := range pa.Config.Processors {
+			plugins.Processors = append(plugins.Processors, apiPlugin{processor.ID(), processor.Config.Name, processor.Config.Alias})
+		}
+		for _, aggregator := range pa.Config.Aggregators {
+			plugins.Aggregators = append(plugins.Aggregators, apiPlugin{aggregator.ID(), aggregator.Config.Name, aggregator.Config.Alias})
+		}
+		r.outputs.RLock()
+		for _, output := range r.outputs.outputs {
+			plugins.Outputs = append(plugins.Outputs, apiPlugin{output.ID(), output.Config.Name, output.Config.Alias})
+		}
+		r.outputs.RUnlock()
+	}
+
+	writeJSON(w, plugins)
+}
+
+func (a *Agent) handleInputs(w http.ResponseWriter, _ *http.Request) {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	r := a.running
+	if r == nil {
+		http.Error(w, "agent is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	status := make([]apiInputStatus, 0, len(r.inputs.inputs))
+	for _, pa := range a.managedAgents() {
+		unit := pa.running.inputs
+		unit.Lock()
+		for _, input := range unit.inputs {
+			var paused bool
+			if task, found := unit.tasks[input]; found {
+				paused = task.paused.Load()
+			}
+			status = append(status, apiInputStatus{
+				apiPlugin:       apiPlugin{input.ID(), input.Config.Name, input.Config.Alias},
+				Paused:          paused,
+				MetricsGathered: input.MetricsGathered.Get(),
+				GatherTimeNs:    input.GatherTime.Get(),
+				GatherTimeouts:  input.GatherTimeouts.Get(),
+			})
+		}
+		unit.Unlock()
+	}
+
+	writeJSON(w, status)
+}
+
+func (a *Agent) handleOutputs(w http.ResponseWriter, _ *http.Request) {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	r := a.running
+	if r == nil {
+		http.Error(w, "agent is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	status := make([]apiOutputStatus, 0, len(r.outputs.outputs))
+	for _, pa := range a.managedAgents() {
+		unit := pa.running.outputs
+		unit.RLock()
+		for _, output := range unit.outputs {
+			stats := output.BufferStats()
+			status = append(status, apiOutputStatus{
+				apiPlugin:       apiPlugin{output.ID(), output.Config.Name, output.Config.Alias},
+				BufferSize:      stats.BufferSize.Get(),
+				BufferLimit:     stats.BufferLimit.Get(),
+				MetricsAdded:    stats.MetricsAdded.Get(),
+				MetricsWritten:  stats.MetricsWritten.Get(),
+				MetricsRejected: stats.MetricsRejected.Get(),
+				MetricsDropped:  stats.MetricsDropped.Get(),
+				MetricsFiltered: output.MetricsFiltered.Get(),
+				WriteTimeNs:     output.WriteTime.Get(),
+			})
+		}
+		unit.RUnlock()
+	}
+
+	writeJSON(w, status)
+}
+
+func (a *Agent) handlePause(pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		a.reloadLock.Lock()
+		defer a.reloadLock.Unlock()
+
+		if a.running == nil {
+			http.Error(w, "agent is not running", http.StatusServiceUnavailable)
+			return
+		}
+
+		id := req.PathValue("id")
+		input, task, matches := a.findInputTask(id)
+		switch matches {
+		case 0:
+			http.Error(w, fmt.Sprintf("unknown input %q", id), http.StatusNotFound)
+			return
+		case 1:
+		default:
+			http.Error(w, fmt.Sprintf("input ID %q is ambiguous, matching %d inputs", id, matches), http.StatusConflict)
+			return
+		}
+		// Service inputs produce metrics independent of the gather loop
+		if _, ok := input.Input.(telegraf.ServiceInput); ok {
+			http.Error(w, fmt.Sprintf("pausing service input %q is not supported", id), http.StatusConflict)
+			return
+		}
+		task.paused.Store(pause)
+		if pause {
+			log.Printf("I! [agent] Pausing input %s", input.LogName())
+		} else {
+			log.Printf("I! [agent] Resuming input %s", input.LogName())
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (a *Agent) handleFlush(w http.ResponseWriter, req *http.Request) {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	if a.running == nil {
+		http.Error(w, "agent is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := req.PathValue("id")
+	output, task, matches := a.findOutputTask(id)
+	switch matches {
+	case 0:
+		http.Error(w, fmt.Sprintf("unknown output %q", id), http.StatusNotFound)
+		return
+	case 1:
+	default:
+		http.Error(w, fmt.Sprintf("output ID %q is ambiguous, matching %d outputs", id, matches), http.StatusConflict)
+		return
+	}
+
+	// Do not block if a flush is already pending
+	select {
+	case task.flush <- struct{}{}:
+		log.Printf("I! [agent] Flushing output %s", output.LogName())
+	default:
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *Agent) handleReload(w http.ResponseWriter, _ *http.Request) {
+	if a.ReloadRequest == nil {
+		http.Error(w, "reloading is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	log.Printf("I! [agent] Reload requested via management API")
+	a.ReloadRequest()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// managedAgents returns the top-level agent followed by the agents of the
+// pipelines. Must be called with the reload lock held while running.
+func (a *Agent) managedAgents() []*Agent {
+	return append([]*Agent{a}, a.pipelines...)
+}
+
+// findInputTask returns the input with the given ID and its gather task
+// together with the number of inputs matching the ID. Identical plugin
+// settings result in the same ID, so the returned input is only meaningful for
+// exactly one match.
+func (a *Agent) findInputTask(id string) (*models.RunningInput, *pluginTask, int) {
+	var found *models.RunningInput
+	var foundTask *pluginTask
+	var matches int
+	for _, pa := range a.managedAgents() {
+		unit := pa.running.inputs
+		unit.Lock()
+		for input, task := range unit.tasks {
+			if input.ID() == id {
+				found, foundTask = input, task
+				matches++
+			}
+		}
+		unit.Unlock()
+	}
+	return found, foundTask, matches
+}
+
+// findOutputTask returns the output with the given ID and its flush task
+// together with the number of outputs matching the ID.
+func (a *Agent) findOutputTask(id string) (*models.RunningOutput, *pluginTask, int) {
+	var found *models.RunningOutput
+	var foundTask *pluginTask
+	var matches int
+	for _, pa := range a.managedAgents() {
+		unit := pa.running.outputs
+		unit.RLock()
+		for output, task := range unit.tasks {
+			if output.ID() == id {
+				found, foundTask = output, task
+				matches++
+			}
+		}
+		unit.RUnlock()
+	}
+	return found, foundTask, matches
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("E! [agent] Writing management API response failed: %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func TestAPI(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "1h"
+		  omit_hostname = true
+		  api_address = "unix://`+socket+`"
+		[[inputs.reload_test]]
+		  value = "a"
+		[[inputs.api_test]]
+		[[outputs.reload_test]]
+	`)
+	// The order of different input plugins is not deterministic
+	input, gatherInput := cfg.Inputs[0], cfg.Inputs[1]
+	if input.Config.Name != "reload_test" {
+		input, gatherInput = gatherInput, input
+	}
+	output := cfg.Outputs[0]
+	plugin := output.Output.(*reloadTestOutput)
+
+	var reloads int
+	agent := NewAgent(cfg)
+	agent.ReloadRequest = func() { reloads++ }
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	request := func(method, path string) *http.Response {
+		req, err := http.NewRequestWithContext(t.Context(), method, "http://localhost"+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Check the plugin list
+	require.Eventually(t, func() bool {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://localhost/api/v1/plugins", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+
+	resp := request(http.MethodGet, "/api/v1/plugins")
+	var plugins apiPlugins
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&plugins))
+	resp.Body.Close()
+	expectedInputs := make([]apiPlugin, 0, len(cfg.Inputs))
+	for _, ri := range cfg.Inputs {
+		expectedInputs = append(expectedInputs, apiPlugin{ID: ri.ID(), Name: ri.Config.Name})
+	}
+	require.Equal(t, expectedInputs, plugins.Inputs)
+	require.Equal(t, []apiPlugin{{ID: output.ID(), Name: "reload_test"}}, plugins.Outputs)
+
+	// Wait for metrics in the buffer and flush the output on request
+	require.Eventually(t, func() bool {
+		return output.BufferLength() > 0
+	}, 5*time.Second, 50*time.Millisecond)
+	require.False(t, plugin.received("a"))
+
+	resp = request(http.MethodPost, "/api/v1/outputs/"+output.ID()+"/flush")
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Eventually(t, func() bool {
+		return plugin.received("a")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	resp = request(http.MethodGet, "/api/v1/outputs")
+	var outputs []apiOutputStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&outputs))
+	resp.Body.Close()
+	require.Len(t, outputs, 1)
+	require.Positive(t, outputs[0].MetricsWritten)
+
+	// Pause the input, service inputs cannot be paused
+	resp = request(http.MethodPost, "/api/v1/inputs/"+gatherInput.ID()+"/pause")
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = request(http.MethodPost, "/api/v1/inputs/"+input.ID()+"/pause")
+	resp.Body.Close()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = request(http.MethodGet, "/api/v1/inputs")
+	var inputs []apiInputStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&inputs))
+	resp.Body.Close()
+	require.Len(t, inputs, 2)
+	for _, status := range inputs {
+		if status.ID == gatherInput.ID() {
+			require.True(t, status.Paused)
+			require.Positive(t, status.MetricsGathered)
+		} else {
+			require.False(t, status.Paused)
+		}
+	}
+
+	// Unknown plugins
+	resp = request(http.MethodPost, "/api/v1/inputs/unknown/resume")
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = request(http.MethodPost, "/api/v1/outputs/unknown/flush")
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Request a reload
+	resp = request(http.MethodPost, "/api/v1/reload")
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Equal(t, 1, reloads)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestAPIAmbiguousID(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "1h"
+		  omit_hostname = true
+		  api_address = "unix://`+socket+`"
+		[[inputs.api_test]]
+		[[inputs.api_test]]
+		[[outputs.reload_test]]
+		[[outputs.reload_test]]
+	`)
+	require.Equal(t, cfg.Inputs[0].ID(), cfg.Inputs[1].ID())
+	require.Equal(t, cfg.Outputs[0].ID(), cfg.Outputs[1].ID())
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	request := func(method, path string) (int, error) {
+		req, err := http.NewRequestWithContext(t.Context(), method, "http://localhost"+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	require.Eventually(t, func() bool {
+		status, err := request(http.MethodGet, "/api/v1/plugins")
+		return err == nil && status == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Requests must not pick one of the plugins arbitrarily
+	status, err := request(http.MethodPost, "/api/v1/inputs/"+cfg.Inputs[0].ID()+"/pause")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, status)
+	status, err = request(http.MethodPost, "/api/v1/outputs/"+cfg.Outputs[0].ID()+"/flush")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, status)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestAPIToken(t *testing.T) {
+	// TCP addresses require a token
+	var empty config.Secret
+	_, err := listenAPI("127.0.0.1:0", &empty)
+	require.ErrorContains(t, err, "requires an 'api_token'")
+
+	token := config.NewSecret([]byte("secret"))
+	defer token.Destroy()
+	listener, err := listenAPI("127.0.0.1:0", &token)
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "1h"
+		  omit_hostname = true
+		  api_address = "`+addr+`"
+		  api_token = "secret"
+		[[inputs.api_test]]
+		[[outputs.reload_test]]
+	`)
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+
+	request := func(token string) (int, error) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+addr+"/api/v1/plugins", nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	require.Eventually(t, func() bool {
+		status, err := request("secret")
+		return err == nil && status == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+
+	status, err := request("")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, err = request("wrong")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestAPIPipelines(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "1h"
+		  omit_hostname = true
+		  api_address = "unix://`+socket+`"
+		[[pipeline]]
+		  name = "other"
+		  [[pipeline.inputs.reload_test]]
+		    value = "b"
+		  [[pipeline.outputs.reload_test]]
+	`)
+	input := cfg.Pipelines[0].Inputs[0]
+	output := cfg.Pipelines[0].Outputs[0]
+	plugin := output.Output.(*reloadTestOutput)
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	request := func(method, path string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(t.Context(), method, "http://localhost"+path, nil)
+		require.NoError(t, err)
+		return client.Do(req)
+	}
+
+	// The plugins of the pipelines must be listed and managed
+	var plugins apiPlugins
+	require.Eventually(t, func() bool {
+		resp, err := request(http.MethodGet, "/api/v1/plugins")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&plugins) == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, []apiPlugin{{ID: input.ID(), Name: "reload_test"}}, plugins.Inputs)
+	require.Equal(t, []apiPlugin{{ID: output.ID(), Name: "reload_test"}}, plugins.Outputs)
+
+	require.Eventually(t, func() bool {
+		return output.BufferLength() > 0
+	}, 5*time.Second, 50*time.Millisecond)
+	resp, err := request(http.MethodPost, "/api/v1/outputs/"+output.ID()+"/flush")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Eventually(t, func() bool {
+		return plugin.received("b")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// apiTestInput is an input only producing metrics when gathered
+type apiTestInput struct{}
+
+func (*apiTestInput) SampleConfig() string {
+	return ""
+}
+
+func (*apiTestInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("test", map[string]interface{}{"value": 42}, map[string]string{"value": "gather"})
+	return nil
+}
+
+func init() {
+	inputs.Add("api_test", func() telegraf.Input {
+		return &apiTestInput{}
+	})
+}
@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/influxdata/telegraf/models"
+	parsers_influx "github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// drainOutput writes the buffered metrics of the output on shutdown. If a
+// drain timeout is configured, writing is retried until the buffer is empty or
+// the timeout elapsed. Otherwise, only a single attempt is made.
+func (a *Agent) drainOutput(output *models.RunningOutput, ticker Ticker) error {
+	err := a.flushOnce(output, ticker, output.Write)
+
+	timeout := time.Duration(a.Config.Agent.ShutdownDrainTimeout)
+	if timeout <= 0 || output.BufferLength() == 0 {
+		return err
+	}
+
+	log.Printf("I! [agent] Draining %d metrics of %s for up to %s", output.BufferLength(), output.LogName(), timeout)
+	deadline := time.Now().Add(timeout)
+	for output.BufferLength() > 0 {
+		// Back off a bit before retrying a failed write
+		if err != nil {
+			if !errors.Is(err, models.ErrCircuitOpen) {
+				log.Printf("E! [agent] Error writing to %s: %v", output.LogName(), err)
+			}
+			time.Sleep(min(time.Second, time.Until(deadline)))
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("draining timed out with %d metrics remaining", output.BufferLength())
+		}
+		err = output.WriteBatch()
+	}
+	return err
+}
+
+// dumpFilename returns the name of the file to dump the metrics of the given
+// output to. Outputs with identical settings share the same ID, so the
+// occurrence of the ID in the top-level and pipeline outputs is appended to the
+// name for all but the first output.
+func (a *Agent) dumpFilename(output *models.RunningOutput) string {
+	root := a
+	if a.parent != nil {
+		root = a.parent
+	}
+	all := slices.Clone(root.Config.Outputs)
+	for _, p := range root.Config.Pipelines {
+		all = append(all, p.Outputs...)
+	}
+
+	id := output.ID()
+	var occurrence int
+	for _, o := range all {
+		if o == output {
+			break
+		}
+		if o.ID() == id {
+			occurrence++
+		}
+	}
+
+	name := id
+	if occurrence > 0 {
+		name += fmt.Sprintf("_%d", occurrence+1)
+	}
+	return filepath.Join(a.Config.Agent.ShutdownDumpDirectory, name+".influx")
+}
+
+// warnOrphanedDumps warns about dump files left after restoring the dumps of
+// all outputs, e.g. due to changed output settings resulting in a different ID.
+func (a *Agent) warnOrphanedDumps() {
+	if a.Config.Agent.ShutdownDumpDirectory == "" {
+		return
+	}
+
+	files, err := filepath.Glob(filepath.Join(a.Config.Agent.ShutdownDumpDirectory, "*.influx"))
+	if err != nil {
+		log.Printf("E! [agent] Checking for dumped metrics failed: %v", err)
+		return
+	}
+	for _, fn := range files {
+		log.Printf("W! [agent] Dumped metrics in %q do not belong to any output, use 'telegraf replay' to write them", fn)
+	}
+}
+
+// dumpOutputs writes the metrics remaining in the memory buffers of the
+// outputs to the dump directory as line-protocol.
+func (a *Agent) dumpOutputs(outputs []*models.RunningOutput) {
+	if a.Config.Agent.ShutdownDumpDirectory == "" {
+		return
+	}
+
+	for _, output := range outputs {
+		// Only memory buffers lose their metrics on shutdown
+		if output.Config.BufferStrategy != "" && output.Config.BufferStrategy != "memory" {
+			continue
+		}
+		if output.BufferLength() == 0 {
+			continue
+		}
+
+		metrics := output.BufferedMetrics()
+		s := &influx.Serializer{SortFields: true, UintSupport: true}
+		if err := s.Init(); err != nil {
+			log.Printf("E! [agent] Initializing serializer for dumping %s failed: %v", output.LogName(), err)
+			return
+		}
+		octets, err := s.SerializeBatch(metrics)
+		if err != nil {
+			log.Printf("E! [agent] Serializing metrics of %s failed: %v", output.LogName(), err)
+			continue
+		}
+
+		if err := os.MkdirAll(a.Config.Agent.ShutdownDumpDirectory, 0750); err != nil {
+			log.Printf("E! [agent] Creating dump directory failed: %v", err)
+			return
+		}
+		filename := a.dumpFilename(output)
+		if err := os.WriteFile(filename, octets, 0640); err != nil {
+			log.Printf("E! [agent] Dumping metrics of %s failed: %v", output.LogName(), err)
+			continue
+		}
+		log.Printf("I! [agent] Dumped %d metrics of %s to %q", len(metrics), output.LogName(), filename)
+	}
+}
+
+// restoreOutputs reads the metrics dumped on the previous shutdown into the
+// buffers of the outputs and removes the dump files.
+func (a *Agent) restoreOutputs(outputs []*models.RunningOutput) error {
+	if a.Config.Agent.ShutdownDumpDirectory == "" {
+		return nil
+	}
+
+	for _, output := range outputs {
+		filename := a.dumpFilename(output)
+		octets, err := os.ReadFile(filename)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("reading dumped metrics of %s failed: %w", output.LogName(), err)
+		}
+
+		parser := &parsers_influx.Parser{}
+		if err := parser.Init(); err != nil {
+			return fmt.Errorf("initializing parser for dumped metrics failed: %w", err)
+		}
+		metrics, err := parser.Parse(octets)
+		if err != nil {
+			log.Printf("E! [agent] Parsing dumped metrics of %s failed: %v", output.LogName(), err)
+		}
+		output.RestoreBuffer(metrics)
+		log.Printf("I! [agent] Restored %d dumped metrics of %s", len(metrics), output.LogName())
+
+		if err := os.Remove(filename); err != nil {
+			return fmt.Errorf("removing dumped metrics of %s failed: %w", output.LogName(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+func TestShutdownDrain(t *testing.T) {
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "50ms"
+		  flush_interval = "1h"
+		  omit_hostname = true
+		  shutdown_drain_timeout = "10s"
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.drain_test]]
+		  metric_batch_size = 1
+	`)
+	output := cfg.Outputs[0]
+	plugin := output.Output.(*drainTestOutput)
+	plugin.failures = 3
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return output.BufferLength() >= 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// All metrics must be written despite the initial failures
+	cancel()
+	require.NoError(t, <-done)
+	require.Zero(t, output.BufferLength())
+
+	plugin.Lock()
+	defer plugin.Unlock()
+	require.Zero(t, plugin.failures)
+	require.GreaterOrEqual(t, plugin.written, 3)
+}
+
+func TestShutdownDumpAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	config := `
+		[agent]
+		  interval = "50ms"
+		  flush_interval = "1h"
+		  omit_hostname = true
+		  shutdown_drain_timeout = "100ms"
+		  shutdown_dump_directory = "` + filepath.ToSlash(dir) + `"
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.drain_test]]
+	`
+
+	// Dump the metrics of a failing output on shutdown
+	cfg := loadReloadConfig(t, nil, config)
+	output := cfg.Outputs[0]
+	plugin := output.Output.(*drainTestOutput)
+	plugin.failures = -1
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return output.BufferLength() >= 3
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	filename := filepath.Join(dir, output.ID()+".influx")
+	require.FileExists(t, filename)
+	dumped := output.BufferLength()
+
+	// Restore the metrics on the next start
+	cfg = loadReloadConfig(t, nil, config)
+	output = cfg.Outputs[0]
+	plugin = output.Output.(*drainTestOutput)
+
+	agent = NewAgent(cfg)
+	ctx, cancel = context.WithCancel(t.Context())
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filename)
+		return errors.Is(err, os.ErrNotExist)
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	plugin.Lock()
+	defer plugin.Unlock()
+	require.GreaterOrEqual(t, plugin.written, dumped)
+}
+
+func TestShutdownDumpIdenticalOutputs(t *testing.T) {
+	dir := t.TempDir()
+	config := `
+		[agent]
+		  interval = "50ms"
+		  flush_interval = "1h"
+		  omit_hostname = true
+		  shutdown_dump_directory = "` + filepath.ToSlash(dir) + `"
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.drain_test]]
+		[[outputs.drain_test]]
+	`
+
+	// Identical outputs must not overwrite each others dumps
+	cfg := loadReloadConfig(t, nil, config)
+	require.Equal(t, cfg.Outputs[0].ID(), cfg.Outputs[1].ID())
+	for _, output := range cfg.Outputs {
+		output.Output.(*drainTestOutput).failures = -1
+	}
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return cfg.Outputs[0].BufferLength() > 0 && cfg.Outputs[1].BufferLength() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	id := cfg.Outputs[0].ID()
+	require.FileExists(t, filepath.Join(dir, id+".influx"))
+	require.FileExists(t, filepath.Join(dir, id+"_2.influx"))
+}
+
+func TestShutdownDumpOrphaned(t *testing.T) {
+	dir := t.TempDir()
+	orphaned := filepath.Join(dir, "unknown.influx")
+	require.NoError(t, os.WriteFile(orphaned, []byte("test value=1 1000000000\n"), 0640))
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "50ms"
+		  flush_interval = "1h"
+		  omit_hostname = true
+		  shutdown_dump_directory = "`+filepath.ToSlash(dir)+`"
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.drain_test]]
+	`)
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return cfg.Outputs[0].BufferLength() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	// Dumps not belonging to any output must be kept for replaying
+	require.FileExists(t, orphaned)
+	require.Contains(t, buf.String(), "use 'telegraf replay'")
+}
+
+type drainTestOutput struct {
+	failures int
+	written  int
+	sync.Mutex
+}
+
+func (*drainTestOutput) SampleConfig() string {
+	return ""
+}
+
+func (*drainTestOutput) Connect() error {
+	return nil
+}
+
+func (*drainTestOutput) Close() error {
+	return nil
+}
+
+func (o *drainTestOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+
+	// Negative failures let all writes fail
+	if o.failures != 0 {
+		if o.failures > 0 {
+			o.failures--
+		}
+		return errors.New("write failed")
+	}
+	o.written += len(metrics)
+	return nil
+}
+
+func init() {
+	outputs.Add("drain_test", func() telegraf.Output {
+		return &drainTestOutput{}
+	})
+}
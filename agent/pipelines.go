@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+)
+
+// pipelineAgent returns an agent running the plugins of the given pipeline
+// using the agent settings, global tags and persister of the parent agent.
+func (a *Agent) pipelineAgent(p *config.Pipeline) *Agent {
+	// Do not share the agent settings as they are modified when setting the
+	// defaults. The parent agent already warned about the defaults.
+	agentConfig := *a.Config.Agent
+	if agentConfig.SkipProcessorsAfterAggregators == nil {
+		skipProcessorsAfterAggregators := false
+		agentConfig.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
+	}
+
+	pa := NewAgent(&config.Config{
+		Tags:          a.Config.Tags,
+		Agent:         &agentConfig,
+		Inputs:        p.Inputs,
+		Outputs:       p.Outputs,
+		Aggregators:   p.Aggregators,
+		Processors:    p.Processors,
+		AggProcessors: p.AggProcessors,
+		Persister:     a.Config.Persister,
+	})
+	pa.parent = a
+	return pa
+}
+
+// runPipelines runs the given function for the top-level plugins and each
+// pipeline concurrently. This is used in --test and --once mode where the
+// chains of plugins are run once instead of being started as units.
+func (a *Agent) runPipelines(fn func(pa *Agent) error) error {
+	// Set the default for processor skipping
+	if a.Config.Agent.SkipProcessorsAfterAggregators == nil {
+		msg := `The default value of 'skip_processors_after_aggregators' will change to 'true' with Telegraf v1.40.0! `
+		msg += `If you need the current default behavior, please explicitly set the option to 'false'!`
+		log.Print("W! [agent] ", color.YellowString(msg))
+		skipProcessorsAfterAggregators := false
+		a.Config.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
+	}
+
+	// Run the top-level plugins as pipeline without name
+	main := a.pipelineAgent(&config.Pipeline{
+		Inputs:        a.Config.Inputs,
+		Outputs:       a.Config.Outputs,
+		Aggregators:   a.Config.Aggregators,
+		Processors:    a.Config.Processors,
+		AggProcessors: a.Config.AggProcessors,
+	})
+	main.Config.Router = a.Config.Router
+
+	agents := []*Agent{main}
+	for _, p := range a.Config.Pipelines {
+		agents = append(agents, a.pipelineAgent(p))
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(agents))
+	for i, pa := range agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(pa)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// runTestPipelines runs the test of the top-level plugins and all pipelines
+// and merges the resulting metrics into the given channel.
+func (a *Agent) runTestPipelines(ctx context.Context, wait time.Duration, outputC chan<- telegraf.Metric) error {
+	var wg sync.WaitGroup
+	err := a.runPipelines(func(pa *Agent) error {
+		dst := make(chan telegraf.Metric, 100)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range dst {
+				outputC <- m
+			}
+		}()
+		return pa.runTest(ctx, wait, dst)
+	})
+	if err != nil {
+		return err
+	}
+	wg.Wait()
+	close(outputC)
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+)
+
+// InitProcessing initializes the processors and aggregators of the
+// configuration for ProcessMetrics. Inputs and outputs are not initialized to
+// not connect to any service.
+func (a *Agent) InitProcessing() error {
+	// Set the default for processor skipping
+	if a.Config.Agent.SkipProcessorsAfterAggregators == nil {
+		msg := `The default value of 'skip_processors_after_aggregators' will change to 'true' with Telegraf v1.40.0! `
+		msg += `If you need the current default behavior, please explicitly set the option to 'false'!`
+		log.Print("W! [agent] ", color.YellowString(msg))
+		skipProcessorsAfterAggregators := false
+		a.Config.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
+	}
+
+	for _, processor := range a.Config.Processors {
+		if err := processor.Init(); err != nil {
+			return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
+		}
+	}
+	for _, aggregator := range a.Config.Aggregators {
+		if err := aggregator.Init(); err != nil {
+			return fmt.Errorf("could not initialize aggregator %s: %w", aggregator.LogName(), err)
+		}
+	}
+	if !*a.Config.Agent.SkipProcessorsAfterAggregators {
+		for _, processor := range a.Config.AggProcessors {
+			if err := processor.Init(); err != nil {
+				return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// ProcessMetrics sends the given metrics through the processors and
+// aggregators of the configuration and returns the resulting metrics in the
+// order they leave the processing chain. Inputs and outputs are not used.
+//
+// All metrics are aggregated in a single aggregation window covering the
+// timestamps of the given metrics and the aggregators are pushed once after
+// all metrics were added. The plugins must be initialized using
+// InitProcessing before.
+func (a *Agent) ProcessMetrics(metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	metrics, err := processBatch(a.Config.Processors, metrics)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.Config.Aggregators) == 0 {
+		return metrics, nil
+	}
+
+	// Cover all metrics by the aggregation window
+	var since, until time.Time
+	for _, m := range metrics {
+		if since.IsZero() || m.Time().Before(since) {
+			since = m.Time()
+		}
+		if until.IsZero() || m.Time().After(until) {
+			until = m.Time()
+		}
+	}
+	for _, agg := range a.Config.Aggregators {
+		agg.UpdateWindow(since, until)
+	}
+
+	// Keep the original metrics not dropped by any aggregator
+	kept := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		var dropOriginal bool
+		for _, agg := range a.Config.Aggregators {
+			if ok := agg.Add(m); ok {
+				dropOriginal = true
+			}
+		}
+
+		if dropOriginal {
+			m.Drop()
+			continue
+		}
+		kept = append(kept, m)
+	}
+
+	aggregated := collectMetrics(func(dst chan<- telegraf.Metric) {
+		for _, agg := range a.Config.Aggregators {
+			agg.Push(NewAccumulator(agg, dst))
+		}
+	})
+	if !*a.Config.Agent.SkipProcessorsAfterAggregators {
+		aggregated, err = processBatch(a.Config.AggProcessors, aggregated)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return append(kept, aggregated...), nil
+}
+
+// processBatch sends all metrics through the processors in order, processing
+// the whole batch with one processor before passing on the resulting metrics
+// to the next processor.
+func processBatch(processors models.RunningProcessors, metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	for _, processor := range processors {
+		var err error
+		metrics = collectMetrics(func(dst chan<- telegraf.Metric) {
+			acc := NewAccumulator(processor, dst)
+			if err = processor.Start(acc); err != nil {
+				return
+			}
+			for _, m := range metrics {
+				if err := processor.Add(m, acc); err != nil {
+					acc.AddError(err)
+					m.Drop()
+				}
+			}
+			processor.Stop()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("starting processor %s: %w", processor.LogName(), err)
+		}
+	}
+	return metrics, nil
+}
+
+// collectMetrics returns all metrics written to the channel by the given function
+func collectMetrics(fn func(dst chan<- telegraf.Metric)) []telegraf.Metric {
+	dst := make(chan telegraf.Metric, 100)
+	done := make(chan []telegraf.Metric)
+	go func() {
+		var metrics []telegraf.Metric
+		for m := range dst {
+			metrics = append(metrics, m)
+		}
+		done <- metrics
+	}()
+
+	fn(dst)
+	close(dst)
+	return <-done
+}
@@ -0,0 +1,501 @@
+package agent
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/persister"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// ErrRestartRequired is returned by Reload if the configuration change cannot
+// be applied to the running agent and the agent must be restarted instead.
+var ErrRestartRequired = errors.New("configuration change requires a restart")
+
+// RunningOutputs returns the outputs of the running agent. The outputs can be
+// passed to config.Config.ReuseOutputs when loading the configuration for
+// reloading the agent.
+func (a *Agent) RunningOutputs() []*models.RunningOutput {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	if a.running == nil {
+		return nil
+	}
+
+	unit := a.running.outputs
+	unit.RLock()
+	defer unit.RUnlock()
+	return slices.Clone(unit.outputs)
+}
+
+// Reload applies the given configuration to the running agent by only stopping
+// and starting the plugins with a changed configuration. All other plugins
+// keep running, i.e. outputs keep their buffers and connections. Outputs are
+// matched when loading the configuration, see config.Config.ReuseOutputs.
+//
+// Processors and aggregators form a chain, so the whole chain is replaced if
+// any of those plugins changed. The state of stateful plugins is transferred
+// to the new instance with the same ID in this case.
+//
+// ErrRestartRequired is returned if the changes cannot be applied, e.g. due to
+// changed agent settings or global tags. Inputs failing to start are skipped
+// and their errors are returned after applying all other changes. For other
+// errors, the agent might be partially reloaded and should be restarted as well.
+func (a *Agent) Reload(cfg *config.Config) error {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	r := a.running
+	if r == nil || r.ctx.Err() != nil {
+		releaseOutputs(addedOutputs(nil, cfg.Outputs))
+		releasePipelineOutputs(cfg)
+		return fmt.Errorf("%w: agent is not running", ErrRestartRequired)
+	}
+
+	// Determine the changed plugins
+	r.inputs.Lock()
+	inputs, startInputs, stopInputs := diffPlugins(r.inputs.inputs, cfg.Inputs)
+	r.inputs.Unlock()
+
+	r.outputs.RLock()
+	startOutputs := addedOutputs(r.outputs.outputs, cfg.Outputs)
+	stopOutputs := addedOutputs(cfg.Outputs, r.outputs.outputs)
+	r.outputs.RUnlock()
+
+	replaceStage := !samePlugins(a.Config.Processors, cfg.Processors) ||
+		!samePlugins(a.Config.AggProcessors, cfg.AggProcessors) ||
+		!samePlugins(a.Config.Aggregators, cfg.Aggregators)
+
+	if err := a.checkReloadable(cfg); err != nil {
+		releaseOutputs(startOutputs)
+		releasePipelineOutputs(cfg)
+		return err
+	}
+
+	replaceRouter := !sameRouting(a.Config.Router, cfg.Router)
+
+	if len(startInputs) == 0 && len(stopInputs) == 0 && len(startOutputs) == 0 && len(stopOutputs) == 0 &&
+		!replaceStage && !replaceRouter {
+		log.Printf("I! [agent] No plugin changes found")
+		return nil
+	}
+
+	// Initialize all new plugins before touching the running agent to be
+	// able to keep the current plugins if the new configuration is invalid.
+	if err := a.initReloadedPlugins(cfg, startInputs, startOutputs, replaceStage); err != nil {
+		releaseOutputs(startOutputs)
+		return err
+	}
+
+	// Connect the new outputs first to not lose any metrics
+	log.Printf("D! [agent] Connecting new outputs")
+	connected := make([]*models.RunningOutput, 0, len(startOutputs))
+	for i, output := range startOutputs {
+		if err := a.connectOutput(r.ctx, output); err != nil {
+			var fatalErr *internal.FatalError
+			if errors.As(err, &fatalErr) {
+				log.Printf("I! [agent] Failed to connect to [%s], error was %q;  shutting down plugin...", output.LogName(), err)
+				output.Close()
+				continue
+			}
+
+			stopRunningOutputs(connected)
+			releaseOutputs(startOutputs[i:])
+			return fmt.Errorf("connecting output %s: %w", output.LogName(), err)
+		}
+		connected = append(connected, output)
+	}
+
+	r.outputs.Lock()
+	if r.outputs.closed {
+		r.outputs.Unlock()
+		stopRunningOutputs(connected)
+		return fmt.Errorf("%w: agent is shutting down", ErrRestartRequired)
+	}
+	for _, output := range connected {
+		log.Printf("I! [agent] Starting output %s", output.LogName())
+		a.startFlushLoop(r.outputs, output)
+		r.outputs.outputs = append(r.outputs.outputs, output)
+	}
+	if replaceRouter {
+		log.Printf("I! [agent] Replacing routing table")
+		r.outputs.router = cfg.Router
+		a.Config.Router = cfg.Router
+	}
+	r.outputs.Unlock()
+
+	// Exchange the processors and aggregators
+	if replaceStage {
+		log.Printf("I! [agent] Replacing processors and aggregators")
+		if err := a.replaceStage(r, cfg); err != nil {
+			return err
+		}
+		a.Config.Processors = cfg.Processors
+		a.Config.AggProcessors = cfg.AggProcessors
+		a.Config.Aggregators = cfg.Aggregators
+	}
+
+	// Exchange the inputs, skipping the ones failing to start to complete
+	// the reload with the remaining plugins
+	for _, input := range stopInputs {
+		log.Printf("I! [agent] Stopping input %s", input.LogName())
+		a.stopGatherLoop(r.inputs, input)
+	}
+	var errs []error
+	startedInputs := make([]*models.RunningInput, 0, len(startInputs))
+	skipped := make(map[*models.RunningInput]bool)
+	for _, input := range startInputs {
+		log.Printf("I! [agent] Starting input %s", input.LogName())
+		started, err := startInput(r.inputs.dst, input)
+		if err != nil {
+			log.Printf("E! [agent] %v", err)
+			errs = append(errs, err)
+		}
+		if !started {
+			skipped[input] = true
+			continue
+		}
+
+		r.inputs.Lock()
+		if r.inputs.closed {
+			r.inputs.Unlock()
+			input.Stop()
+			return fmt.Errorf("%w: agent is shutting down", ErrRestartRequired)
+		}
+		r.inputs.inputs = append(r.inputs.inputs, input)
+		a.startGatherLoop(r.ctx, r.startTime, r.inputs, input)
+		r.inputs.Unlock()
+		startedInputs = append(startedInputs, input)
+	}
+	a.Config.Inputs = slices.DeleteFunc(inputs, func(input *models.RunningInput) bool { return skipped[input] })
+
+	// Remove the outputs after stopping the inputs to get all metrics
+	for _, output := range stopOutputs {
+		log.Printf("I! [agent] Stopping output %s", output.LogName())
+		a.stopFlushLoop(r.outputs, output)
+	}
+	a.Config.Outputs = cfg.Outputs
+
+	// Keep track of the stateful plugins
+	if a.Config.Persister != nil {
+		unregisterStatefulPlugins(a.Config.Persister, stopInputs)
+		unregisterStatefulPlugins(a.Config.Persister, stopOutputs)
+		if err := registerStatefulPlugins(a.Config.Persister, startedInputs); err != nil {
+			return err
+		}
+		if err := registerStatefulPlugins(a.Config.Persister, startOutputs); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("I! [agent] Reloaded plugins: started %d and stopped %d inputs, started %d and stopped %d outputs",
+		len(startedInputs), len(stopInputs), len(connected), len(stopOutputs))
+	if len(errs) > 0 {
+		return fmt.Errorf("reloading inputs failed: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// checkReloadable checks if the given configuration can be applied to the
+// running agent.
+func (a *Agent) checkReloadable(cfg *config.Config) error {
+	// Apply the same default as the agent to be able to compare the settings
+	if cfg.Agent.SkipProcessorsAfterAggregators == nil {
+		skipProcessorsAfterAggregators := false
+		cfg.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
+	}
+
+	// The API token holds protected memory so compare its content separately
+	current, updated := *a.Config.Agent, *cfg.Agent
+	current.APIToken, updated.APIToken = config.Secret{}, config.Secret{}
+	if !reflect.DeepEqual(current, updated) {
+		return fmt.Errorf("%w: agent settings changed", ErrRestartRequired)
+	}
+	if equal, err := secretsEqual(&a.Config.Agent.APIToken, &cfg.Agent.APIToken); err != nil {
+		return fmt.Errorf("comparing API tokens failed: %w", err)
+	} else if !equal {
+		return fmt.Errorf("%w: agent settings changed", ErrRestartRequired)
+	}
+	if !maps.Equal(a.Config.Tags, cfg.Tags) {
+		return fmt.Errorf("%w: global tags changed", ErrRestartRequired)
+	}
+	if !a.Config.SecretStoresEqual(cfg) {
+		return fmt.Errorf("%w: secret-stores changed", ErrRestartRequired)
+	}
+	if len(a.Config.Pipelines) > 0 || len(cfg.Pipelines) > 0 {
+		return fmt.Errorf("%w: pipelines configured", ErrRestartRequired)
+	}
+	return nil
+}
+
+// secretsEqual compares the content of the two secrets.
+func secretsEqual(a, b *config.Secret) (bool, error) {
+	if a.Empty() || b.Empty() {
+		return a.Empty() == b.Empty(), nil
+	}
+
+	bufA, err := a.Get()
+	if err != nil {
+		return false, err
+	}
+	defer bufA.Destroy()
+
+	bufB, err := b.Get()
+	if err != nil {
+		return false, err
+	}
+	defer bufB.Destroy()
+
+	return subtle.ConstantTimeCompare(bufA.Bytes(), bufB.Bytes()) == 1, nil
+}
+
+// initReloadedPlugins runs the Init function on the plugins to be started.
+func (a *Agent) initReloadedPlugins(cfg *config.Config, inputs []*models.RunningInput, outputs []*models.RunningOutput, stage bool) error {
+	for _, input := range inputs {
+		// Share the snmp translator setting with plugins that need it.
+		if tp, ok := input.Input.(snmp.TranslatorPlugin); ok {
+			tp.SetTranslator(a.Config.Agent.SnmpTranslator)
+		}
+		if err := input.Init(); err != nil {
+			return fmt.Errorf("could not initialize input %s: %w", input.LogName(), err)
+		}
+	}
+	if stage {
+		for _, processor := range cfg.Processors {
+			if err := processor.Init(); err != nil {
+				return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
+			}
+		}
+		for _, aggregator := range cfg.Aggregators {
+			if err := aggregator.Init(); err != nil {
+				return fmt.Errorf("could not initialize aggregator %s: %w", aggregator.LogName(), err)
+			}
+		}
+		if !*a.Config.Agent.SkipProcessorsAfterAggregators {
+			for _, processor := range cfg.AggProcessors {
+				if err := processor.Init(); err != nil {
+					return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
+				}
+			}
+		}
+	}
+	for _, output := range outputs {
+		if err := output.Init(); err != nil {
+			return fmt.Errorf("could not initialize output %s: %w", output.LogName(), err)
+		}
+	}
+	if cfg.Router != nil {
+		if err := initRouter(cfg.Router, cfg.Outputs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameRouting checks if the routing tables contain the same routes
+func sameRouting(a, b *models.Router) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !slices.Equal(a.Default, b.Default) || len(a.Routes) != len(b.Routes) {
+		return false
+	}
+	for i, route := range a.Routes {
+		other := b.Routes[i]
+		if route.Name != other.Name || route.MetricPass != other.MetricPass || !slices.Equal(route.Outputs, other.Outputs) {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceStage drains the current processors and aggregators and replaces them
+// by the ones in the given configuration. While replacing the stage, the
+// metrics of the inputs are held back.
+func (a *Agent) replaceStage(r *runningUnits, cfg *config.Config) error {
+	unit := r.pipeline
+	unit.Lock()
+	defer unit.Unlock()
+
+	if unit.closed {
+		return fmt.Errorf("%w: agent is shutting down", ErrRestartRequired)
+	}
+
+	// Drain the current stage to get the final states
+	close(unit.stage.src)
+	<-unit.stage.done
+
+	// Transfer the state of the plugins and keep track of the stateful plugins
+	transferStates(a.Config.Processors, cfg.Processors)
+	transferStates(a.Config.AggProcessors, cfg.AggProcessors)
+	transferStates(a.Config.Aggregators, cfg.Aggregators)
+	if a.Config.Persister != nil {
+		unregisterStatefulPlugins(a.Config.Persister, a.Config.Processors)
+		unregisterStatefulPlugins(a.Config.Persister, a.Config.AggProcessors)
+		unregisterStatefulPlugins(a.Config.Persister, a.Config.Aggregators)
+		if err := registerStatefulPlugins(a.Config.Persister, cfg.Processors); err != nil {
+			return err
+		}
+		if err := registerStatefulPlugins(a.Config.Persister, cfg.AggProcessors); err != nil {
+			return err
+		}
+		if err := registerStatefulPlugins(a.Config.Persister, cfg.Aggregators); err != nil {
+			return err
+		}
+	}
+
+	stage, err := a.startStage(cfg.Processors, cfg.AggProcessors, cfg.Aggregators)
+	if err != nil {
+		// Keep the metrics flowing to the outputs until the agent is restarted
+		//nolint:errcheck // starting a stage without plugins cannot fail
+		stage, _ = a.startStage(nil, nil, nil)
+	}
+	unit.stage = stage
+	a.runStageAsync(r.startTime, unit, stage)
+
+	return err
+}
+
+// diffPlugins matches the configured plugins to the running ones by their ID.
+// It returns the plugins to use, i.e. the running instance for unchanged
+// plugins and the configured instance otherwise, as well as the plugins to
+// start and to stop.
+func diffPlugins[T interface {
+	comparable
+	ID() string
+}](running, configured []T) (merged, added, removed []T) {
+	pool := make(map[string][]T, len(running))
+	for _, p := range running {
+		pool[p.ID()] = append(pool[p.ID()], p)
+	}
+
+	kept := make(map[T]bool, len(running))
+	merged = make([]T, 0, len(configured))
+	for _, p := range configured {
+		id := p.ID()
+		if candidates := pool[id]; len(candidates) > 0 {
+			merged = append(merged, candidates[0])
+			kept[candidates[0]] = true
+			pool[id] = candidates[1:]
+			continue
+		}
+		merged = append(merged, p)
+		added = append(added, p)
+	}
+
+	for _, p := range running {
+		if !kept[p] {
+			removed = append(removed, p)
+		}
+	}
+	return merged, added, removed
+}
+
+// samePlugins returns true if both lists contain plugins with the same ID in
+// the same order.
+func samePlugins[T interface{ ID() string }](a, b []T) bool {
+	return slices.EqualFunc(a, b, func(x, y T) bool { return x.ID() == y.ID() })
+}
+
+// addedOutputs returns the outputs in current not contained in previous.
+func addedOutputs(previous, current []*models.RunningOutput) []*models.RunningOutput {
+	var added []*models.RunningOutput
+	for _, output := range current {
+		if !slices.Contains(previous, output) {
+			added = append(added, output)
+		}
+	}
+	return added
+}
+
+// releaseOutputs frees the resources of outputs never connected.
+func releaseOutputs(outputs []*models.RunningOutput) {
+	for _, output := range outputs {
+		output.Release()
+	}
+}
+
+// releasePipelineOutputs releases the outputs of the pipelines of a
+// configuration not applied to the agent.
+func releasePipelineOutputs(cfg *config.Config) {
+	for _, p := range cfg.Pipelines {
+		releaseOutputs(p.Outputs)
+	}
+}
+
+// statefulPlugin returns the stateful plugin wrapped by the given running
+// plugin if any.
+func statefulPlugin(plugin any) (telegraf.StatefulPlugin, bool) {
+	switch p := plugin.(type) {
+	case *models.RunningInput:
+		plugin = p.Input
+	case *models.RunningProcessor:
+		plugin = p.Processor
+		if up, ok := p.Processor.(processors.HasUnwrap); ok {
+			plugin = up.Unwrap()
+		}
+	case *models.RunningAggregator:
+		plugin = p.Aggregator
+	case *models.RunningOutput:
+		plugin = p.Output
+	}
+	sp, ok := plugin.(telegraf.StatefulPlugin)
+	return sp, ok
+}
+
+// transferStates sets the state of the previous plugin instances to the
+// current instances with the same ID.
+func transferStates[T interface{ ID() string }](previous, current []T) {
+	states := make(map[string][]any, len(previous))
+	for _, p := range previous {
+		if sp, ok := statefulPlugin(p); ok {
+			states[p.ID()] = append(states[p.ID()], sp.GetState())
+		}
+	}
+
+	for _, p := range current {
+		sp, ok := statefulPlugin(p)
+		if !ok || len(states[p.ID()]) == 0 {
+			continue
+		}
+		state := states[p.ID()][0]
+		states[p.ID()] = states[p.ID()][1:]
+		if err := sp.SetState(state); err != nil {
+			log.Printf("E! [agent] Transferring state of plugin %q failed: %v", p.ID(), err)
+		}
+	}
+}
+
+// registerStatefulPlugins registers the stateful plugins at the persister.
+func registerStatefulPlugins[T interface{ ID() string }](p *persister.Persister, plugins []T) error {
+	for _, plugin := range plugins {
+		sp, ok := statefulPlugin(plugin)
+		if !ok {
+			continue
+		}
+		if err := p.Register(plugin.ID(), sp); err != nil {
+			return fmt.Errorf("could not register plugin %q: %w", plugin.ID(), err)
+		}
+	}
+	return nil
+}
+
+// unregisterStatefulPlugins removes the stateful plugins from the persister.
+func unregisterStatefulPlugins[T interface{ ID() string }](p *persister.Persister, plugins []T) {
+	for _, plugin := range plugins {
+		if _, ok := statefulPlugin(plugin); ok {
+			p.Unregister(plugin.ID())
+		}
+	}
+}
@@ -0,0 +1,470 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+func TestReloadUnchangedOutputKept(t *testing.T) {
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[inputs.reload_test]]
+		  value = "b"
+		[[outputs.reload_test]]
+	`)
+	require.Len(t, cfg.Outputs, 1)
+	output := cfg.Outputs[0]
+	plugin := output.Output.(*reloadTestOutput)
+	inputA := cfg.Inputs[0]
+	inputB := cfg.Inputs[1]
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return plugin.received("b")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Reload with a changed input and an unchanged output
+	reloaded := loadReloadConfig(t, agent.RunningOutputs(), `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[inputs.reload_test]]
+		  value = "c"
+		[[outputs.reload_test]]
+	`)
+	require.Same(t, output, reloaded.Outputs[0])
+	require.NoError(t, agent.Reload(reloaded))
+
+	// The unchanged plugins must be kept, the changed input replaced
+	require.Same(t, inputA, agent.Config.Inputs[0])
+	require.NotSame(t, inputB, agent.Config.Inputs[1])
+	require.True(t, inputB.Input.(*reloadTestInput).isStopped())
+	require.False(t, inputA.Input.(*reloadTestInput).isStopped())
+	require.Eventually(t, func() bool {
+		return plugin.received("c")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	plugin.Lock()
+	require.Equal(t, 1, plugin.connects)
+	require.Equal(t, 0, plugin.closes)
+	plugin.Unlock()
+
+	cancel()
+	require.NoError(t, <-done)
+
+	plugin.Lock()
+	require.Equal(t, 1, plugin.closes)
+	plugin.Unlock()
+}
+
+func TestReloadOutputExchanged(t *testing.T) {
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.reload_test]]
+		  alias = "first"
+	`)
+	first := cfg.Outputs[0].Output.(*reloadTestOutput)
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return first.received("a")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	reloaded := loadReloadConfig(t, agent.RunningOutputs(), `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.reload_test]]
+		  alias = "second"
+	`)
+	second := reloaded.Outputs[0].Output.(*reloadTestOutput)
+	require.NoError(t, agent.Reload(reloaded))
+
+	first.Lock()
+	require.Equal(t, 1, first.closes)
+	first.Unlock()
+	require.Eventually(t, func() bool {
+		return second.received("a")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestReloadInputStartFailure(t *testing.T) {
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.reload_test]]
+		  alias = "first"
+	`)
+	input := cfg.Inputs[0].Input.(*reloadTestInput)
+	first := cfg.Outputs[0].Output.(*reloadTestOutput)
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return first.received("a")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	reloaded := loadReloadConfig(t, agent.RunningOutputs(), `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "b"
+		  fail = true
+		[[inputs.reload_test]]
+		  value = "c"
+		[[outputs.reload_test]]
+		  alias = "second"
+	`)
+	started := reloaded.Inputs[1]
+	second := reloaded.Outputs[0].Output.(*reloadTestOutput)
+	require.ErrorContains(t, agent.Reload(reloaded), "start failed")
+
+	// All other changes must be applied and only started inputs be recorded
+	require.True(t, input.isStopped())
+	first.Lock()
+	require.Equal(t, 1, first.closes)
+	first.Unlock()
+	require.Eventually(t, func() bool {
+		return second.received("c")
+	}, 5*time.Second, 50*time.Millisecond)
+	require.False(t, second.received("b"))
+	require.Equal(t, []*models.RunningInput{started}, agent.Config.Inputs)
+	require.Equal(t, reloaded.Outputs, agent.Config.Outputs)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestReloadProcessorsReplaced(t *testing.T) {
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.reload_test]]
+	`)
+	plugin := cfg.Outputs[0].Output.(*reloadTestOutput)
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return plugin.received("a")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	reloaded := loadReloadConfig(t, agent.RunningOutputs(), `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[processors.override]]
+		  [processors.override.tags]
+		    value = "overridden"
+		[[outputs.reload_test]]
+	`)
+	require.NoError(t, agent.Reload(reloaded))
+	require.Eventually(t, func() bool {
+		return plugin.received("overridden")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestReloadRequiresRestart(t *testing.T) {
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.reload_test]]
+	`)
+
+	// Reloading an agent not running requires a restart
+	agent := NewAgent(cfg)
+	require.ErrorIs(t, agent.Reload(cfg), ErrRestartRequired)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return len(agent.RunningOutputs()) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Changing agent settings requires a restart
+	reloaded := loadReloadConfig(t, agent.RunningOutputs(), `
+		[agent]
+		  interval = "200ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.reload_test]]
+	`)
+	require.ErrorIs(t, agent.Reload(reloaded), ErrRestartRequired)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestCheckReloadableAPIToken(t *testing.T) {
+	cfg := loadReloadConfig(t, nil, `
+		[agent]
+		  skip_processors_after_aggregators = false
+		  api_token = "secret"
+		[[inputs.reload_test]]
+		[[outputs.reload_test]]
+	`)
+	agent := NewAgent(cfg)
+
+	same := loadReloadConfig(t, nil, `
+		[agent]
+		  skip_processors_after_aggregators = false
+		  api_token = "secret"
+		[[inputs.reload_test]]
+		[[outputs.reload_test]]
+	`)
+	require.NoError(t, agent.checkReloadable(same))
+
+	changed := loadReloadConfig(t, nil, `
+		[agent]
+		  skip_processors_after_aggregators = false
+		  api_token = "other"
+		[[inputs.reload_test]]
+		[[outputs.reload_test]]
+	`)
+	require.ErrorIs(t, agent.checkReloadable(changed), ErrRestartRequired)
+}
+
+func TestReloadPipelinesRequireRestart(t *testing.T) {
+	data := `
+		[agent]
+		  interval = "100ms"
+		  flush_interval = "100ms"
+		  omit_hostname = true
+		[[inputs.reload_test]]
+		  value = "a"
+		[[outputs.reload_test]]
+		[[pipeline]]
+		  name = "other"
+		  [[pipeline.inputs.reload_test]]
+		    value = "b"
+		  [[pipeline.outputs.reload_test]]
+	`
+	cfg := loadReloadConfig(t, nil, data)
+	require.Len(t, cfg.Pipelines, 1)
+	plugin := cfg.Outputs[0].Output.(*reloadTestOutput)
+	pipelinePlugin := cfg.Pipelines[0].Outputs[0].Output.(*reloadTestOutput)
+
+	agent := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+
+	// The metrics must only reach the outputs of their pipeline
+	require.Eventually(t, func() bool {
+		return plugin.received("a") && pipelinePlugin.received("b")
+	}, 5*time.Second, 50*time.Millisecond)
+	require.False(t, plugin.received("b"))
+	require.False(t, pipelinePlugin.received("a"))
+
+	reloaded := loadReloadConfig(t, agent.RunningOutputs(), data)
+	require.ErrorIs(t, agent.Reload(reloaded), ErrRestartRequired)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestDiffPlugins(t *testing.T) {
+	a1 := &idPlugin{id: "a"}
+	a2 := &idPlugin{id: "a"}
+	b := &idPlugin{id: "b"}
+	c := &idPlugin{id: "c"}
+	newA1 := &idPlugin{id: "a"}
+	newC := &idPlugin{id: "c"}
+	newD := &idPlugin{id: "d"}
+
+	merged, added, removed := diffPlugins(
+		[]*idPlugin{a1, a2, b, c},
+		[]*idPlugin{newA1, newC, newD},
+	)
+	require.Equal(t, []*idPlugin{a1, c, newD}, merged)
+	require.Equal(t, []*idPlugin{newD}, added)
+	require.Equal(t, []*idPlugin{a2, b}, removed)
+}
+
+type idPlugin struct {
+	id string
+}
+
+func (p *idPlugin) ID() string {
+	return p.id
+}
+
+func loadReloadConfig(t *testing.T, reuse []*models.RunningOutput, data string) *config.Config {
+	t.Helper()
+
+	cfg := config.NewConfig()
+	cfg.ReuseOutputs = reuse
+	require.NoError(t, cfg.LoadConfigData([]byte(data), config.EmptySourcePath))
+	return cfg
+}
+
+type reloadTestInput struct {
+	Value string `toml:"value"`
+	Fail  bool   `toml:"fail"`
+
+	stopped bool
+	sync.Mutex
+}
+
+func (*reloadTestInput) SampleConfig() string {
+	return ""
+}
+
+func (i *reloadTestInput) Start(telegraf.Accumulator) error {
+	if i.Fail {
+		return errors.New("start failed")
+	}
+	return nil
+}
+
+func (i *reloadTestInput) Stop() {
+	i.Lock()
+	defer i.Unlock()
+	i.stopped = true
+}
+
+func (i *reloadTestInput) isStopped() bool {
+	i.Lock()
+	defer i.Unlock()
+	return i.stopped
+}
+
+func (i *reloadTestInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("test", map[string]interface{}{"value": 42}, map[string]string{"value": i.Value})
+	return nil
+}
+
+type reloadTestOutput struct {
+	connects int
+	closes   int
+	values   map[string]bool
+	sync.Mutex
+}
+
+func (*reloadTestOutput) SampleConfig() string {
+	return ""
+}
+
+func (o *reloadTestOutput) Connect() error {
+	o.Lock()
+	defer o.Unlock()
+	o.connects++
+	return nil
+}
+
+func (o *reloadTestOutput) Close() error {
+	o.Lock()
+	defer o.Unlock()
+	o.closes++
+	return nil
+}
+
+func (o *reloadTestOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	if o.closes > 0 {
+		return errors.New("output closed")
+	}
+	for _, m := range metrics {
+		if v, found := m.GetTag("value"); found {
+			o.values[v] = true
+		}
+	}
+	return nil
+}
+
+func (o *reloadTestOutput) received(value string) bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.values[value]
+}
+
+func init() {
+	inputs.Add("reload_test", func() telegraf.Input {
+		return &reloadTestInput{}
+	})
+	outputs.Add("reload_test", func() telegraf.Output {
+		return &reloadTestOutput{values: make(map[string]bool)}
+	})
+}
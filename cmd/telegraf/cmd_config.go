@@ -11,12 +11,17 @@ import (
 	"path/filepath"
 
 	"github.com/fatih/color"
+	"github.com/google/go-cmp/cmp"
 	"github.com/urfave/cli/v2"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/migrations"
+	parsers_influx "github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil/metricdiff"
 )
 
 func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) []*cli.Command {
@@ -41,15 +46,29 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 		The 'check' command reads the configuration files specified via '--config' or
 		'--config-directory' and tries to initialize, but not start, the plugins.
 		Syntax and semantic errors detectable without starting the plugins will
-		be reported.
+		be reported. Unknown options, options of the wrong type and out-of-range
+		values are reported for all plugins with their file and line.
 		If no configuration file is	explicitly specified the command reads the
 		default locations and uses those configuration files.
 
 		To check the file 'mysettings.conf' use
 
 		> telegraf config check --config mysettings.conf
+
+		To review the configuration with all templates expanded use
+
+		> telegraf config check --config mysettings.conf --expanded
+
+		Please note, the expanded configuration contains the values of the
+		environment variables used in the configuration files. Settings holding
+		secrets are masked.
 		`,
-					Flags: configHandlingFlags,
+					Flags: append([]cli.Flag{
+						&cli.BoolFlag{
+							Name:  "expanded",
+							Usage: "print the configuration with expanded templates",
+						},
+					}, configHandlingFlags...),
 					Action: func(cCtx *cli.Context) error {
 						// Setup logging
 						logConfig := &logger.Config{Debug: cCtx.Bool("debug")}
@@ -58,33 +77,30 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 						}
 
 						// Collect the given configuration files
-						configFiles := cCtx.StringSlice("config")
-						configDir := cCtx.StringSlice("config-directory")
-						for _, fConfigDirectory := range configDir {
-							files, err := config.WalkDirectory(fConfigDirectory)
-							if err != nil {
-								return err
-							}
-							configFiles = append(configFiles, files...)
-						}
-
-						// If no "config" or "config-directory" flag(s) was
-						// provided we should load default configuration files
-						if len(configFiles) == 0 {
-							paths, err := config.GetDefaultConfigPath()
-							if err != nil {
-								return err
-							}
-							configFiles = paths
+						configFiles, err := collectConfigFiles(cCtx.StringSlice("config"), cCtx.StringSlice("config-directory"))
+						if err != nil {
+							return err
 						}
 
 						// Load the config and try to initialize the plugins
 						c := config.NewConfig()
 						c.Agent.Quiet = cCtx.Bool("quiet")
+						c.KeepExpandedConfig = cCtx.Bool("expanded")
+						c.ValidateSchema = true
 						if err := c.LoadAll(configFiles...); err != nil {
 							return err
 						}
 
+						if c.KeepExpandedConfig {
+							expanded, err := c.ExpandedConfig()
+							if err != nil {
+								return err
+							}
+							if _, err := outputBuffer.Write(expanded); err != nil {
+								return err
+							}
+						}
+
 						ag := agent.NewAgent(c)
 
 						// Set the default for processor skipping
@@ -99,6 +115,190 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 						return ag.InitPlugins()
 					},
 				},
+				{
+					Name:  "print",
+					Usage: "print the configuration loaded from the configuration file(s)",
+					Description: `
+		The 'print' command reads the configuration files specified via '--config'
+		or '--config-directory', or the default locations if none is specified,
+		and prints the loaded configuration with all templates expanded.
+
+		To print the configuration actually used when running the plugins use
+
+		> telegraf config print --config mysettings.conf --effective
+
+		The effective configuration lists all settings of each plugin including
+		the plugin's defaults, the general plugin options resolved against the
+		agent settings, e.g. 'flush_interval' or 'metric_batch_size', and the
+		generated plugin ID. Secrets are masked.
+
+		Please note, the printed configuration contains the values of the
+		environment variables used in the configuration files. Settings holding
+		secrets are masked.
+		`,
+					Flags: append([]cli.Flag{
+						&cli.BoolFlag{
+							Name:  "effective",
+							Usage: "print the effective settings of all plugins",
+						},
+					}, configHandlingFlags...),
+					Action: func(cCtx *cli.Context) error {
+						// Setup logging
+						logConfig := &logger.Config{Debug: cCtx.Bool("debug")}
+						if err := logger.SetupLogging(logConfig); err != nil {
+							return err
+						}
+
+						// Collect the given configuration files
+						configFiles, err := collectConfigFiles(cCtx.StringSlice("config"), cCtx.StringSlice("config-directory"))
+						if err != nil {
+							return err
+						}
+
+						// Load the config without initializing the plugins
+						c := config.NewConfig()
+						c.Agent.Quiet = cCtx.Bool("quiet")
+						c.KeepExpandedConfig = !cCtx.Bool("effective")
+						if err := c.LoadAll(configFiles...); err != nil {
+							return err
+						}
+
+						var out []byte
+						if cCtx.Bool("effective") {
+							out, err = c.EffectiveConfig()
+						} else {
+							out, err = c.ExpandedConfig()
+						}
+						if err != nil {
+							return err
+						}
+						_, err = outputBuffer.Write(out)
+						return err
+					},
+				},
+				{
+					Name:  "test",
+					Usage: "test the processing of metrics by the configured processors and aggregators",
+					Description: `
+The 'test' command reads the configuration files specified via '--config' or
+'--config-directory' and sends the metrics of the line-protocol file given via
+'--input' through the configured processors and aggregators. Inputs and outputs
+of the configuration are not used. The resulting metrics are compared to the
+metrics in the line-protocol file given via '--expected' and the differences
+are reported. Without '--expected' the resulting metrics are printed as
+line-protocol, e.g. to create the expected file.
+All input metrics are aggregated in a single aggregation window. Aggregated
+metrics usually carry the time of the test run, so use '--ignore-time' when
+testing aggregators.
+
+To test the processors in 'processors.conf' use
+
+> telegraf config test --config processors.conf --input input.influx --expected expected.influx
+`,
+					Flags: append([]cli.Flag{
+						&cli.StringFlag{
+							Name:     "input",
+							Usage:    "line-protocol file with the metrics to process",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:  "expected",
+							Usage: "line-protocol file with the expected metrics",
+						},
+						&cli.BoolFlag{
+							Name:  "sort",
+							Usage: "sort the metrics before comparing",
+						},
+						&cli.BoolFlag{
+							Name:  "ignore-time",
+							Usage: "do not compare the timestamps of the metrics",
+						},
+						&cli.StringSliceFlag{
+							Name:  "ignore-tag",
+							Usage: "do not compare the given tag of the metrics",
+						},
+						&cli.StringSliceFlag{
+							Name:  "ignore-field",
+							Usage: "do not compare the given field of the metrics",
+						},
+					}, configHandlingFlags...),
+					Action: func(cCtx *cli.Context) error {
+						// Setup logging
+						logConfig := &logger.Config{Debug: cCtx.Bool("debug")}
+						if err := logger.SetupLogging(logConfig); err != nil {
+							return err
+						}
+
+						// Collect the given configuration files
+						configFiles, err := collectConfigFiles(cCtx.StringSlice("config"), cCtx.StringSlice("config-directory"))
+						if err != nil {
+							return err
+						}
+
+						// Read the metrics before loading the config to fail early
+						input, err := readLineProtocolFile(cCtx.String("input"))
+						if err != nil {
+							return err
+						}
+						var expected []telegraf.Metric
+						if fn := cCtx.String("expected"); fn != "" {
+							if expected, err = readLineProtocolFile(fn); err != nil {
+								return err
+							}
+						}
+
+						// Load the config and process the metrics
+						c := config.NewConfig()
+						c.Agent.Quiet = cCtx.Bool("quiet")
+						if err := c.LoadAll(configFiles...); err != nil {
+							return err
+						}
+
+						ag := agent.NewAgent(c)
+						if err := ag.InitProcessing(); err != nil {
+							return err
+						}
+						actual, err := ag.ProcessMetrics(input)
+						if err != nil {
+							return err
+						}
+
+						// Print the metrics if there is nothing to compare
+						if cCtx.String("expected") == "" {
+							serializer := &influx.Serializer{SortFields: true, UintSupport: true}
+							if err := serializer.Init(); err != nil {
+								return err
+							}
+							octets, err := serializer.SerializeBatch(actual)
+							if err != nil {
+								return err
+							}
+							_, err = outputBuffer.Write(octets)
+							return err
+						}
+
+						var options []cmp.Option
+						if cCtx.Bool("sort") {
+							options = append(options, metricdiff.SortMetrics())
+						}
+						if cCtx.Bool("ignore-time") {
+							options = append(options, metricdiff.IgnoreTime())
+						}
+						if tags := cCtx.StringSlice("ignore-tag"); len(tags) > 0 {
+							options = append(options, metricdiff.IgnoreTags(tags...))
+						}
+						if fields := cCtx.StringSlice("ignore-field"); len(fields) > 0 {
+							options = append(options, metricdiff.IgnoreFields(fields...))
+						}
+
+						if diff := metricdiff.Diff(expected, actual, options...); diff != "" {
+							fmt.Fprintf(outputBuffer, "Metrics differ from the expected metrics (-expected +actual):\n%s", diff)
+							return errors.New("test failed")
+						}
+						fmt.Fprintf(outputBuffer, "Test passed, %d metrics match the expected metrics\n", len(actual))
+						return nil
+					},
+				},
 				{
 					Name:  "create",
 					Usage: "create a full sample configuration and show it",
@@ -165,24 +365,9 @@ To migrate the file 'mysettings.conf' use
 						log.Printf("%d plugin migration(s) available", len(migrations.PluginMigrations))
 
 						// Collect the given configuration files
-						configFiles := cCtx.StringSlice("config")
-						configDir := cCtx.StringSlice("config-directory")
-						for _, fConfigDirectory := range configDir {
-							files, err := config.WalkDirectory(fConfigDirectory)
-							if err != nil {
-								return err
-							}
-							configFiles = append(configFiles, files...)
-						}
-
-						// If no "config" or "config-directory" flag(s) was
-						// provided we should load default configuration files
-						if len(configFiles) == 0 {
-							paths, err := config.GetDefaultConfigPath()
-							if err != nil {
-								return err
-							}
-							configFiles = paths
+						configFiles, err := collectConfigFiles(cCtx.StringSlice("config"), cCtx.StringSlice("config-directory"))
+						if err != nil {
+							return err
 						}
 
 						for _, fn := range configFiles {
@@ -238,3 +423,21 @@ To migrate the file 'mysettings.conf' use
 		},
 	}
 }
+
+// readLineProtocolFile parses the metrics of the given line-protocol file
+func readLineProtocolFile(filename string) ([]telegraf.Metric, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q failed: %w", filename, err)
+	}
+
+	parser := &parsers_influx.Parser{}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	metrics, err := parser.Parse(buf)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %w", filename, err)
+	}
+	return metrics, nil
+}
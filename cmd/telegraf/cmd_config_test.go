@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandConfigTest(t *testing.T) {
+	dir := t.TempDir()
+
+	cfgFile := filepath.Join(dir, "telegraf.conf")
+	cfg := `
+[agent]
+  skip_processors_after_aggregators = true
+[[processors.override]]
+  namepass = ["cpu"]
+  [processors.override.tags]
+    env = "test"
+[[aggregators.minmax]]
+  period = "30s"
+  namepass = ["cpu"]
+`
+	require.NoError(t, os.WriteFile(cfgFile, []byte(cfg), 0640))
+
+	inputFile := filepath.Join(dir, "input.influx")
+	input := "cpu value=1 1000000000\nmem value=2 2000000000\ncpu value=3 3000000000\n"
+	require.NoError(t, os.WriteFile(inputFile, []byte(input), 0640))
+
+	// Print the resulting metrics without expectation
+	out := new(bytes.Buffer)
+	args := []string{os.Args[0], "config", "test", "--config", cfgFile, "--input", inputFile}
+	require.NoError(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()))
+	require.Contains(t, out.String(), "cpu,env=test value=1 1000000000\n")
+	require.Contains(t, out.String(), "mem value=2 2000000000\n")
+	require.Contains(t, out.String(), "cpu,env=test value_max=3,value_min=1 ")
+
+	// Compare to the expected metrics
+	expectedFile := filepath.Join(dir, "expected.influx")
+	expected := "cpu,env=test value=1 1000000000\n" +
+		"mem value=2 2000000000\n" +
+		"cpu,env=test value=3 3000000000\n" +
+		"cpu,env=test value_max=3,value_min=1 0\n"
+	require.NoError(t, os.WriteFile(expectedFile, []byte(expected), 0640))
+
+	out.Reset()
+	args = []string{
+		os.Args[0], "config", "test", "--config", cfgFile, "--input", inputFile, "--expected", expectedFile,
+		"--ignore-time",
+	}
+	require.NoError(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()))
+	require.Contains(t, out.String(), "Test passed, 4 metrics match the expected metrics")
+
+	// Report differences
+	expected = "cpu,env=prod value=1 1000000000\n" +
+		"cpu,env=test value=3 3000000000\n" +
+		"mem value=2 2000000000\n" +
+		"cpu,env=test value_max=3,value_min=1 0\n"
+	require.NoError(t, os.WriteFile(expectedFile, []byte(expected), 0640))
+
+	out.Reset()
+	args = []string{
+		os.Args[0], "config", "test", "--config", cfgFile, "--input", inputFile, "--expected", expectedFile,
+		"--ignore-time", "--sort",
+	}
+	require.ErrorContains(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()), "test failed")
+	require.Contains(t, out.String(), `"prod"`)
+}
+
+func TestCommandConfigTestDefaults(t *testing.T) {
+	dir := t.TempDir()
+
+	// Leave 'skip_processors_after_aggregators' unset and add an output
+	// failing to initialize as inputs and outputs must not be used
+	cfgFile := filepath.Join(dir, "telegraf.conf")
+	cfg := `
+[[processors.override]]
+  namepass = ["cpu*"]
+  name_suffix = "_processed"
+[[aggregators.minmax]]
+  period = "30s"
+  namepass = ["cpu*"]
+[[outputs.file]]
+  compression_algorithm = "invalid"
+`
+	require.NoError(t, os.WriteFile(cfgFile, []byte(cfg), 0640))
+
+	inputFile := filepath.Join(dir, "input.influx")
+	require.NoError(t, os.WriteFile(inputFile, []byte("cpu value=1 1000000000\n"), 0640))
+
+	// The processors also apply to the aggregated metrics by default
+	out := new(bytes.Buffer)
+	args := []string{os.Args[0], "config", "test", "--config", cfgFile, "--input", inputFile}
+	require.NoError(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()))
+	require.Contains(t, out.String(), "cpu_processed value=1 1000000000\n")
+	require.Contains(t, out.String(), "cpu_processed_processed value_max=1,value_min=1 ")
+}
+
+func TestCommandConfigCheckExpanded(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "telegraf.conf")
+	cfg := `
+[templates.common]
+  interval = "30s"
+  [templates.common.tags]
+    env = "test"
+[[processors.override]]
+  inherit = ["common"]
+`
+	require.NoError(t, os.WriteFile(cfgFile, []byte(cfg), 0640))
+
+	out := new(bytes.Buffer)
+	args := []string{os.Args[0], "config", "check", "--config", cfgFile, "--expanded"}
+	require.NoError(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()))
+	require.Contains(t, out.String(), "[[processors.override]]\ninterval = \"30s\"\n\n[processors.override.tags]\nenv = \"test\"\n")
+	require.NotContains(t, out.String(), "inherit")
+
+	// Without the flag the configuration is not printed
+	out.Reset()
+	args = []string{os.Args[0], "config", "check", "--config", cfgFile}
+	require.NoError(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()))
+	require.NotContains(t, out.String(), "processors.override")
+}
+
+func TestCommandConfigCheckSchema(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "telegraf.conf")
+	cfg := `
+[[processors.override]]
+  name_overide = "test"
+  order = "first"
+`
+	require.NoError(t, os.WriteFile(cfgFile, []byte(cfg), 0640))
+
+	out := new(bytes.Buffer)
+	args := []string{os.Args[0], "config", "check", "--config", cfgFile}
+	err := runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf())
+	require.ErrorContains(t, err, cfgFile+":3: processors.override.name_overide: unknown option")
+	require.ErrorContains(t, err, cfgFile+":4: processors.override.order: expected integer but got string")
+}
+
+func TestCommandConfigPrintEffective(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "telegraf.conf")
+	cfg := `
+[agent]
+  flush_interval = "20s"
+[[processors.override]]
+  name_override = "test"
+  order = 2
+`
+	require.NoError(t, os.WriteFile(cfgFile, []byte(cfg), 0640))
+
+	out := new(bytes.Buffer)
+	args := []string{os.Args[0], "config", "print", "--config", cfgFile, "--effective"}
+	require.NoError(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()))
+	require.Contains(t, out.String(), "[agent]\n")
+	require.Contains(t, out.String(), `flush_interval = "20s"`)
+	require.Contains(t, out.String(), "# ID: ")
+	require.Contains(t, out.String(), "[[processors.override]]\nname_override = \"test\"\nname_prefix = \"\"\nname_suffix = \"\"\norder = 2\n")
+
+	// Without the flag the loaded configuration is printed
+	out.Reset()
+	args = []string{os.Args[0], "config", "print", "--config", cfgFile}
+	require.NoError(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()))
+	require.Contains(t, out.String(), "[[processors.override]]\nname_override = \"test\"\norder = 2\n")
+	require.NotContains(t, out.String(), "# ID: ")
+}
@@ -2,6 +2,8 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -168,6 +171,42 @@ func getPluginCommands(outputBuffer io.Writer) []*cli.Command {
 						return nil
 					},
 				},
+				{
+					Name:      "schema",
+					Usage:     "Print the JSON Schema of the options of a plugin",
+					ArgsUsage: "<type>.<name>",
+					Description: `
+		The 'schema' command prints the options of the given plugin, e.g.
+		'inputs.cpu', as JSON Schema. Each option contains the Go type, the
+		default value if not empty and deprecation information.
+
+		> telegraf plugins schema outputs.influxdb_v2
+
+		The schema includes the general options of the plugin type. For plugins
+		accepting a data format, the options of the parser or serializer are
+		given by the schema of the respective plugin, e.g. 'parsers.json'.
+		`,
+					Action: func(cCtx *cli.Context) error {
+						if cCtx.NArg() != 1 {
+							return errors.New("expected exactly one plugin in the form <type>.<name>")
+						}
+						category, name, found := strings.Cut(cCtx.Args().First(), ".")
+						if !found || name == "" {
+							return fmt.Errorf("invalid plugin %q, expected <type>.<name>", cCtx.Args().First())
+						}
+
+						schema, err := config.PluginSchema(category, name)
+						if err != nil {
+							return err
+						}
+						out, err := json.MarshalIndent(schema, "", "  ")
+						if err != nil {
+							return err
+						}
+						_, err = fmt.Fprintln(outputBuffer, string(out))
+						return err
+					},
+				},
 				{
 					Name:  "serializers",
 					Usage: "Print available serializer plugins",
@@ -0,0 +1,348 @@
+// Command handling for the "replay" command
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+)
+
+// replayer sends metrics read from files to the configured outputs
+type replayer struct {
+	since      time.Time
+	until      time.Time
+	filter     models.Filter
+	units      time.Duration
+	skip       int
+	retries    int
+	retryDelay time.Duration
+	outputs    []*models.RunningOutput
+
+	read     int
+	selected int
+}
+
+func getReplayCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) []*cli.Command {
+	flags := append([]cli.Flag{
+		&cli.TimestampFlag{
+			Name:   "since",
+			Usage:  "only replay metrics with a timestamp at or after the given RFC3339 time",
+			Layout: time.RFC3339,
+		},
+		&cli.TimestampFlag{
+			Name:   "until",
+			Usage:  "only replay metrics with a timestamp before the given RFC3339 time",
+			Layout: time.RFC3339,
+		},
+		&cli.StringSliceFlag{
+			Name:  "namepass",
+			Usage: "only replay metrics with a name matching one of the given glob patterns",
+		},
+		&cli.StringSliceFlag{
+			Name:  "namedrop",
+			Usage: "do not replay metrics with a name matching one of the given glob patterns",
+		},
+		&cli.StringFlag{
+			Name:  "metricpass",
+			Usage: "only replay metrics matching the given CEL expression",
+		},
+		&cli.DurationFlag{
+			Name:  "json-timestamp-units",
+			Usage: "units of the timestamps in JSON files",
+			Value: time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "skip",
+			Usage: "skip the given number of selected metrics, e.g. to resume an aborted replay",
+		},
+		&cli.IntFlag{
+			Name:  "retries",
+			Usage: "number of retries for writing a failed batch before aborting the replay",
+			Value: 3,
+		},
+	}, configHandlingFlags...)
+
+	return []*cli.Command{
+		{
+			Name:      "replay",
+			Usage:     "send metrics from disk-buffers or files to the configured outputs",
+			ArgsUsage: "<path>...",
+			Description: `
+The 'replay' command reads metrics from the given paths and sends them to the
+outputs of the configuration specified via '--config' or '--config-directory'.
+Inputs, processors and aggregators of the configuration are not used.
+
+A path can either be the directory of a 'disk' or 'overflow' buffer, a file
+containing line-protocol or a file with a '.json' extension containing metrics
+in the format of the JSON serializer. Metrics can be restricted to a time range
+using '--since' and '--until' and selected using the '--namepass', '--namedrop'
+and '--metricpass' flags with the same semantics as the plugin options.
+
+To replay the disk-buffer of an output to the outputs of 'backfill.conf' use
+
+> telegraf replay --config backfill.conf /var/lib/telegraf/buffer/<output ID>
+
+To only replay the 'cpu' metrics of a day stored as line-protocol use
+
+> telegraf replay --config backfill.conf --namepass cpu \
+    --since 2024-01-01T00:00:00Z --until 2024-01-02T00:00:00Z metrics.influx
+
+Failed writes are retried as set by '--retries'. If writing still fails, the
+replay is aborted and the number of metrics to skip for resuming the replay
+with the same paths and selection using '--skip' is reported.
+`,
+			Flags: flags,
+			Action: func(cCtx *cli.Context) error {
+				if cCtx.NArg() == 0 {
+					return errors.New("no path to replay given")
+				}
+
+				// Setup logging
+				logConfig := &logger.Config{Debug: cCtx.Bool("debug")}
+				if err := logger.SetupLogging(logConfig); err != nil {
+					return err
+				}
+
+				// Collect the given configuration files
+				configFiles, err := collectConfigFiles(cCtx.StringSlice("config"), cCtx.StringSlice("config-directory"))
+				if err != nil {
+					return err
+				}
+
+				c := config.NewConfig()
+				c.Agent.Quiet = cCtx.Bool("quiet")
+				c.OutputFilters = processFilterFlags(cCtx).output
+				// Buffer the metrics in memory as disk-buffers of the outputs
+				// might be in use by a running agent or even be the source of
+				// the replay.
+				c.ForceBufferStrategy = "memory"
+				if err := c.LoadAll(configFiles...); err != nil {
+					return err
+				}
+				if len(c.Outputs) == 0 {
+					return errors.New("no outputs found, probably invalid config file provided")
+				}
+
+				r := &replayer{
+					filter: models.Filter{
+						NamePass:   cCtx.StringSlice("namepass"),
+						NameDrop:   cCtx.StringSlice("namedrop"),
+						MetricPass: cCtx.String("metricpass"),
+					},
+					units:      cCtx.Duration("json-timestamp-units"),
+					skip:       cCtx.Int("skip"),
+					retries:    cCtx.Int("retries"),
+					retryDelay: time.Second,
+				}
+				if ts := cCtx.Timestamp("since"); ts != nil {
+					r.since = *ts
+				}
+				if ts := cCtx.Timestamp("until"); ts != nil {
+					r.until = *ts
+				}
+				if err := r.filter.Compile(); err != nil {
+					return fmt.Errorf("compiling filter failed: %w", err)
+				}
+
+				if err := r.run(c.Outputs, cCtx.Args().Slice()); err != nil {
+					resume := r.written()
+					fmt.Fprintf(outputBuffer, "Replayed %d of %d metrics before aborting\n", resume-r.skip, r.read)
+					fmt.Fprintf(outputBuffer, "Resume the replay using '--skip %d'\n", resume)
+					return err
+				}
+				fmt.Fprintf(outputBuffer, "Replayed %d of %d metrics\n", max(r.selected-r.skip, 0), r.read)
+				return nil
+			},
+		},
+	}
+}
+
+// run connects the outputs and replays the metrics of all paths
+func (r *replayer) run(outputs []*models.RunningOutput, paths []string) error {
+	r.outputs = outputs
+
+	for _, o := range r.outputs {
+		if err := o.Init(); err != nil {
+			return fmt.Errorf("initializing %s failed: %w", o.LogName(), err)
+		}
+		if err := o.Connect(); err != nil {
+			return fmt.Errorf("connecting %s failed: %w", o.LogName(), err)
+		}
+		defer o.Close()
+	}
+
+	for _, path := range paths {
+		if err := r.replay(path); err != nil {
+			return fmt.Errorf("replaying %q failed: %w", path, err)
+		}
+	}
+
+	// Write the remaining metrics
+	for _, o := range r.outputs {
+		if err := r.write(o, o.Write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write calls the given write function of the output and retries failed
+// writes with increasing delay
+func (r *replayer) write(o *models.RunningOutput, fn func() error) error {
+	delay := r.retryDelay
+	err := fn()
+	for attempt := 0; err != nil && attempt < r.retries; attempt++ {
+		log.Printf("W! Writing to %s failed, retrying in %s: %v", o.LogName(), delay, err)
+		time.Sleep(delay)
+		delay *= 2
+		err = fn()
+	}
+	if err != nil {
+		return fmt.Errorf("writing to %s failed: %w", o.LogName(), err)
+	}
+	return nil
+}
+
+// written returns the number of selected metrics, including the skipped ones,
+// that were written to all outputs
+func (r *replayer) written() int {
+	var pending int
+	for _, o := range r.outputs {
+		pending = max(pending, o.BufferLength())
+	}
+	return max(r.selected-pending, r.skip)
+}
+
+// replay reads all metrics of the given path depending on its type
+func (r *replayer) replay(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		log.Printf("I! Replaying disk-buffer %q", path)
+		return models.ReadDiskBuffer(path, r.add)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		log.Printf("I! Replaying JSON file %q", path)
+		return r.readJSON(f)
+	}
+	log.Printf("I! Replaying line-protocol file %q", path)
+	return r.readInflux(f)
+}
+
+func (r *replayer) readInflux(reader io.Reader) error {
+	parser := influx.NewStreamParser(reader)
+	for {
+		m, err := parser.Next()
+		if err != nil {
+			if errors.Is(err, influx.EOF) {
+				return nil
+			}
+			var perr *influx.ParseError
+			if errors.As(err, &perr) {
+				log.Printf("W! Skipping invalid line: %v", err)
+				continue
+			}
+			return err
+		}
+		if err := r.add(m); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonMetric is a metric in the format of the JSON serializer
+type jsonMetric struct {
+	Name      string                 `json:"name"`
+	Tags      map[string]string      `json:"tags"`
+	Fields    map[string]interface{} `json:"fields"`
+	Timestamp int64                  `json:"timestamp"`
+}
+
+// readJSON reads a stream of single metrics and batches in the format of the
+// JSON serializer
+func (r *replayer) readJSON(reader io.Reader) error {
+	decoder := json.NewDecoder(bufio.NewReader(reader))
+	for {
+		var entry struct {
+			jsonMetric
+			Metrics []jsonMetric `json:"metrics"`
+		}
+		if err := decoder.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		entries := entry.Metrics
+		if entry.Name != "" {
+			entries = append(entries, entry.jsonMetric)
+		}
+		for _, e := range entries {
+			m := metric.New(e.Name, e.Tags, e.Fields, time.Unix(0, e.Timestamp*int64(r.units)))
+			if err := r.add(m); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// add passes the metric to the outputs if it is selected and writes a batch
+// as soon as an output has enough metrics
+func (r *replayer) add(m telegraf.Metric) error {
+	r.read++
+
+	ts := m.Time()
+	if !r.since.IsZero() && ts.Before(r.since) {
+		return nil
+	}
+	if !r.until.IsZero() && !ts.Before(r.until) {
+		return nil
+	}
+	if ok, err := r.filter.Select(m); err != nil {
+		return fmt.Errorf("filtering metric failed: %w", err)
+	} else if !ok {
+		return nil
+	}
+	r.selected++
+	if r.selected <= r.skip {
+		return nil
+	}
+
+	for _, o := range r.outputs {
+		o.AddMetric(m)
+		if o.BufferLength() < o.MetricBatchSize {
+			continue
+		}
+		if err := r.write(o, o.WriteBatch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+)
+
+func TestCommandReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	// Create a disk-buffer
+	buf, err := models.NewBuffer("replay_test", "123", "", 0, "disk", dir)
+	require.NoError(t, err)
+	buf.Add(
+		metric.New("cpu", map[string]string{"source": "wal"}, map[string]interface{}{"value": 1}, time.Unix(10, 0)),
+		metric.New("mem", map[string]string{"source": "wal"}, map[string]interface{}{"value": 2}, time.Unix(20, 0)),
+	)
+	require.NoError(t, buf.Close())
+	walDir := filepath.Join(dir, "123")
+	require.DirExists(t, walDir)
+
+	// Create the line-protocol and JSON files
+	lpFile := filepath.Join(dir, "metrics.influx")
+	lp := "cpu,source=lp value=3i 30000000000\nmem,source=lp value=4i 40000000000\ncpu,source=lp value=5i 50000000000\n"
+	require.NoError(t, os.WriteFile(lpFile, []byte(lp), 0640))
+
+	jsonFile := filepath.Join(dir, "metrics.json")
+	js := `{"metrics":[{"fields":{"value":6},"name":"cpu","tags":{"source":"json"},"timestamp":35}]}
+{"fields":{"value":7},"name":"cpu","tags":{"source":"json"},"timestamp":60}`
+	require.NoError(t, os.WriteFile(jsonFile, []byte(js), 0640))
+
+	// Replay to a file output
+	outFile := filepath.Join(dir, "out.influx")
+	cfgFile := filepath.Join(dir, "telegraf.conf")
+	cfg := `
+[agent]
+  omit_hostname = true
+  buffer_strategy = "disk"
+  buffer_directory = "` + filepath.ToSlash(dir) + `"
+[[outputs.file]]
+  files = ["` + filepath.ToSlash(outFile) + `"]
+  data_format = "influx"
+`
+	require.NoError(t, os.WriteFile(cfgFile, []byte(cfg), 0640))
+
+	out := new(bytes.Buffer)
+	args := []string{
+		os.Args[0], "replay", "--config", cfgFile,
+		"--namepass", "cpu", "--since", "1970-01-01T00:00:05Z", "--until", "1970-01-01T00:00:50Z",
+		walDir, lpFile, jsonFile,
+	}
+	require.NoError(t, runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf()))
+	require.Contains(t, out.String(), "Replayed 3 of 7 metrics")
+
+	expected := "cpu,source=wal value=1i 10000000000\n" +
+		"cpu,source=lp value=3i 30000000000\n" +
+		"cpu,source=json value=6 35000000000\n"
+	actual, err := os.ReadFile(outFile)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(actual))
+
+	// The source buffer must not be modified
+	var count int
+	require.NoError(t, models.ReadDiskBuffer(walDir, func(telegraf.Metric) error {
+		count++
+		return nil
+	}))
+	require.Equal(t, 2, count)
+
+	// The outputs must not open a disk-buffer of their own
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, e.Name())
+		}
+	}
+	require.Equal(t, []string{"123"}, dirs)
+}
+
+func TestReplayRetryAndResume(t *testing.T) {
+	lp := "cpu value=1i 1\ncpu value=2i 2\ncpu value=3i 3\ncpu value=4i 4\ncpu value=5i 5\n"
+
+	// Failed batches are retried
+	plugin := &replayTestOutput{failures: 1}
+	output := models.NewRunningOutput(plugin, &models.OutputConfig{Name: "replay_test"}, 2, 100)
+	r := &replayer{retries: 1, retryDelay: time.Millisecond, outputs: []*models.RunningOutput{output}}
+	require.NoError(t, r.readInflux(bytes.NewBufferString(lp)))
+	require.NoError(t, r.write(output, output.Write))
+	require.Len(t, plugin.metrics, 5)
+
+	// Writes failing after the retries abort the replay and report the
+	// position to resume at
+	plugin = &replayTestOutput{failures: 2}
+	output = models.NewRunningOutput(plugin, &models.OutputConfig{Name: "replay_test"}, 2, 100)
+	r = &replayer{retries: 1, retryDelay: time.Millisecond, outputs: []*models.RunningOutput{output}}
+	require.ErrorContains(t, r.readInflux(bytes.NewBufferString(lp)), "write failed")
+	require.Empty(t, plugin.metrics)
+	require.Zero(t, r.written())
+
+	// Resuming skips the metrics already written
+	plugin = &replayTestOutput{}
+	output = models.NewRunningOutput(plugin, &models.OutputConfig{Name: "replay_test"}, 2, 100)
+	r = &replayer{skip: 3, outputs: []*models.RunningOutput{output}}
+	require.NoError(t, r.readInflux(bytes.NewBufferString(lp)))
+	require.NoError(t, r.write(output, output.Write))
+	require.Len(t, plugin.metrics, 2)
+	require.Equal(t, 5, r.written())
+}
+
+type replayTestOutput struct {
+	failures int
+	metrics  []telegraf.Metric
+}
+
+func (*replayTestOutput) SampleConfig() string {
+	return ""
+}
+
+func (*replayTestOutput) Connect() error {
+	return nil
+}
+
+func (*replayTestOutput) Close() error {
+	return nil
+}
+
+func (o *replayTestOutput) Write(metrics []telegraf.Metric) error {
+	if o.failures > 0 {
+		o.failures--
+		return errors.New("write failed")
+	}
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
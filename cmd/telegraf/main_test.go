@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -280,6 +281,23 @@ func TestPluginDirectoryFlag(t *testing.T) {
 	require.ErrorContains(t, err, "go plugin support is not enabled")
 }
 
+func TestCommandPluginsSchema(t *testing.T) {
+	buf := new(bytes.Buffer)
+	args := []string{os.Args[0], "plugins", "schema", "processors.override"}
+	require.NoError(t, runApp(args, buf, NewMockServer(), NewMockConfig(buf), NewMockTelegraf()))
+
+	var schema config.Schema
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &schema))
+	require.Equal(t, "processors.override", schema.Title)
+	require.Equal(t, "string", schema.Properties["name_override"].Type)
+	require.Equal(t, "object", schema.Properties["tags"].Type)
+	require.Contains(t, schema.Properties, "order")
+
+	args = []string{os.Args[0], "plugins", "schema", "override"}
+	err := runApp(args, buf, NewMockServer(), NewMockConfig(buf), NewMockTelegraf())
+	require.ErrorContains(t, err, `invalid plugin "override", expected <type>.<name>`)
+}
+
 func TestCommandConfig(t *testing.T) {
 	tests := []struct {
 		name            string
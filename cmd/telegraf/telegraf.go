@@ -9,7 +9,9 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -81,6 +83,13 @@ type Telegraf struct {
 
 	cfg *config.Config
 
+	// agent is the running agent used for reloading plugins
+	agent     *agent.Agent
+	agentLock sync.Mutex
+
+	// signals is the channel of the reload loop used to request a reload
+	signals chan os.Signal
+
 	GlobalFlags
 	WindowFlags
 }
@@ -149,57 +158,44 @@ func (t *Telegraf) reloadLoop() error {
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
-		if t.watchConfig != "" {
-			for _, fConfig := range t.configFiles {
-				if isURL(fConfig) {
-					continue
-				}
-
-				if _, err := os.Stat(fConfig); err != nil {
-					log.Printf("W! Cannot watch config %s: %s", fConfig, err)
-				} else {
-					go t.watchLocalConfig(ctx, signals, fConfig)
-				}
-			}
-			for _, fConfigDirectory := range t.configDir {
-				if _, err := os.Stat(fConfigDirectory); err != nil {
-					log.Printf("W! Cannot watch config directory %s: %s", fConfigDirectory, err)
-				} else {
-					go t.watchLocalConfig(ctx, signals, fConfigDirectory)
-				}
-			}
-		}
-		if t.configURLWatchInterval > 0 {
-			remoteConfigs := make([]string, 0)
-			for _, fConfig := range t.configFiles {
-				if isURL(fConfig) {
-					remoteConfigs = append(remoteConfigs, fConfig)
-				}
-			}
-			if len(remoteConfigs) > 0 {
-				go t.watchRemoteConfigs(ctx, signals, t.configURLWatchInterval, remoteConfigs)
-			}
-		}
+		t.signals = signals
+		watchCtx, watchCancel := context.WithCancel(ctx)
+		t.startConfigWatchers(watchCtx, signals)
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Println("I! Reloading Telegraf config")
-					// May need to update the list of known config files
-					// if a delete or create occured. That way on the reload
-					// we ensure we watch the correct files.
-					if err := t.getConfigFiles(); err != nil {
-						log.Println("E! Error loading config files: ", err)
+			for {
+				select {
+				case sig := <-signals:
+					if sig == syscall.SIGHUP {
+						log.Println("I! Reloading Telegraf config")
+						// May need to update the list of known config files
+						// if a delete or create occured. That way on the reload
+						// we ensure we watch the correct files.
+						if err := t.getConfigFiles(); err != nil {
+							log.Println("E! Error loading config files: ", err)
+						}
+
+						// Try to only exchange the changed plugins and fall
+						// back to restarting the agent if this is not possible
+						err := t.reloadPlugins()
+						if err == nil {
+							watchCancel()
+							watchCtx, watchCancel = context.WithCancel(ctx)
+							t.startConfigWatchers(watchCtx, signals)
+							continue
+						}
+						log.Printf("I! Restarting agent: %v", err)
+						<-reload
+						reload <- true
 					}
-					<-reload
-					reload <- true
+					cancel()
+				case err := <-t.pprofErr:
+					log.Printf("E! pprof server failed: %v", err)
+					cancel()
+				case <-stop:
+					cancel()
 				}
-				cancel()
-			case err := <-t.pprofErr:
-				log.Printf("E! pprof server failed: %v", err)
-				cancel()
-			case <-stop:
-				cancel()
+				watchCancel()
+				return
 			}
 		}()
 
@@ -213,6 +209,77 @@ func (t *Telegraf) reloadLoop() error {
 	return nil
 }
 
+// startConfigWatchers starts watching the local and remote configurations
+// for changes if requested.
+func (t *Telegraf) startConfigWatchers(ctx context.Context, signals chan os.Signal) {
+	if t.watchConfig != "" {
+		for _, fConfig := range t.configFiles {
+			if isURL(fConfig) {
+				continue
+			}
+
+			if _, err := os.Stat(fConfig); err != nil {
+				log.Printf("W! Cannot watch config %s: %s", fConfig, err)
+			} else {
+				go t.watchLocalConfig(ctx, signals, fConfig)
+			}
+		}
+		for _, fConfigDirectory := range t.configDir {
+			if _, err := os.Stat(fConfigDirectory); err != nil {
+				log.Printf("W! Cannot watch config directory %s: %s", fConfigDirectory, err)
+			} else {
+				go t.watchLocalConfig(ctx, signals, fConfigDirectory)
+			}
+		}
+	}
+	if t.configURLWatchInterval > 0 {
+		remoteConfigs := make([]string, 0)
+		for _, fConfig := range t.configFiles {
+			if isURL(fConfig) {
+				remoteConfigs = append(remoteConfigs, fConfig)
+			}
+		}
+		if len(remoteConfigs) > 0 {
+			go t.watchRemoteConfigs(ctx, signals, t.configURLWatchInterval, remoteConfigs)
+		}
+	}
+}
+
+// reloadPlugins loads the configuration and only exchanges the plugins of the
+// running agent with changed settings.
+func (t *Telegraf) reloadPlugins() error {
+	t.agentLock.Lock()
+	ag := t.agent
+	t.agentLock.Unlock()
+	if ag == nil {
+		return agent.ErrRestartRequired
+	}
+
+	// Reuse the running outputs with unchanged settings to keep their buffers
+	running := ag.RunningOutputs()
+	c := t.newConfig()
+	c.ReuseOutputs = slices.Clone(running)
+	err := c.LoadAll(t.configFiles...)
+	if err == nil {
+		err = t.validateConfig(c)
+	}
+	if err != nil {
+		for _, output := range c.Outputs {
+			if !slices.Contains(running, output) {
+				output.Release()
+			}
+		}
+		for _, p := range c.Pipelines {
+			for _, output := range p.Outputs {
+				output.Release()
+			}
+		}
+		return err
+	}
+
+	return ag.Reload(c)
+}
+
 func (t *Telegraf) watchLocalConfig(ctx context.Context, signals chan os.Signal, fConfig string) {
 	var mytomb tomb.Tomb
 	var watcher watch.FileWatcher
@@ -311,13 +378,7 @@ func (*Telegraf) watchRemoteConfigs(ctx context.Context, signals chan os.Signal,
 
 func (t *Telegraf) loadConfiguration() (*config.Config, error) {
 	// If no other options are specified, load the config file and run.
-	c := config.NewConfig()
-	c.Agent.Quiet = t.quiet
-	c.Agent.ConfigURLRetryAttempts = t.configURLRetryAttempts
-	c.OutputFilters = t.outputFilters
-	c.InputFilters = t.inputFilters
-	c.SecretStoreFilters = t.secretstoreFilters
-
+	c := t.newConfig()
 	if err := t.getConfigFiles(); err != nil {
 		return c, err
 	}
@@ -327,14 +388,34 @@ func (t *Telegraf) loadConfiguration() (*config.Config, error) {
 	return c, nil
 }
 
+func (t *Telegraf) newConfig() *config.Config {
+	c := config.NewConfig()
+	c.Agent.Quiet = t.quiet
+	c.Agent.ConfigURLRetryAttempts = t.configURLRetryAttempts
+	c.OutputFilters = t.outputFilters
+	c.InputFilters = t.inputFilters
+	c.SecretStoreFilters = t.secretstoreFilters
+	return c
+}
+
 func (t *Telegraf) getConfigFiles() error {
-	var configFiles []string
+	configFiles, err := collectConfigFiles(t.config, t.configDir)
+	if err != nil {
+		return err
+	}
+	t.configFiles = configFiles
+	return nil
+}
 
-	configFiles = append(configFiles, t.config...)
-	for _, fConfigDirectory := range t.configDir {
+// collectConfigFiles returns the given configuration files followed by the
+// files found in the given directories. The default configuration files are
+// returned if neither files nor directories are given.
+func collectConfigFiles(files, directories []string) ([]string, error) {
+	configFiles := append([]string(nil), files...)
+	for _, fConfigDirectory := range directories {
 		files, err := config.WalkDirectory(fConfigDirectory)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		configFiles = append(configFiles, files...)
 	}
@@ -343,13 +424,11 @@ func (t *Telegraf) getConfigFiles() error {
 	if len(configFiles) == 0 {
 		defaultFiles, err := config.GetDefaultConfigPath()
 		if err != nil {
-			return fmt.Errorf("unable to load default config paths: %w", err)
+			return nil, fmt.Errorf("unable to load default config paths: %w", err)
 		}
 		configFiles = append(configFiles, defaultFiles...)
 	}
-
-	t.configFiles = configFiles
-	return nil
+	return configFiles, nil
 }
 
 func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
@@ -361,19 +440,8 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 		}
 	}
 
-	if !(t.test || t.testWait != 0) && len(c.Outputs) == 0 {
-		return errors.New("no outputs found, probably invalid config file provided")
-	}
-	if t.plugindDir == "" && len(c.Inputs) == 0 {
-		return errors.New("no inputs found, probably invalid config file provided")
-	}
-
-	if int64(c.Agent.Interval) <= 0 {
-		return fmt.Errorf("agent interval must be positive, found %v", c.Agent.Interval)
-	}
-
-	if int64(c.Agent.FlushInterval) <= 0 {
-		return fmt.Errorf("agent flush_interval must be positive; found %v", c.Agent.Interval)
+	if err := t.validateConfig(c); err != nil {
+		return err
 	}
 
 	// Setup logging as configured.
@@ -412,6 +480,9 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 	} else {
 		log.Printf("I! Loaded outputs: %s\n%s", strings.Join(c.OutputNames(), " "), c.OutputNamesWithSources())
 	}
+	if len(c.Pipelines) > 0 {
+		log.Printf("I! Loaded pipelines: %s", strings.Join(c.PipelineNames(), " "))
+	}
 	log.Printf("I! Tags enabled: %s", c.ListTags())
 
 	if count, found := c.Deprecations["inputs"]; found && (count[0] > 0 || count[1] > 0) {
@@ -444,6 +515,7 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 		}
 	}
 	ag := agent.NewAgent(c)
+	ag.ReloadRequest = t.requestReload
 
 	// Notify systemd that telegraf is ready
 	// SdNotify() only tries to notify if the NOTIFY_SOCKET environment is set, so it's safe to call when systemd isn't present.
@@ -483,9 +555,58 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 		}
 	}
 
+	t.agentLock.Lock()
+	t.agent = ag
+	t.agentLock.Unlock()
+	defer func() {
+		t.agentLock.Lock()
+		t.agent = nil
+		t.agentLock.Unlock()
+	}()
+
 	return ag.Run(ctx)
 }
 
+// requestReload triggers a reload of the configuration as done on SIGHUP.
+func (t *Telegraf) requestReload() {
+	// Do not block if another signal is pending
+	select {
+	case t.signals <- syscall.SIGHUP:
+	default:
+	}
+}
+
+// validateConfig checks if the configuration can be used to run the agent
+func (t *Telegraf) validateConfig(c *config.Config) error {
+	for _, p := range c.Pipelines {
+		if !(t.test || t.testWait != 0) && len(p.Outputs) == 0 {
+			return fmt.Errorf("no outputs found in pipeline %q", p.Name)
+		}
+		if len(p.Inputs) == 0 {
+			return fmt.Errorf("no inputs found in pipeline %q", p.Name)
+		}
+	}
+	// The top-level plugins are optional if pipelines are configured but
+	// metrics of top-level inputs must be written somewhere
+	if len(c.Pipelines) == 0 || len(c.Inputs) > 0 {
+		if !(t.test || t.testWait != 0) && len(c.Outputs) == 0 {
+			return errors.New("no outputs found, probably invalid config file provided")
+		}
+	}
+	if len(c.Pipelines) == 0 && t.plugindDir == "" && len(c.Inputs) == 0 {
+		return errors.New("no inputs found, probably invalid config file provided")
+	}
+
+	if int64(c.Agent.Interval) <= 0 {
+		return fmt.Errorf("agent interval must be positive, found %v", c.Agent.Interval)
+	}
+
+	if int64(c.Agent.FlushInterval) <= 0 {
+		return fmt.Errorf("agent flush_interval must be positive; found %v", c.Agent.Interval)
+	}
+	return nil
+}
+
 // isURL checks if string is valid url
 func isURL(str string) bool {
 	u, err := url.Parse(str)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
@@ -81,6 +82,7 @@ type Config struct {
 
 	SecretStores      map[string]telegraf.SecretStore
 	secretStoreSource map[string][]string
+	secretStoreIDs    map[string]string
 
 	Agent       *AgentConfig
 	Inputs      []*models.RunningInput
@@ -99,12 +101,53 @@ type Config struct {
 
 	Persister *persister.Persister
 
+	// KeepExpandedConfig records the loaded configuration with expanded
+	// templates, see ExpandedConfig.
+	KeepExpandedConfig bool
+	templates          map[string]*ast.Table
+	expanded           []expandedConfig
+
+	// ValidateSchema checks the options of the agent and all plugins against
+	// their schema before loading the plugins and reports all violations,
+	// see PluginSchema.
+	ValidateSchema bool
+
+	// Pipelines are independent chains of plugins running next to the
+	// top-level plugins
+	Pipelines []*Pipeline
+	pipeline  string
+
+	// Router selects the outputs of the metrics if a routing table is
+	// configured, nil otherwise.
+	Router *models.Router
+
+	// ReuseOutputs contains the outputs of a running agent. When loading the
+	// configuration, outputs with an identical setup are taken from this list
+	// instead of creating a new instance. This allows to keep the buffers and
+	// connections of unchanged outputs when reloading the configuration.
+	ReuseOutputs []*models.RunningOutput
+
+	// ForceBufferStrategy overrides the configured buffer strategy of all
+	// outputs if set, e.g. to not open the disk-buffers of a running agent.
+	ForceBufferStrategy string
+
 	NumberSecrets uint64
 
 	seenAgentTable     bool
 	seenAgentTableOnce sync.Once
 }
 
+// Pipeline is a named chain of inputs, processors, aggregators and outputs
+// processing metrics independently of other pipelines.
+type Pipeline struct {
+	Name          string
+	Inputs        []*models.RunningInput
+	Outputs       []*models.RunningOutput
+	Aggregators   []*models.RunningAggregator
+	Processors    models.RunningProcessors
+	AggProcessors models.RunningProcessors
+}
+
 // Ordered plugins used to keep the order in which they appear in a file
 type OrderedPlugin struct {
 	Line   int
@@ -139,6 +182,7 @@ func NewConfig() *Config {
 		AggProcessors:      make([]*models.RunningProcessor, 0),
 		SecretStores:       make(map[string]telegraf.SecretStore),
 		secretStoreSource:  make(map[string][]string),
+		secretStoreIDs:     make(map[string]string),
 		fileProcessors:     make([]*OrderedPlugin, 0),
 		fileAggProcessors:  make([]*OrderedPlugin, 0),
 		InputFilters:       make([]string, 0),
@@ -275,6 +319,11 @@ type AgentConfig struct {
 	// the state in the file will be restored for the plugins.
 	Statefile string `toml:"statefile"`
 
+	// Interval for writing the state of plugins to the state-file while
+	// running in addition to writing the state on termination of Telegraf.
+	// This prevents losing the state on crashes. Zero disables checkpoints.
+	StatefileCheckpointInterval Duration `toml:"statefile_checkpoint_interval"`
+
 	// Flag to always keep tags explicitly defined in the plugin itself and
 	// ensure those tags always pass filtering.
 	AlwaysIncludeLocalTags bool `toml:"always_include_local_tags"`
@@ -293,12 +342,50 @@ type AgentConfig struct {
 	ConfigURLRetryAttempts int `toml:"config_url_retry_attempts"`
 
 	// BufferStrategy is the metric buffer type to use for a given output plugin.
-	// Supported types currently are "memory" and "disk".
+	// Supported types currently are "memory", "disk" and "overflow".
 	BufferStrategy string `toml:"buffer_strategy"`
 
 	// BufferDirectory is the directory to store buffer files for serialized
-	// to disk metrics when using the "disk" buffer strategy.
+	// to disk metrics when using the "disk" or "overflow" buffer strategy.
 	BufferDirectory string `toml:"buffer_directory"`
+
+	// BufferMaxBytes is the maximum size of the buffer files on disk when using
+	// the "disk" or "overflow" buffer strategy. The oldest metrics are dropped
+	// if the size is exceeded.
+	BufferMaxBytes Size `toml:"buffer_max_bytes"`
+
+	// BufferMaxAge is the maximum age of metrics in the buffer files on disk
+	// when using the "disk" or "overflow" buffer strategy. Older metrics are
+	// dropped.
+	BufferMaxAge Duration `toml:"buffer_max_age"`
+
+	// ShutdownDrainTimeout is the maximum time to keep writing the buffered
+	// metrics of the outputs on shutdown until the buffers are empty. By
+	// default, only a single attempt to write the metrics is made.
+	ShutdownDrainTimeout Duration `toml:"shutdown_drain_timeout"`
+
+	// ShutdownDumpDirectory is the directory to write the metrics remaining in
+	// the memory buffers of outputs to on shutdown. The metrics are restored
+	// on the next start. Metrics are dropped if empty.
+	ShutdownDumpDirectory string `toml:"shutdown_dump_directory"`
+
+	// APIAddress is the address of the local management API of the running
+	// agent, either as "host:port" or as "unix:///path/to/socket". The API is
+	// disabled if empty.
+	APIAddress string `toml:"api_address"`
+
+	// APIToken is the bearer token required to access the management API. A
+	// token is mandatory for TCP addresses.
+	APIToken Secret `toml:"api_token"`
+}
+
+// PipelineNames returns a list of the names of the configured pipelines.
+func (c *Config) PipelineNames() []string {
+	names := make([]string, 0, len(c.Pipelines))
+	for _, p := range c.Pipelines {
+		names = append(names, p.Name)
+	}
+	return names
 }
 
 // InputNames returns a list of strings of the configured inputs.
@@ -613,6 +700,26 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 		}
 	}
 
+	// Parse the templates and expand them in the plugin tables
+	if val, ok := tbl.Fields["templates"]; ok {
+		if err := c.addTemplates(val); err != nil {
+			return fmt.Errorf("error parsing templates: %w", err)
+		}
+	}
+	if err := c.expandTemplates(tbl); err != nil {
+		return err
+	}
+	if c.KeepExpandedConfig {
+		if err := c.keepExpanded(path, tbl); err != nil {
+			return fmt.Errorf("recording expanded configuration failed: %w", err)
+		}
+	}
+	if c.ValidateSchema {
+		if err := validateSchema(path, tbl); err != nil {
+			return err
+		}
+	}
+
 	// Parse agent table:
 	if val, ok := tbl.Fields["agent"]; ok {
 		if c.seenAgentTable {
@@ -673,106 +780,23 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 
 	// Parse all the rest of the plugins:
 	for name, val := range tbl.Fields {
+		if name == "pipeline" {
+			if err = c.addPipelines(path, val); err != nil {
+				return err
+			}
+			continue
+		}
+
 		subTable, ok := val.(*ast.Table)
 		if !ok {
 			return fmt.Errorf("invalid configuration, error parsing field %q as table", name)
 		}
 
 		switch name {
-		case "agent", "global_tags", "tags":
-		case "outputs":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				// legacy [outputs.influxdb] support
-				case *ast.Table:
-					if err = c.addOutput(pluginName, path, pluginSubTable); err != nil {
-						return fmt.Errorf("error parsing %s, %w", pluginName, err)
-					}
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addOutput(pluginName, path, t); err != nil {
-							return fmt.Errorf("error parsing %s array, %w", pluginName, err)
-						}
-					}
-				default:
-					return fmt.Errorf("unsupported config format: %s",
-						pluginName)
-				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf(
-						"plugin %s.%s: line %d: configuration specified the fields %q, but they were not used. "+
-							"This is either a typo or this config option does not exist in this version.",
-						name, pluginName, subTable.Line, keys(c.UnusedFields))
-				}
-			}
-		case "inputs", "plugins":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				// legacy [inputs.cpu] support
-				case *ast.Table:
-					if err = c.addInput(pluginName, path, pluginSubTable); err != nil {
-						return fmt.Errorf("error parsing %s, %w", pluginName, err)
-					}
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addInput(pluginName, path, t); err != nil {
-							return fmt.Errorf("error parsing %s, %w", pluginName, err)
-						}
-					}
-				default:
-					return fmt.Errorf("unsupported config format: %s",
-						pluginName)
-				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf(
-						"plugin %s.%s: line %d: configuration specified the fields %q, but they were not used. "+
-							"This is either a typo or this config option does not exist in this version.",
-						name, pluginName, subTable.Line, keys(c.UnusedFields))
-				}
-			}
-		case "processors":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addProcessor(pluginName, path, t); err != nil {
-							return fmt.Errorf("error parsing %s, %w", pluginName, err)
-						}
-					}
-				default:
-					return fmt.Errorf("unsupported config format: %s",
-						pluginName)
-				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf(
-						"plugin %s.%s: line %d: configuration specified the fields %q, but they were not used. "+
-							"This is either a typo or this config option does not exist in this version.",
-						name,
-						pluginName,
-						subTable.Line,
-						keys(c.UnusedFields),
-					)
-				}
-			}
-		case "aggregators":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addAggregator(pluginName, path, t); err != nil {
-							return fmt.Errorf("error parsing %s, %w", pluginName, err)
-						}
-					}
-				default:
-					return fmt.Errorf("unsupported config format: %s",
-						pluginName)
-				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf(
-						"plugin %s.%s: line %d: configuration specified the fields %q, but they were not used. "+
-							"This is either a typo or this config option does not exist in this version.",
-						name, pluginName, subTable.Line, keys(c.UnusedFields))
-				}
+		case "agent", "global_tags", "tags", "templates":
+		case "outputs", "inputs", "plugins", "processors", "aggregators":
+			if err = c.addPluginTables(name, path, subTable); err != nil {
+				return err
 			}
 		case "secretstores":
 			for pluginName, pluginVal := range subTable.Fields {
@@ -792,6 +816,16 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 					return fmt.Errorf(msg, name, pluginName, subTable.Line, keys(c.UnusedFields))
 				}
 			}
+		case "routing":
+			if err = c.addRouting(subTable); err != nil {
+				return fmt.Errorf("error parsing routing table, %w", err)
+			}
+			if len(c.UnusedFields) > 0 {
+				return fmt.Errorf(
+					"routing: line %d: configuration specified the fields %q, but they were not used. "+
+						"This is either a typo or this config option does not exist in this version.",
+					subTable.Line, keys(c.UnusedFields))
+			}
 
 		// Assume it's an input for legacy config file support if no other
 		// identifiers are present
@@ -817,6 +851,193 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 	return nil
 }
 
+// addPipelines adds the pipelines defined by the given [[pipeline]] tables.
+// The plugins of each pipeline are parsed like the top-level plugins but are
+// collected in the pipeline instead of the configuration.
+func (c *Config) addPipelines(path string, val interface{}) error {
+	tables, ok := val.([]*ast.Table)
+	if !ok {
+		return errors.New("invalid configuration, pipelines must be defined as [[pipeline]] array")
+	}
+
+	for _, tbl := range tables {
+		name := c.getFieldString(tbl, "name")
+		if c.hasErrs() {
+			return c.firstErr()
+		}
+		if name == "" {
+			return fmt.Errorf("line %d: pipeline without name", tbl.Line)
+		}
+		for _, p := range c.Pipelines {
+			if p.Name == name {
+				return fmt.Errorf("line %d: duplicate pipeline %q", tbl.Line, name)
+			}
+		}
+
+		pipeline, err := c.buildPipeline(name, path, tbl)
+		if err != nil {
+			return fmt.Errorf("error parsing pipeline %q, %w", name, err)
+		}
+		c.Pipelines = append(c.Pipelines, pipeline)
+	}
+	return nil
+}
+
+// buildPipeline parses the plugins of a pipeline table. To reuse the plugin
+// parsing, the plugins of the configuration are temporarily replaced by the
+// ones of the pipeline.
+func (c *Config) buildPipeline(name, path string, tbl *ast.Table) (*Pipeline, error) {
+	inputs, outputs, aggregators := c.Inputs, c.Outputs, c.Aggregators
+	fileProcessors, fileAggProcessors := c.fileProcessors, c.fileAggProcessors
+	defer func() {
+		c.Inputs, c.Outputs, c.Aggregators = inputs, outputs, aggregators
+		c.fileProcessors, c.fileAggProcessors = fileProcessors, fileAggProcessors
+		c.pipeline = ""
+	}()
+	c.Inputs, c.Outputs, c.Aggregators = nil, nil, nil
+	c.fileProcessors = make(OrderedPlugins, 0)
+	c.fileAggProcessors = make(OrderedPlugins, 0)
+	c.pipeline = name
+
+	for category, val := range tbl.Fields {
+		if category == "name" {
+			continue
+		}
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("invalid configuration, error parsing field %q as table", category)
+		}
+		switch category {
+		case "inputs", "outputs", "processors", "aggregators":
+			if err := c.addPluginTables(category, path, subTable); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unsupported table %q in pipeline", subTable.Line, category)
+		}
+	}
+
+	pipeline := &Pipeline{
+		Name:        name,
+		Inputs:      c.Inputs,
+		Outputs:     c.Outputs,
+		Aggregators: c.Aggregators,
+	}
+	sort.Sort(c.fileProcessors)
+	for _, op := range c.fileProcessors {
+		pipeline.Processors = append(pipeline.Processors, op.plugin.(*models.RunningProcessor))
+	}
+	sort.Sort(c.fileAggProcessors)
+	for _, op := range c.fileAggProcessors {
+		pipeline.AggProcessors = append(pipeline.AggProcessors, op.plugin.(*models.RunningProcessor))
+	}
+	sort.Stable(pipeline.Processors)
+	sort.Stable(pipeline.AggProcessors)
+
+	return pipeline, nil
+}
+
+// addPluginTables adds all plugins of the given category
+func (c *Config) addPluginTables(name, path string, subTable *ast.Table) error {
+	switch name {
+	case "outputs":
+		for pluginName, pluginVal := range subTable.Fields {
+			switch pluginSubTable := pluginVal.(type) {
+			// legacy [outputs.influxdb] support
+			case *ast.Table:
+				if err := c.addOutput(pluginName, path, pluginSubTable); err != nil {
+					return fmt.Errorf("error parsing %s, %w", pluginName, err)
+				}
+			case []*ast.Table:
+				for _, t := range pluginSubTable {
+					if err := c.addOutput(pluginName, path, t); err != nil {
+						return fmt.Errorf("error parsing %s array, %w", pluginName, err)
+					}
+				}
+			default:
+				return fmt.Errorf("unsupported config format: %s",
+					pluginName)
+			}
+			if len(c.UnusedFields) > 0 {
+				return fmt.Errorf(
+					"plugin %s.%s: line %d: configuration specified the fields %q, but they were not used. "+
+						"This is either a typo or this config option does not exist in this version.",
+					name, pluginName, subTable.Line, keys(c.UnusedFields))
+			}
+		}
+	case "inputs", "plugins":
+		for pluginName, pluginVal := range subTable.Fields {
+			switch pluginSubTable := pluginVal.(type) {
+			// legacy [inputs.cpu] support
+			case *ast.Table:
+				if err := c.addInput(pluginName, path, pluginSubTable); err != nil {
+					return fmt.Errorf("error parsing %s, %w", pluginName, err)
+				}
+			case []*ast.Table:
+				for _, t := range pluginSubTable {
+					if err := c.addInput(pluginName, path, t); err != nil {
+						return fmt.Errorf("error parsing %s, %w", pluginName, err)
+					}
+				}
+			default:
+				return fmt.Errorf("unsupported config format: %s",
+					pluginName)
+			}
+			if len(c.UnusedFields) > 0 {
+				return fmt.Errorf(
+					"plugin %s.%s: line %d: configuration specified the fields %q, but they were not used. "+
+						"This is either a typo or this config option does not exist in this version.",
+					name, pluginName, subTable.Line, keys(c.UnusedFields))
+			}
+		}
+	case "processors":
+		for pluginName, pluginVal := range subTable.Fields {
+			switch pluginSubTable := pluginVal.(type) {
+			case []*ast.Table:
+				for _, t := range pluginSubTable {
+					if err := c.addProcessor(pluginName, path, t); err != nil {
+						return fmt.Errorf("error parsing %s, %w", pluginName, err)
+					}
+				}
+			default:
+				return fmt.Errorf("unsupported config format: %s",
+					pluginName)
+			}
+			if len(c.UnusedFields) > 0 {
+				return fmt.Errorf(
+					"plugin %s.%s: line %d: configuration specified the fields %q, but they were not used. "+
+						"This is either a typo or this config option does not exist in this version.",
+					name,
+					pluginName,
+					subTable.Line,
+					keys(c.UnusedFields),
+				)
+			}
+		}
+	case "aggregators":
+		for pluginName, pluginVal := range subTable.Fields {
+			switch pluginSubTable := pluginVal.(type) {
+			case []*ast.Table:
+				for _, t := range pluginSubTable {
+					if err := c.addAggregator(pluginName, path, t); err != nil {
+						return fmt.Errorf("error parsing %s, %w", pluginName, err)
+					}
+				}
+			default:
+				return fmt.Errorf("unsupported config format: %s",
+					pluginName)
+			}
+			if len(c.UnusedFields) > 0 {
+				return fmt.Errorf(
+					"plugin %s.%s: line %d: configuration specified the fields %q, but they were not used. "+
+						"This is either a typo or this config option does not exist in this version.",
+					name, pluginName, subTable.Line, keys(c.UnusedFields))
+			}
+		}
+	}
+	return nil
+}
+
 // trimBOM trims the Byte-Order-Marks from the beginning of the file.
 // this is for Windows compatibility only.
 // see https://github.com/influxdata/telegraf/issues/1378
@@ -967,6 +1188,28 @@ func (c *Config) addAggregator(name, source string, table *ast.Table) error {
 	return nil
 }
 
+// addRouting adds the routes of the given routing table. Routes of multiple
+// files are appended while the default route is overridden by later files.
+func (c *Config) addRouting(table *ast.Table) error {
+	var routing struct {
+		Default []string        `toml:"default"`
+		Routes  []*models.Route `toml:"route"`
+	}
+	if err := c.toml.UnmarshalTable(table, &routing); err != nil {
+		return err
+	}
+
+	if c.Router == nil {
+		c.Router = &models.Router{}
+	}
+	c.Router.Routes = append(c.Router.Routes, routing.Routes...)
+	if routing.Default != nil {
+		c.Router.Default = routing.Default
+	}
+
+	return nil
+}
+
 func (c *Config) addSecretStore(name, source string, table *ast.Table) error {
 	if len(c.SecretStoreFilters) > 0 && !sliceContains(name, c.SecretStoreFilters) {
 		return nil
@@ -1009,7 +1252,12 @@ func (c *Config) addSecretStore(name, source string, table *ast.Table) error {
 	if _, found := c.SecretStores[storeID]; found {
 		return fmt.Errorf("duplicate ID %q for secretstore %q", storeID, name)
 	}
+	id, err := generatePluginID("secretstores."+name, table)
+	if err != nil {
+		return err
+	}
 	c.SecretStores[storeID] = store
+	c.secretStoreIDs[storeID] = id
 	if _, found := c.secretStoreSource[name]; !found {
 		c.secretStoreSource[name] = make([]string, 0)
 	}
@@ -1017,6 +1265,12 @@ func (c *Config) addSecretStore(name, source string, table *ast.Table) error {
 	return nil
 }
 
+// SecretStoresEqual returns true if both configurations contain the same
+// secret-stores with identical settings.
+func (c *Config) SecretStoresEqual(other *Config) bool {
+	return maps.Equal(c.secretStoreIDs, other.secretStoreIDs)
+}
+
 func (c *Config) LinkSecrets() error {
 	for _, s := range unlinkedSecrets {
 		resolvers := make(map[string]telegraf.ResolveFunc)
@@ -1293,59 +1547,205 @@ func (c *Config) addOutput(name, source string, table *ast.Table) error {
 	}
 	output := creator()
 
+	hasSerializer, err := c.setOutputSerializer(name, table, output)
+	if err != nil {
+		return err
+	}
+	if hasSerializer {
+		missThreshold = 1
+	}
+
+	outputConfig, err := c.buildOutput(name, source, table)
+	if err != nil {
+		return err
+	}
+
+	// Create the outputs wrapped by the plugin. The tracker for missing
+	// fields is replaced when building the wrapped outputs, so restore it.
+	if p, ok := output.(outputs.ParentOutput); ok {
+		children, err := c.buildChildOutputs(name, table)
+		if err != nil {
+			return err
+		}
+		p.SetOutputs(children)
+		c.setLocalMissingTomlFieldTracker(missCount)
+	}
+
+	if err := c.toml.UnmarshalTable(table, output); err != nil {
+		return err
+	}
+
+	if err := c.printUserDeprecation("outputs", name, output); err != nil {
+		return err
+	}
+
+	if c, ok := interface{}(output).(interface{ TLSConfig() (*tls.Config, error) }); ok {
+		if _, err := c.TLSConfig(); err != nil {
+			return err
+		}
+	}
+
+	// Check the number of misses against the threshold
+	for key, count := range missCount {
+		if count <= missThreshold {
+			continue
+		}
+		if err := c.missingTomlField(nil, key); err != nil {
+			return err
+		}
+	}
+
+	// Reuse a running instance of the output if the configuration did not
+	// change to keep the buffered metrics
+	if ro := c.takeReusableOutput(outputConfig.ID); ro != nil {
+		c.Outputs = append(c.Outputs, ro)
+		return nil
+	}
+
+	ro := models.NewRunningOutput(output, outputConfig, c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	c.Outputs = append(c.Outputs, ro)
+
+	return nil
+}
+
+// setOutputSerializer builds and sets the serializer if the output can write
+// arbitrary types of output and returns if the output uses a serializer.
+func (c *Config) setOutputSerializer(name string, table *ast.Table, output telegraf.Output) (bool, error) {
+	var hasSerializer bool
+
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
 	if t, ok := output.(telegraf.SerializerPlugin); ok {
-		missThreshold = 1
+		hasSerializer = true
 		serializer, err := c.addSerializer(name, table)
 		if err != nil {
-			return err
+			return false, err
 		}
 		t.SetSerializer(serializer)
 	}
 
 	if t, ok := output.(telegraf.SerializerFuncPlugin); ok {
-		missThreshold = 1
+		hasSerializer = true
 		if !c.probeSerializer(table) {
-			return errors.New("serializer not found")
+			return false, errors.New("serializer not found")
 		}
 		t.SetSerializerFunc(func() (telegraf.Serializer, error) {
 			return c.addSerializer(name, table)
 		})
 	}
 
-	outputConfig, err := c.buildOutput(name, source, table)
+	return hasSerializer, nil
+}
+
+// buildChildOutputs creates the outputs configured in the "output" sub-table
+// of a parent output in the order of their definition. The sub-table is
+// removed from the parent's table afterwards.
+func (c *Config) buildChildOutputs(parent string, table *ast.Table) ([]*outputs.ChildOutput, error) {
+	node, found := table.Fields["output"]
+	if !found {
+		return nil, nil
+	}
+	delete(table.Fields, "output")
+
+	subtbl, ok := node.(*ast.Table)
+	if !ok {
+		return nil, fmt.Errorf("unsupported config format for outputs of %s", parent)
+	}
+
+	type childTable struct {
+		name  string
+		table *ast.Table
+	}
+	tables := make([]childTable, 0, len(subtbl.Fields))
+	for name, val := range subtbl.Fields {
+		switch t := val.(type) {
+		case *ast.Table:
+			tables = append(tables, childTable{name, t})
+		case []*ast.Table:
+			for _, tbl := range t {
+				tables = append(tables, childTable{name, tbl})
+			}
+		default:
+			return nil, fmt.Errorf("unsupported config format: %s.output.%s", parent, name)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].table.Line < tables[j].table.Line })
+
+	children := make([]*outputs.ChildOutput, 0, len(tables))
+	for _, t := range tables {
+		child, err := c.buildChildOutput(t.name, t.table)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s.output.%s: %w", parent, t.name, err)
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// buildChildOutput creates an output wrapped by a parent output. Only the
+// plugin options and the alias are supported for wrapped outputs.
+func (c *Config) buildChildOutput(name string, table *ast.Table) (*outputs.ChildOutput, error) {
+	missThreshold := 0
+	missCount := make(map[string]int)
+	c.setLocalMissingTomlFieldTracker(missCount)
+	defer c.resetMissingTomlFieldTracker()
+
+	creator, ok := outputs.Outputs[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined but requested output: %s", name)
+	}
+	output := creator()
+
+	hasSerializer, err := c.setOutputSerializer(name, table, output)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if hasSerializer {
+		missThreshold = 1
+	}
+
+	alias := c.getFieldString(table, "alias")
+	if c.hasErrs() {
+		return nil, c.firstErr()
 	}
 
 	if err := c.toml.UnmarshalTable(table, output); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := c.printUserDeprecation("outputs", name, output); err != nil {
-		return err
+		return nil, err
 	}
 
 	if c, ok := interface{}(output).(interface{ TLSConfig() (*tls.Config, error) }); ok {
 		if _, err := c.TLSConfig(); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	// Check the number of misses against the threshold
 	for key, count := range missCount {
 		if count <= missThreshold {
 			continue
 		}
 		if err := c.missingTomlField(nil, key); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	ro := models.NewRunningOutput(output, outputConfig, c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
-	c.Outputs = append(c.Outputs, ro)
+	models.SetLoggerOnPlugin(output, logging.New("outputs", name, alias))
+
+	return &outputs.ChildOutput{Name: name, Alias: alias, Output: output}, nil
+}
 
+// takeReusableOutput removes the first output with the given ID from the list
+// of reusable outputs and returns it. Nil is returned if no output matches.
+func (c *Config) takeReusableOutput(id string) *models.RunningOutput {
+	for i, ro := range c.ReuseOutputs {
+		if ro.Config.ID == id {
+			c.ReuseOutputs = append(c.ReuseOutputs[:i:i], c.ReuseOutputs[i+1:]...)
+			return ro
+		}
+	}
 	return nil
 }
 
@@ -1483,7 +1883,7 @@ func (c *Config) buildAggregator(name, source string, tbl *ast.Table) (*models.A
 	}
 
 	// Generate an ID for the plugin
-	conf.ID, err = generatePluginID("aggregators."+name, tbl)
+	conf.ID, err = c.pluginID("aggregators."+name, tbl)
 	return conf, err
 }
 
@@ -1511,7 +1911,7 @@ func (c *Config) buildProcessor(category, name, source string, tbl *ast.Table) (
 	}
 
 	// Generate an ID for the plugin
-	conf.ID, err = generatePluginID(category+"."+name, tbl)
+	conf.ID, err = c.pluginID(category+"."+name, tbl)
 	return conf, err
 }
 
@@ -1612,6 +2012,8 @@ func (c *Config) buildInput(name, source string, tbl *ast.Table) (*models.InputC
 	cp.CollectionOffset, _ = c.getFieldDuration(tbl, "collection_offset")
 	cp.StartupErrorBehavior = c.getFieldString(tbl, "startup_error_behavior")
 	cp.TimeSource = c.getFieldString(tbl, "time_source")
+	cp.MaxMetricsPerGather = c.getFieldInt(tbl, "max_metrics_per_gather")
+	cp.GatherDeadline, _ = c.getFieldDuration(tbl, "gather_deadline")
 
 	cp.MeasurementPrefix = c.getFieldString(tbl, "name_prefix")
 	cp.MeasurementSuffix = c.getFieldString(tbl, "name_suffix")
@@ -1639,7 +2041,7 @@ func (c *Config) buildInput(name, source string, tbl *ast.Table) (*models.InputC
 	}
 
 	// Generate an ID for the plugin
-	cp.ID, err = generatePluginID("inputs."+name, tbl)
+	cp.ID, err = c.pluginID("inputs."+name, tbl)
 	return cp, err
 }
 
@@ -1658,6 +2060,11 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 		Filter:          filter,
 		BufferStrategy:  c.Agent.BufferStrategy,
 		BufferDirectory: c.Agent.BufferDirectory,
+		BufferMaxBytes:  int64(c.Agent.BufferMaxBytes),
+		BufferMaxAge:    time.Duration(c.Agent.BufferMaxAge),
+	}
+	if c.ForceBufferStrategy != "" {
+		oc.BufferStrategy = c.ForceBufferStrategy
 	}
 
 	// TODO: support FieldPass/FieldDrop on outputs
@@ -1672,32 +2079,63 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	oc.NamePrefix = c.getFieldString(tbl, "name_prefix")
 	oc.StartupErrorBehavior = c.getFieldString(tbl, "startup_error_behavior")
 	oc.LogLevel = c.getFieldString(tbl, "log_level")
+	oc.CircuitBreakerThreshold = c.getFieldInt(tbl, "circuit_breaker_threshold")
+	oc.CircuitBreakerMinBackoff, _ = c.getFieldDuration(tbl, "circuit_breaker_min_backoff")
+	oc.CircuitBreakerMaxBackoff, _ = c.getFieldDuration(tbl, "circuit_breaker_max_backoff")
+	oc.CardinalityLimit = c.getFieldInt(tbl, "cardinality_limit")
+	oc.CardinalityWindow, _ = c.getFieldDuration(tbl, "cardinality_window")
+	oc.CardinalityAction = c.getFieldString(tbl, "cardinality_action")
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
 
-	if oc.BufferStrategy == "disk" {
+	switch oc.BufferStrategy {
+	case "disk":
 		log.Printf("W! Using disk buffer strategy for plugin outputs.%s, this is an experimental feature", name)
+	case "overflow":
+		log.Printf("W! Using overflow buffer strategy for plugin outputs.%s, this is an experimental feature", name)
 	}
 
 	// Generate an ID for the plugin
-	oc.ID, err = generatePluginID("outputs."+name, tbl)
+	oc.ID, err = c.pluginID("outputs."+name, tbl)
 	return oc, err
 }
 
+// pluginID generates the ID of a plugin, distinguishing identical plugins of
+// different pipelines
+func (c *Config) pluginID(prefix string, tbl *ast.Table) (string, error) {
+	if c.pipeline != "" {
+		prefix = "pipeline." + c.pipeline + "." + prefix
+	}
+	return generatePluginID(prefix, tbl)
+}
+
 func (c *Config) missingTomlField(_ reflect.Type, key string) error {
+	if !toleratedOption(key) {
+		c.unusedFieldsMutex.Lock()
+		c.UnusedFields[key] = true
+		c.unusedFieldsMutex.Unlock()
+	}
+	return nil
+}
+
+// toleratedOption returns true for options accepted by all plugins even if not
+// used by the plugin, e.g. general options of another plugin type
+func toleratedOption(key string) bool {
 	switch key {
 	// General options to ignore
 	case "alias", "always_include_local_tags",
 		"buffer_strategy", "buffer_directory",
+		"cardinality_action", "cardinality_limit", "cardinality_window",
+		"circuit_breaker_max_backoff", "circuit_breaker_min_backoff", "circuit_breaker_threshold",
 		"collection_jitter", "collection_offset",
 		"data_format", "delay", "drop", "drop_original",
 		"fielddrop", "fieldexclude", "fieldinclude", "fieldpass", "flush_interval", "flush_jitter",
-		"grace",
+		"gather_deadline", "grace",
 		"interval",
 		"log_level", "lvm", // What is this used for?
-		"metric_batch_size", "metric_buffer_limit", "metricpass",
+		"max_metrics_per_gather", "metric_batch_size", "metric_buffer_limit", "metricpass",
 		"name_override", "name_prefix", "name_suffix", "namedrop", "namedrop_separator", "namepass", "namepass_separator",
 		"order",
 		"pass", "period", "precision",
@@ -1710,11 +2148,9 @@ func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 	case "data_type", "influx_parser_type":
 
 	default:
-		c.unusedFieldsMutex.Lock()
-		c.UnusedFields[key] = true
-		c.unusedFieldsMutex.Unlock()
+		return false
 	}
-	return nil
+	return true
 }
 
 func (c *Config) setLocalMissingTomlFieldTracker(counter map[string]int) {
@@ -614,6 +614,193 @@ func TestConfig_Filtering(t *testing.T) {
 	testutil.RequireMetricsEqual(t, expected, actual, testutil.SortMetrics())
 }
 
+func TestConfig_Routing(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/routing.toml"))
+	require.NotNil(t, c.Router)
+	require.Equal(t, []string{"archive"}, c.Router.Default)
+	require.Len(t, c.Router.Routes, 2)
+	require.Equal(t, "alerts", c.Router.Routes[0].Name)
+	require.Equal(t, `name == "alert"`, c.Router.Routes[0].MetricPass)
+	require.Equal(t, []string{"pager", "archive"}, c.Router.Routes[0].Outputs)
+	require.Equal(t, "metrics", c.Router.Routes[1].Name)
+	require.Equal(t, []string{"timeseries"}, c.Router.Routes[1].Outputs)
+	require.NoError(t, c.Router.Init())
+}
+
+func TestConfig_RoutingInvalidField(t *testing.T) {
+	c := config.NewConfig()
+	err := c.LoadConfig("./testdata/routing_invalid_field.toml")
+	require.ErrorContains(t, err, `configuration specified the fields ["output"], but they were not used`)
+}
+
+func TestConfig_Pipelines(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/pipelines.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Empty(t, c.Processors)
+	require.Empty(t, c.Outputs)
+	require.Equal(t, []string{"security", "metrics"}, c.PipelineNames())
+
+	security := c.Pipelines[0]
+	require.Len(t, security.Inputs, 2)
+	require.Len(t, security.Outputs, 1)
+	require.Len(t, security.Processors, 2)
+	require.Equal(t, "first", security.Processors[0].Config.Alias)
+	require.Equal(t, "second", security.Processors[1].Config.Alias)
+
+	metrics := c.Pipelines[1]
+	require.Len(t, metrics.Inputs, 1)
+	require.Empty(t, metrics.Processors)
+	require.Empty(t, metrics.Outputs)
+
+	// Identical plugins in different pipelines must have different IDs
+	ids := map[string]bool{
+		c.Inputs[0].ID():        true,
+		security.Inputs[0].ID(): true,
+		security.Inputs[1].ID(): true,
+		metrics.Inputs[0].ID():  true,
+	}
+	require.Len(t, ids, 4)
+}
+
+func TestConfig_PipelinesInvalid(t *testing.T) {
+	c := config.NewConfig()
+	err := c.LoadConfig("./testdata/pipelines_invalid.toml")
+	require.ErrorContains(t, err, `unsupported table "secretstores" in pipeline`)
+
+	c = config.NewConfig()
+	data := "[[pipeline]]\n  name = \"a\"\n[[pipeline]]\n  name = \"a\"\n"
+	require.ErrorContains(t, c.LoadConfigData([]byte(data), config.EmptySourcePath), `duplicate pipeline "a"`)
+
+	c = config.NewConfig()
+	data = "[[pipeline]]\n  [[pipeline.inputs.memcached]]\n"
+	require.ErrorContains(t, c.LoadConfigData([]byte(data), config.EmptySourcePath), "pipeline without name")
+}
+
+func TestConfig_Templates(t *testing.T) {
+	c := config.NewConfig()
+	c.KeepExpandedConfig = true
+	require.NoError(t, c.LoadConfig("./testdata/templates.toml"))
+	require.Len(t, c.Inputs, 2)
+
+	// The settings of the plugin take precedence over the templates and
+	// later templates over earlier ones
+	first := c.Inputs[0]
+	require.Equal(t, []string{"localhost"}, first.Input.(*MockupInputPlugin).Servers)
+	require.Equal(t, map[string]string{"team": "db", "env": "prod"}, first.Config.Tags)
+	password, err := first.Input.(*MockupInputPlugin).Password.Get()
+	require.NoError(t, err)
+	require.Equal(t, "secret", password.String())
+	password.Destroy()
+
+	second := c.Inputs[1]
+	require.Equal(t, []string{"192.168.1.1"}, second.Input.(*MockupInputPlugin).Servers)
+	require.Equal(t, map[string]string{"team": "ops", "env": "dev"}, second.Config.Tags)
+
+	// Templates must not be modified by the plugins
+	require.Len(t, c.Pipelines, 1)
+	require.Equal(t, map[string]string{"env": "prod"}, c.Pipelines[0].Inputs[0].Config.Tags)
+
+	expanded, err := c.ExpandedConfig()
+	require.NoError(t, err)
+	require.NotContains(t, string(expanded), "[templates")
+	require.NotContains(t, string(expanded), "inherit")
+	require.Contains(t, string(expanded), `servers = ["192.168.1.1"]`)
+
+	// Secrets are masked
+	require.Contains(t, string(expanded), `password = "********"`)
+	require.NotContains(t, string(expanded), "secret")
+}
+
+func TestConfig_TemplatesInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{
+			name:     "unknown template",
+			data:     "[[inputs.memcached]]\n  inherit = [\"foo\"]\n",
+			expected: `plugin inputs.memcached: line 1: unknown template "foo"`,
+		},
+		{
+			name:     "invalid inherit",
+			data:     "[templates.foo]\n  servers = [\"localhost\"]\n[[inputs.memcached]]\n  inherit = \"foo\"\n",
+			expected: `invalid "inherit" setting`,
+		},
+		{
+			name:     "nested inheritance",
+			data:     "[templates.foo]\n  inherit = [\"bar\"]\n",
+			expected: `template "foo" cannot inherit other templates`,
+		},
+		{
+			name:     "template not a table",
+			data:     "[templates]\n  foo = 42\n",
+			expected: `invalid template "foo"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewConfig()
+			require.ErrorContains(t, c.LoadConfigData([]byte(tt.data), config.EmptySourcePath), tt.expected)
+		})
+	}
+
+	// Templates cannot be redefined in a later file
+	c := config.NewConfig()
+	data := []byte("[templates.foo]\n  servers = [\"localhost\"]\n")
+	require.NoError(t, c.LoadConfigData(data, config.EmptySourcePath))
+	require.ErrorContains(t, c.LoadConfigData(data, config.EmptySourcePath), `duplicate template "foo"`)
+}
+
+func TestConfig_Effective(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/effective.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Len(t, c.Outputs, 2)
+
+	effective, err := c.EffectiveConfig()
+	require.NoError(t, err)
+	actual := string(effective)
+
+	// Plugin settings contain the plugin defaults and general options resolved
+	// against the agent settings
+	expectedInput := "# ID: " + c.Inputs[0].ID() + "\n" +
+		"# Source: ./testdata/effective.toml\n" +
+		"[[inputs.memcached]]\n"
+	require.Contains(t, actual, expectedInput)
+	require.Contains(t, actual, `servers = ["localhost"]`)
+	require.Contains(t, actual, `timeout = "5s"`)
+	require.Contains(t, actual, `interval = "10s"`)
+	require.Contains(t, actual, `namepass = ["memcached"]`)
+	require.Contains(t, actual, `namespace_prefix = "Telegraf/"`)
+
+	// Secrets are masked
+	require.Contains(t, actual, `password = "********"`)
+	require.Contains(t, actual, `api_token = "********"`)
+	require.NotContains(t, actual, "secret")
+
+	// Output settings take precedence over agent settings
+	for _, output := range c.Outputs {
+		header := "# ID: " + output.ID() + "\n"
+		require.Contains(t, actual, header)
+		section := actual[strings.Index(actual, header):]
+		if next := strings.Index(section[len(header):], "# ID: "); next >= 0 {
+			section = section[:len(header)+next]
+		}
+		switch output.Config.Name {
+		case "azure_monitor":
+			require.Contains(t, section, `flush_interval = "5s"`)
+			require.Contains(t, section, "metric_batch_size = 100\n")
+		case "http":
+			require.Contains(t, section, `flush_interval = "20s"`)
+			require.Contains(t, section, "metric_batch_size = 500\n")
+			require.Contains(t, section, "metric_buffer_limit = 10000\n")
+		}
+	}
+}
+
 func TestConfig_SerializerInterfaceNewFormat(t *testing.T) {
 	formats := []string{
 		"carbon2",
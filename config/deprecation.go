@@ -134,19 +134,11 @@ func (c *Config) collectDeprecationInfo(category, name string, plugin interface{
 			return
 		}
 
-		tags := strings.SplitN(field.Tag.Get("deprecated"), ";", 3)
-		if len(tags) < 1 || tags[0] == "" {
+		deprecation, deprecated := optionDeprecation(field.Tag.Get("deprecated"))
+		if !deprecated {
 			return
 		}
-		optionInfo := DeprecationInfo{Name: field.Name}
-		optionInfo.info.Since = tags[0]
-
-		if len(tags) > 1 {
-			optionInfo.info.Notice = tags[len(tags)-1]
-		}
-		if len(tags) > 2 {
-			optionInfo.info.RemovalIn = tags[1]
-		}
+		optionInfo := DeprecationInfo{Name: field.Name, info: deprecation}
 		if err := optionInfo.determineEscalation(); err != nil {
 			panic(fmt.Errorf("plugin %q option %q: %w", info.Name, field.Name, err))
 		}
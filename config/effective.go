@@ -0,0 +1,394 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/influxdata/toml"
+
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// maskedSecret replaces the value of secrets in the effective configuration
+const maskedSecret = "********"
+
+var (
+	durationType     = reflect.TypeOf(Duration(0))
+	timeDurationType = reflect.TypeOf(time.Duration(0))
+	secretType       = reflect.TypeOf(Secret{})
+	textMarshaler    = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// EffectiveConfig returns the configuration used when running the loaded
+// plugins. The settings of each plugin contain the defaults of the plugin,
+// the general plugin options resolved against the agent settings and the
+// generated ID of the plugin. Secrets are masked.
+func (c *Config) EffectiveConfig() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if len(c.Tags) > 0 {
+		if err := writeEffectiveTable(&buf, map[string]interface{}{"global_tags": c.Tags}); err != nil {
+			return nil, fmt.Errorf("marshalling global tags failed: %w", err)
+		}
+		buf.WriteString("\n")
+	}
+	if err := writeEffectiveTable(&buf, map[string]interface{}{"agent": effectiveSettings(&c.Agent)}); err != nil {
+		return nil, fmt.Errorf("marshalling agent settings failed: %w", err)
+	}
+
+	if err := c.writeEffectivePlugins(&buf, "", c.Inputs, c.Processors, c.Aggregators, c.Outputs); err != nil {
+		return nil, err
+	}
+	for _, p := range c.Pipelines {
+		buf.WriteString("\n[[pipeline]]\n")
+		fmt.Fprintf(&buf, "name = %q\n", p.Name)
+		if err := c.writeEffectivePlugins(&buf, "pipeline", p.Inputs, p.Processors, p.Aggregators, p.Outputs); err != nil {
+			return nil, fmt.Errorf("pipeline %q: %w", p.Name, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *Config) writeEffectivePlugins(
+	buf *bytes.Buffer,
+	prefix string,
+	inputs []*models.RunningInput,
+	procs models.RunningProcessors,
+	aggs []*models.RunningAggregator,
+	outputs []*models.RunningOutput,
+) error {
+	for _, input := range inputs {
+		settings := effectiveSettings(input.Input)
+		c.effectiveInputOptions(settings, input.Config)
+		if err := writeEffectivePlugin(buf, prefix, "inputs", input.Config.Name, input.ID(), input.Config.Source, settings); err != nil {
+			return err
+		}
+	}
+	for _, proc := range procs {
+		var plugin interface{} = proc.Processor
+		if p, ok := proc.Processor.(processors.HasUnwrap); ok {
+			plugin = p.Unwrap()
+		}
+		settings := effectiveSettings(plugin)
+		settings["order"] = proc.Config.Order
+		effectiveCommonOptions(settings, proc.Config.Alias, proc.Config.LogLevel, &proc.Config.Filter)
+		if err := writeEffectivePlugin(buf, prefix, "processors", proc.Config.Name, proc.ID(), proc.Config.Source, settings); err != nil {
+			return err
+		}
+	}
+	for _, agg := range aggs {
+		settings := effectiveSettings(agg.Aggregator)
+		effectiveAggregatorOptions(settings, agg.Config)
+		if err := writeEffectivePlugin(buf, prefix, "aggregators", agg.Config.Name, agg.ID(), agg.Config.Source, settings); err != nil {
+			return err
+		}
+	}
+	for _, output := range outputs {
+		settings := effectiveSettings(output.Output)
+		c.effectiveOutputOptions(settings, output)
+		if err := writeEffectivePlugin(buf, prefix, "outputs", output.Config.Name, output.ID(), output.Config.Source, settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEffectivePlugin(buf *bytes.Buffer, prefix, category, name, id, source string, settings map[string]interface{}) error {
+	table := map[string]interface{}{category: map[string]interface{}{name: []interface{}{settings}}}
+	if prefix != "" {
+		table = map[string]interface{}{prefix: table}
+	}
+
+	buf.WriteString("\n")
+	fmt.Fprintf(buf, "# ID: %s\n", id)
+	if source != "" && source != EmptySourcePath {
+		fmt.Fprintf(buf, "# Source: %s\n", source)
+	}
+	if err := writeEffectiveTable(buf, table); err != nil {
+		return fmt.Errorf("marshalling settings of plugin %s.%s failed: %w", category, name, err)
+	}
+	return nil
+}
+
+func writeEffectiveTable(buf *bytes.Buffer, table map[string]interface{}) error {
+	out, err := toml.Marshal(table)
+	if err != nil {
+		return err
+	}
+	buf.Write(out)
+	return nil
+}
+
+// effectiveInputOptions adds the general input options, falling back to the
+// agent settings if not set for the plugin
+func (c *Config) effectiveInputOptions(settings map[string]interface{}, cfg *models.InputConfig) {
+	settings["interval"] = effectiveDuration(cfg.Interval, c.Agent.Interval)
+	settings["precision"] = effectiveDuration(cfg.Precision, c.Agent.Precision)
+	settings["collection_jitter"] = effectiveDuration(cfg.CollectionJitter, c.Agent.CollectionJitter)
+	settings["collection_offset"] = effectiveDuration(cfg.CollectionOffset, c.Agent.CollectionOffset)
+	settings["startup_error_behavior"] = effectiveString(cfg.StartupErrorBehavior, "error")
+	settings["max_metrics_per_gather"] = cfg.MaxMetricsPerGather
+	settings["gather_deadline"] = cfg.GatherDeadline.String()
+	settings["always_include_local_tags"] = cfg.AlwaysIncludeLocalTags
+	settings["always_include_global_tags"] = cfg.AlwaysIncludeGlobalTags
+	effectiveNameOptions(settings, cfg.NameOverride, cfg.MeasurementPrefix, cfg.MeasurementSuffix, cfg.Tags)
+	effectiveCommonOptions(settings, cfg.Alias, cfg.LogLevel, &cfg.Filter)
+}
+
+// effectiveOutputOptions adds the general output options, falling back to the
+// agent settings if not set for the plugin
+func (c *Config) effectiveOutputOptions(settings map[string]interface{}, output *models.RunningOutput) {
+	cfg := output.Config
+	settings["flush_interval"] = effectiveDuration(cfg.FlushInterval, c.Agent.FlushInterval)
+	settings["flush_jitter"] = effectiveDuration(cfg.FlushJitter, c.Agent.FlushJitter)
+	settings["metric_batch_size"] = output.MetricBatchSize
+	settings["metric_buffer_limit"] = output.MetricBufferLimit
+	settings["buffer_strategy"] = effectiveString(cfg.BufferStrategy, "memory")
+	if cfg.BufferDirectory != "" {
+		settings["buffer_directory"] = cfg.BufferDirectory
+	}
+	if cfg.BufferMaxBytes > 0 {
+		settings["buffer_max_bytes"] = cfg.BufferMaxBytes
+	}
+	if cfg.BufferMaxAge > 0 {
+		settings["buffer_max_age"] = cfg.BufferMaxAge.String()
+	}
+	if cfg.CircuitBreakerThreshold > 0 {
+		settings["circuit_breaker_threshold"] = cfg.CircuitBreakerThreshold
+		settings["circuit_breaker_min_backoff"] = cfg.CircuitBreakerMinBackoff.String()
+		settings["circuit_breaker_max_backoff"] = cfg.CircuitBreakerMaxBackoff.String()
+	}
+	if cfg.CardinalityLimit > 0 {
+		settings["cardinality_limit"] = cfg.CardinalityLimit
+		settings["cardinality_window"] = effectiveDuration(cfg.CardinalityWindow, Duration(models.DefaultCardinalityWindow))
+		settings["cardinality_action"] = effectiveString(cfg.CardinalityAction, "drop")
+	}
+	settings["startup_error_behavior"] = effectiveString(cfg.StartupErrorBehavior, "error")
+	effectiveNameOptions(settings, cfg.NameOverride, cfg.NamePrefix, cfg.NameSuffix, nil)
+	effectiveCommonOptions(settings, cfg.Alias, cfg.LogLevel, &cfg.Filter)
+}
+
+// effectiveAggregatorOptions adds the general aggregator options
+func effectiveAggregatorOptions(settings map[string]interface{}, cfg *models.AggregatorConfig) {
+	settings["period"] = cfg.Period.String()
+	settings["delay"] = cfg.Delay.String()
+	settings["grace"] = cfg.Grace.String()
+	settings["drop_original"] = cfg.DropOriginal
+	effectiveNameOptions(settings, cfg.NameOverride, cfg.MeasurementPrefix, cfg.MeasurementSuffix, cfg.Tags)
+	effectiveCommonOptions(settings, cfg.Alias, cfg.LogLevel, &cfg.Filter)
+}
+
+func effectiveNameOptions(settings map[string]interface{}, override, prefix, suffix string, tags map[string]string) {
+	if override != "" {
+		settings["name_override"] = override
+	}
+	if prefix != "" {
+		settings["name_prefix"] = prefix
+	}
+	if suffix != "" {
+		settings["name_suffix"] = suffix
+	}
+	if len(tags) > 0 {
+		settings["tags"] = tags
+	}
+}
+
+// effectiveCommonOptions adds the options available for all plugin types. Only
+// the filter options in use are added.
+func effectiveCommonOptions(settings map[string]interface{}, alias, logLevel string, f *models.Filter) {
+	if alias != "" {
+		settings["alias"] = alias
+	}
+	if logLevel != "" {
+		settings["log_level"] = logLevel
+	}
+
+	if len(f.NamePass) > 0 {
+		settings["namepass"] = f.NamePass
+		if f.NamePassSeparators != "" {
+			settings["namepass_separator"] = f.NamePassSeparators
+		}
+	}
+	if len(f.NameDrop) > 0 {
+		settings["namedrop"] = f.NameDrop
+		if f.NameDropSeparators != "" {
+			settings["namedrop_separator"] = f.NameDropSeparators
+		}
+	}
+	if len(f.FieldInclude) > 0 {
+		settings["fieldinclude"] = f.FieldInclude
+	}
+	if len(f.FieldExclude) > 0 {
+		settings["fieldexclude"] = f.FieldExclude
+	}
+	if len(f.TagPassFilters) > 0 {
+		settings["tagpass"] = effectiveTagFilters(f.TagPassFilters)
+	}
+	if len(f.TagDropFilters) > 0 {
+		settings["tagdrop"] = effectiveTagFilters(f.TagDropFilters)
+	}
+	if len(f.TagInclude) > 0 {
+		settings["taginclude"] = f.TagInclude
+	}
+	if len(f.TagExclude) > 0 {
+		settings["tagexclude"] = f.TagExclude
+	}
+	if f.MetricPass != "" {
+		settings["metricpass"] = f.MetricPass
+	}
+}
+
+func effectiveTagFilters(filters []models.TagFilter) map[string][]string {
+	table := make(map[string][]string, len(filters))
+	for _, f := range filters {
+		table[f.Name] = f.Values
+	}
+	return table
+}
+
+func effectiveDuration(value time.Duration, fallback Duration) string {
+	if value != 0 {
+		return value.String()
+	}
+	return time.Duration(fallback).String()
+}
+
+func effectiveString(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
+
+// effectiveSettings returns the settings of the given plugin or configuration
+// struct keyed by their TOML name. Settings which cannot be represented in
+// TOML, like interfaces or channels, are omitted.
+func effectiveSettings(plugin interface{}) map[string]interface{} {
+	settings := make(map[string]interface{})
+	if plugin == nil {
+		return settings
+	}
+	rv := reflect.ValueOf(plugin)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return settings
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		structSettings(settings, rv, make(map[uintptr]bool))
+	}
+	return settings
+}
+
+func structSettings(settings map[string]interface{}, rv reflect.Value, seen map[uintptr]bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		ft := rt.Field(i)
+		if ft.PkgPath != "" && !ft.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(ft.Tag.Get("toml"), ",")
+		name = strings.TrimSpace(name)
+		if name == "-" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if ft.Anonymous && name == "" {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				structSettings(settings, fv, seen)
+			}
+			continue
+		}
+		if ft.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = toml.DefaultConfig.FieldToKey(rt, ft.Name)
+		}
+		if v, ok := settingValue(fv, seen); ok {
+			settings[name] = v
+		}
+	}
+}
+
+// settingValue converts the given value to a TOML representable value
+func settingValue(rv reflect.Value, seen map[uintptr]bool) (interface{}, bool) {
+	switch rv.Type() {
+	case secretType:
+		if rv.IsZero() {
+			return "", true
+		}
+		return maskedSecret, true
+	case durationType, timeDurationType:
+		return time.Duration(rv.Int()).String(), true
+	}
+	if rv.Kind() != reflect.Ptr && rv.Kind() != reflect.Interface && rv.CanInterface() && rv.Type().Implements(textMarshaler) {
+		text, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, false
+		}
+		return string(text), true
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Ptr:
+		if rv.IsNil() || seen[rv.Pointer()] {
+			return nil, false
+		}
+		seen[rv.Pointer()] = true
+		defer delete(seen, rv.Pointer())
+		return settingValue(rv.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			v, ok := settingValue(rv.Index(i), seen)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, v)
+		}
+		return values, true
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		values := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			if v, ok := settingValue(iter.Value(), seen); ok {
+				values[iter.Key().String()] = v
+			}
+		}
+		return values, true
+	case reflect.Struct:
+		values := make(map[string]interface{})
+		structSettings(values, rv, seen)
+		return values, true
+	}
+	return nil, false
+}
@@ -0,0 +1,820 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// SchemaDialect is the JSON Schema dialect of the generated schemas
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	sizeType          = reflect.TypeOf(Size(0))
+	timeType          = reflect.TypeOf(time.Time{})
+	textUnmarshaler   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	tomlUnmarshaler   = reflect.TypeOf((*toml.Unmarshaler)(nil)).Elem()
+	tomlUnmarshalerRe = reflect.TypeOf((*toml.UnmarshalerRec)(nil)).Elem()
+)
+
+// Schema is a JSON Schema describing the options of a plugin. Besides the
+// standard keywords, the Go type and the deprecation of options are provided
+// in the "x-go-type" and "x-deprecation" keywords.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              interface{}        `json:"minimum,omitempty"`
+	Maximum              interface{}        `json:"maximum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+	Deprecation          *SchemaDeprecation `json:"x-deprecation,omitempty"`
+	GoType               string             `json:"x-go-type,omitempty"`
+
+	// text is the type used to check the values of options implementing
+	// encoding.TextUnmarshaler, e.g. durations
+	text reflect.Type
+	// untagged maps the normalized names of options without TOML tag to the
+	// option name as the TOML decoder matches those case-insensitive
+	untagged map[string]string
+}
+
+// SchemaDeprecation describes the deprecation of a plugin or option
+type SchemaDeprecation struct {
+	Since     string `json:"since"`
+	RemovalIn string `json:"removal_in,omitempty"`
+	Notice    string `json:"notice,omitempty"`
+}
+
+// PluginSchema returns the schema of the options of the given plugin,
+// including the general options of the plugin category. Defaults are given
+// for options with a non-zero value after creating the plugin. Options of
+// plugins accepting a data format additionally depend on the parser or
+// serializer selected via "data_format", see the schemas of the respective
+// parsers and serializers.
+func PluginSchema(category, name string) (*Schema, error) {
+	plugin, err := createPlugin(category, name)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{
+		Schema:               SchemaDialect,
+		Title:                category + "." + name,
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+	structSchema(schema, reflect.ValueOf(plugin), make(map[reflect.Type]bool))
+	for option, s := range generalOptionSchemas(category) {
+		if _, found := schema.Properties[option]; !found {
+			schema.Properties[option] = s
+		}
+	}
+
+	if info, deprecated := pluginDeprecation(category, name); deprecated {
+		schema.Deprecated = true
+		schema.Deprecation = &SchemaDeprecation{Since: info.Since, RemovalIn: info.RemovalIn, Notice: info.Notice}
+	}
+
+	if _, ok := plugin.(outputs.ParentOutput); ok {
+		schema.Properties["output"] = &Schema{
+			Type:        "object",
+			Description: "Outputs wrapped by the plugin configured as 'output.<name>' sub-tables, see the schemas of the respective outputs.",
+		}
+	}
+
+	if format := dataFormatCategory(category, plugin); format != "" {
+		schema.Properties["data_format"] = dataFormatSchema(format)
+		schema.AdditionalProperties = true
+		schema.Description = "Additional options are given by the schema of the " + format + " selected via 'data_format'."
+	}
+
+	return schema, nil
+}
+
+func createPlugin(category, name string) (interface{}, error) {
+	var plugin interface{}
+	switch category {
+	case "inputs":
+		if creator, found := inputs.Inputs[name]; found {
+			plugin = creator()
+		}
+	case "outputs":
+		if creator, found := outputs.Outputs[name]; found {
+			plugin = creator()
+		}
+	case "processors":
+		if creator, found := processors.Processors[name]; found {
+			p := creator()
+			if unwrapped, ok := p.(processors.HasUnwrap); ok {
+				plugin = unwrapped.Unwrap()
+			} else {
+				plugin = p
+			}
+		}
+	case "aggregators":
+		if creator, found := aggregators.Aggregators[name]; found {
+			plugin = creator()
+		}
+	case "secretstores":
+		if creator, found := secretstores.SecretStores[name]; found {
+			plugin = creator("")
+		}
+	case "parsers":
+		if creator, found := parsers.Parsers[name]; found {
+			plugin = creator("")
+		}
+	case "serializers":
+		if creator, found := serializers.Serializers[name]; found {
+			plugin = creator()
+		}
+	default:
+		return nil, fmt.Errorf("invalid plugin category %q", category)
+	}
+	if plugin == nil {
+		return nil, fmt.Errorf("unknown plugin %s.%s", category, name)
+	}
+	return plugin, nil
+}
+
+func pluginDeprecation(category, name string) (telegraf.DeprecationInfo, bool) {
+	var info telegraf.DeprecationInfo
+	var deprecated bool
+	switch category {
+	case "inputs":
+		info, deprecated = inputs.Deprecations[name]
+	case "outputs":
+		info, deprecated = outputs.Deprecations[name]
+	case "processors":
+		info, deprecated = processors.Deprecations[name]
+	case "aggregators":
+		info, deprecated = aggregators.Deprecations[name]
+	case "secretstores":
+		info, deprecated = secretstores.Deprecations[name]
+	case "parsers":
+		info, deprecated = parsers.Deprecations[name]
+	case "serializers":
+		info, deprecated = serializers.Deprecations[name]
+	}
+	return info, deprecated
+}
+
+// dataFormatCategory returns the category of the data formats accepted by the
+// plugin, i.e. "parsers" or "serializers", or an empty string if the plugin
+// does not accept a data format.
+func dataFormatCategory(category string, plugin interface{}) string {
+	switch plugin.(type) {
+	case telegraf.ParserPlugin, telegraf.ParserFuncPlugin:
+		if category == "inputs" || category == "processors" {
+			return "parsers"
+		}
+	case telegraf.SerializerPlugin, telegraf.SerializerFuncPlugin:
+		if category == "outputs" || category == "processors" {
+			return "serializers"
+		}
+	}
+	return ""
+}
+
+func dataFormatSchema(format string) *Schema {
+	var names []string
+	switch format {
+	case "parsers":
+		for name := range parsers.Parsers {
+			names = append(names, name)
+		}
+	case "serializers":
+		for name := range serializers.Serializers {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return &Schema{Type: "string", Enum: names, GoType: "string"}
+}
+
+// structSchema adds the options of the given struct to the schema
+func structSchema(schema *Schema, rv reflect.Value, seen map[reflect.Type]bool) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		ft := rt.Field(i)
+		if ft.PkgPath != "" && !ft.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(ft.Tag.Get("toml"), ",")
+		name = strings.TrimSpace(name)
+		if name == "-" {
+			continue
+		}
+		if ft.Anonymous && ft.Type.Kind() == reflect.Struct && name == "" {
+			structSchema(schema, rv.Field(i), seen)
+			continue
+		}
+		if ft.PkgPath != "" {
+			continue
+		}
+
+		s := typeSchema(ft.Type, seen)
+		if s == nil {
+			continue
+		}
+		s.GoType = ft.Type.String()
+		if fv := rv.Field(i); !fv.IsZero() && ft.Type != secretType {
+			if v, ok := settingValue(fv, make(map[uintptr]bool)); ok {
+				s.Default = v
+			}
+		}
+		if info, deprecated := optionDeprecation(ft.Tag.Get("deprecated")); deprecated {
+			s.Deprecated = true
+			s.Deprecation = &SchemaDeprecation{Since: info.Since, RemovalIn: info.RemovalIn, Notice: info.Notice}
+		}
+
+		if name == "" {
+			name = toml.DefaultConfig.FieldToKey(rt, ft.Name)
+			if schema.untagged == nil {
+				schema.untagged = make(map[string]string)
+			}
+			schema.untagged[toml.DefaultConfig.NormFieldName(rt, ft.Name)] = name
+		}
+		if _, found := schema.Properties[name]; !found {
+			schema.Properties[name] = s
+		}
+	}
+}
+
+// typeSchema returns the schema of values of the given type or nil if the
+// type cannot be configured
+func typeSchema(rt reflect.Type, seen map[reflect.Type]bool) *Schema {
+	switch rt {
+	case secretType:
+		return &Schema{Type: "string"}
+	case durationType, timeDurationType:
+		return &Schema{Type: []string{"string", "integer", "number"}, text: durationType}
+	case sizeType:
+		return &Schema{Type: []string{"string", "integer"}, text: sizeType}
+	case timeType:
+		return &Schema{Type: "string"}
+	}
+
+	pt := reflect.PointerTo(rt)
+	if pt.Implements(tomlUnmarshaler) || pt.Implements(tomlUnmarshalerRe) {
+		return &Schema{}
+	}
+	if pt.Implements(textUnmarshaler) {
+		return &Schema{Type: "string", text: rt}
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		bits := rt.Bits()
+		return &Schema{Type: "integer", Minimum: int64(-1) << (bits - 1), Maximum: int64(1)<<(bits-1) - 1}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Minimum: int64(0), Maximum: int64(1)<<rt.Bits() - 1}
+	case reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: int64(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Ptr:
+		return typeSchema(rt.Elem(), seen)
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Slice, reflect.Array:
+		items := typeSchema(rt.Elem(), seen)
+		if items == nil {
+			return nil
+		}
+		return &Schema{Type: "array", Items: items}
+	case reflect.Map:
+		if rt.Key().Kind() != reflect.String {
+			return nil
+		}
+		values := typeSchema(rt.Elem(), seen)
+		if values == nil {
+			return nil
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}
+	case reflect.Struct:
+		// Stop on recursive types and accept any value
+		if seen[rt] {
+			return &Schema{Type: "object"}
+		}
+		seen[rt] = true
+		defer delete(seen, rt)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		structSchema(s, reflect.New(rt).Elem(), seen)
+		return s
+	}
+	return nil
+}
+
+// optionDeprecation parses the "deprecated" tag of an option in the format
+// "<since>;<removal in>;<notice>" or "<since>;<notice>"
+func optionDeprecation(tag string) (telegraf.DeprecationInfo, bool) {
+	parts := strings.SplitN(tag, ";", 3)
+	if parts[0] == "" {
+		return telegraf.DeprecationInfo{}, false
+	}
+
+	info := telegraf.DeprecationInfo{Since: parts[0]}
+	if len(parts) > 1 {
+		info.Notice = parts[len(parts)-1]
+	}
+	if len(parts) > 2 {
+		info.RemovalIn = parts[1]
+	}
+	return info, true
+}
+
+// generalOptionSchemas returns the schemas of the options handled by Telegraf
+// for all plugins of the given category
+func generalOptionSchemas(category string) map[string]*Schema {
+	str := func() *Schema { return &Schema{Type: "string", GoType: "string"} }
+	strs := func() *Schema { return &Schema{Type: "array", Items: &Schema{Type: "string"}, GoType: "[]string"} }
+	duration := func() *Schema {
+		return &Schema{Type: []string{"string", "integer", "number"}, GoType: "config.Duration", text: durationType}
+	}
+	integer := func() *Schema { return &Schema{Type: "integer", Minimum: int64(0), GoType: "int"} }
+	boolean := func() *Schema { return &Schema{Type: "boolean", GoType: "bool"} }
+	tagFilter := func() *Schema {
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: &Schema{Type: "array", Items: &Schema{Type: "string"}},
+			GoType:               "map[string][]string",
+		}
+	}
+	deprecated := func(s *Schema, since, removal, notice string) *Schema {
+		s.Deprecated = true
+		s.Deprecation = &SchemaDeprecation{Since: since, RemovalIn: removal, Notice: notice}
+		return s
+	}
+
+	var options map[string]*Schema
+	switch category {
+	case "secretstores":
+		return map[string]*Schema{"id": str()}
+	case "parsers", "serializers":
+		return map[string]*Schema{"log_level": str()}
+	case "inputs":
+		options = map[string]*Schema{
+			"interval":                   duration(),
+			"precision":                  duration(),
+			"collection_jitter":          duration(),
+			"collection_offset":          duration(),
+			"startup_error_behavior":     {Type: "string", Enum: []string{"error", "retry", "ignore", "probe"}, GoType: "string"},
+			"time_source":                str(),
+			"max_metrics_per_gather":     integer(),
+			"gather_deadline":            duration(),
+			"name_override":              str(),
+			"name_prefix":                str(),
+			"name_suffix":                str(),
+			"tags":                       {Type: "object", AdditionalProperties: &Schema{Type: "string"}, GoType: "map[string]string"},
+			"always_include_local_tags":  boolean(),
+			"always_include_global_tags": boolean(),
+		}
+	case "outputs":
+		options = map[string]*Schema{
+			"flush_interval":              duration(),
+			"flush_jitter":                duration(),
+			"metric_batch_size":           integer(),
+			"metric_buffer_limit":         integer(),
+			"startup_error_behavior":      {Type: "string", Enum: []string{"error", "retry", "ignore"}, GoType: "string"},
+			"name_override":               str(),
+			"name_prefix":                 str(),
+			"name_suffix":                 str(),
+			"buffer_strategy":             {Type: "string", Enum: []string{"memory", "disk", "overflow"}, GoType: "string"},
+			"buffer_directory":            str(),
+			"circuit_breaker_threshold":   integer(),
+			"circuit_breaker_min_backoff": duration(),
+			"circuit_breaker_max_backoff": duration(),
+			"cardinality_limit":           integer(),
+			"cardinality_window":          duration(),
+			"cardinality_action":          {Type: "string", Enum: []string{"drop", "aggregate"}, GoType: "string"},
+		}
+	case "processors":
+		options = map[string]*Schema{
+			"order": {Type: "integer", GoType: "int64"},
+		}
+	case "aggregators":
+		options = map[string]*Schema{
+			"period":        duration(),
+			"delay":         duration(),
+			"grace":         duration(),
+			"drop_original": boolean(),
+			"name_override": str(),
+			"name_prefix":   str(),
+			"name_suffix":   str(),
+			"tags":          {Type: "object", AdditionalProperties: &Schema{Type: "string"}, GoType: "map[string]string"},
+		}
+	}
+
+	// Options common to inputs, outputs, processors and aggregators
+	options["alias"] = str()
+	options["log_level"] = str()
+	options["inherit"] = strs()
+	options["namepass"] = strs()
+	options["namepass_separator"] = str()
+	options["namedrop"] = strs()
+	options["namedrop_separator"] = str()
+	options["fieldinclude"] = strs()
+	options["fieldexclude"] = strs()
+	options["fieldpass"] = deprecated(strs(), "1.29.0", "1.40.0", "use 'fieldinclude' instead")
+	options["fielddrop"] = deprecated(strs(), "1.29.0", "1.40.0", "use 'fieldexclude' instead")
+	options["pass"] = deprecated(strs(), "0.10.4", "1.35.0", "use 'fieldinclude' instead")
+	options["drop"] = deprecated(strs(), "0.10.4", "1.35.0", "use 'fieldexclude' instead")
+	options["tagpass"] = tagFilter()
+	options["tagdrop"] = tagFilter()
+	options["taginclude"] = strs()
+	options["tagexclude"] = strs()
+	options["metricpass"] = str()
+	return options
+}
+
+// SchemaError describes an option of a configuration file violating the
+// schema of the plugin
+type SchemaError struct {
+	Source  string
+	Line    int
+	Option  string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s:%d: %s: %s", e.Source, e.Line, e.Option, e.Message)
+}
+
+// validateSchema checks the agent settings and the options of all plugins in
+// the given configuration table against their schema, reporting all unknown
+// options, options with wrong types and out-of-range values. Unknown options
+// always tolerated when loading plugins are only reported as warnings.
+func validateSchema(source string, tbl *ast.Table) error {
+	v := &schemaValidator{source: source}
+	for name, val := range tbl.Fields {
+		switch name {
+		case "agent":
+			if subTable, ok := val.(*ast.Table); ok {
+				schema := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+				structSchema(schema, reflect.ValueOf(&AgentConfig{}), make(map[reflect.Type]bool))
+				v.table(schema, "agent", subTable)
+			}
+		case "global_tags", "tags":
+			if subTable, ok := val.(*ast.Table); ok {
+				v.table(&Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}, name, subTable)
+			}
+		case "inputs", "outputs", "processors", "aggregators", "secretstores":
+			if subTable, ok := val.(*ast.Table); ok {
+				v.category(name+".", name, subTable)
+			}
+		case "pipeline":
+			tables, ok := val.([]*ast.Table)
+			if !ok {
+				continue
+			}
+			for _, pipeline := range tables {
+				for category, val := range pipeline.Fields {
+					if subTable, ok := val.(*ast.Table); ok {
+						v.category("pipeline."+category+".", category, subTable)
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(v.warnings, func(i, j int) bool {
+		return v.warnings[i].Line < v.warnings[j].Line
+	})
+	for _, w := range v.warnings {
+		log.Printf("W! %s", w.Error())
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		return v.errs[i].(*SchemaError).Line < v.errs[j].(*SchemaError).Line
+	})
+	return errors.Join(v.errs...)
+}
+
+type schemaValidator struct {
+	source   string
+	errs     []error
+	warnings []*SchemaError
+}
+
+func (v *schemaValidator) fail(line int, option, format string, args ...interface{}) {
+	v.errs = append(v.errs, &SchemaError{Source: v.source, Line: line, Option: option, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) category(prefix, category string, tbl *ast.Table) {
+	for name, val := range tbl.Fields {
+		var plugins []*ast.Table
+		switch p := val.(type) {
+		case *ast.Table:
+			plugins = []*ast.Table{p}
+		case []*ast.Table:
+			plugins = p
+		}
+
+		// Unknown plugins are reported when loading the plugins
+		schema, err := PluginSchema(category, name)
+		if err != nil {
+			continue
+		}
+		plugin, err := createPlugin(category, name)
+		if err != nil {
+			continue
+		}
+		for _, p := range plugins {
+			path := prefix + name
+			s := schema
+			if format := dataFormatCategory(category, plugin); format != "" {
+				s = withDataFormatOptions(schema, format, dataFormat(category, name, format, p))
+			}
+			v.table(s, path, p)
+
+			// Check the outputs wrapped by the plugin
+			if _, ok := plugin.(outputs.ParentOutput); ok {
+				if children, ok := p.Fields["output"].(*ast.Table); ok {
+					v.category(path+".output.", "outputs", children)
+				}
+			}
+		}
+	}
+}
+
+// dataFormat returns the data format configured in the plugin table
+func dataFormat(category, name, format string, tbl *ast.Table) string {
+	if kv, ok := tbl.Fields["data_format"].(*ast.KeyValue); ok {
+		if s, ok := kv.Value.(*ast.String); ok && s.Value != "" {
+			if s.Value == "influx" && format == "parsers" {
+				if kv, ok := tbl.Fields["influx_parser_type"].(*ast.KeyValue); ok {
+					if t, ok := kv.Value.(*ast.String); ok && t.Value == "upstream" {
+						return "influx_upstream"
+					}
+				}
+			}
+			return s.Value
+		}
+	}
+	if format == "parsers" {
+		return setDefaultParser(category, name)
+	}
+	return "influx"
+}
+
+// withDataFormatOptions returns a copy of the plugin schema also accepting the
+// options of the given parser or serializer
+func withDataFormatOptions(schema *Schema, format, dataFormat string) *Schema {
+	s := *schema
+	s.AdditionalProperties = false
+	s.Properties = make(map[string]*Schema, len(schema.Properties))
+	for k, p := range schema.Properties {
+		s.Properties[k] = p
+	}
+	s.untagged = make(map[string]string, len(schema.untagged))
+	for k, p := range schema.untagged {
+		s.untagged[k] = p
+	}
+
+	// Unknown data formats are reported when loading the plugins
+	dfSchema, err := PluginSchema(format, dataFormat)
+	if err != nil {
+		s.AdditionalProperties = true
+		return &s
+	}
+	for k, p := range dfSchema.Properties {
+		if _, found := s.Properties[k]; !found {
+			s.Properties[k] = p
+		}
+	}
+	for k, p := range dfSchema.untagged {
+		if _, found := s.untagged[k]; !found {
+			s.untagged[k] = p
+		}
+	}
+	if format == "parsers" {
+		s.Properties["influx_parser_type"] = &Schema{Type: "string", Enum: []string{"internal", "upstream"}}
+	}
+	return &s
+}
+
+func (s *Schema) property(key string) *Schema {
+	if p, found := s.Properties[key]; found {
+		return p
+	}
+	if name, found := s.untagged[toml.DefaultConfig.NormFieldName(nil, key)]; found {
+		return s.Properties[name]
+	}
+	if p, ok := s.AdditionalProperties.(*Schema); ok {
+		return p
+	}
+	return nil
+}
+
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+func (v *schemaValidator) table(schema *Schema, path string, tbl *ast.Table) {
+	for key, field := range tbl.Fields {
+		option := path + "." + key
+		s := schema.property(key)
+		if s == nil {
+			if allowed, ok := schema.AdditionalProperties.(bool); ok && !allowed {
+				if toleratedOption(key) {
+					v.warnings = append(v.warnings, &SchemaError{
+						Source:  v.source,
+						Line:    fieldLine(field, tbl.Line),
+						Option:  option,
+						Message: "option not used by the plugin",
+					})
+					continue
+				}
+				v.fail(fieldLine(field, tbl.Line), option, "unknown option")
+			}
+			continue
+		}
+		v.field(s, option, field, tbl.Line)
+	}
+}
+
+func (v *schemaValidator) field(s *Schema, option string, field interface{}, line int) {
+	types := s.types()
+	if len(types) == 0 {
+		return
+	}
+
+	switch f := field.(type) {
+	case *ast.KeyValue:
+		v.value(s, option, f.Value, f.Line)
+	case *ast.Table:
+		if !sliceContains("object", types) {
+			v.fail(f.Line, option, "expected %s but got table", strings.Join(types, " or "))
+			return
+		}
+		v.table(s, option, f)
+	case []*ast.Table:
+		if !sliceContains("array", types) || s.Items == nil || !sliceContains("object", s.Items.types()) {
+			v.fail(fieldLine(f, line), option, "expected %s but got array of tables", strings.Join(types, " or "))
+			return
+		}
+		for _, t := range f {
+			v.table(s.Items, option, t)
+		}
+	}
+}
+
+func (v *schemaValidator) value(s *Schema, option string, val ast.Value, line int) {
+	types := s.types()
+	if len(types) == 0 {
+		return
+	}
+
+	var actual string
+	switch val := val.(type) {
+	case *ast.String:
+		actual = "string"
+	case *ast.Integer:
+		actual = "integer"
+	case *ast.Float:
+		actual = "number"
+	case *ast.Boolean:
+		actual = "boolean"
+	case *ast.Datetime:
+		actual = "datetime"
+	case *ast.Array:
+		if !sliceContains("array", types) {
+			v.fail(line, option, "expected %s but got array", strings.Join(types, " or "))
+			return
+		}
+		if s.Items != nil {
+			for _, elem := range val.Value {
+				v.value(s.Items, option, elem, line)
+			}
+		}
+		return
+	case *ast.Table:
+		if !sliceContains("object", types) {
+			v.fail(line, option, "expected %s but got table", strings.Join(types, " or "))
+			return
+		}
+		v.table(s, option, val)
+		return
+	}
+
+	switch {
+	case sliceContains(actual, types):
+	case actual == "integer" && sliceContains("number", types):
+	case actual == "datetime" && s.GoType == "time.Time":
+	default:
+		v.fail(line, option, "expected %s but got %s", strings.Join(types, " or "), actual)
+		return
+	}
+
+	// Check the value using the decoding function of the option's type
+	if s.text != nil {
+		data := val.Source()
+		if str, ok := val.(*ast.String); ok {
+			data = str.Value
+		}
+		u := reflect.New(s.text).Interface().(encoding.TextUnmarshaler)
+		if err := u.UnmarshalText([]byte(data)); err != nil {
+			v.fail(line, option, "invalid value %q: %v", data, err)
+		}
+		return
+	}
+
+	if i, ok := val.(*ast.Integer); ok {
+		v.integerRange(s, option, i, line)
+	}
+	if str, ok := val.(*ast.String); ok && len(s.Enum) > 0 && !sliceContains(str.Value, s.Enum) {
+		v.fail(line, option, "invalid value %q, expected one of %q", str.Value, s.Enum)
+	}
+}
+
+func (v *schemaValidator) integerRange(s *Schema, option string, val *ast.Integer, line int) {
+	if !sliceContains("integer", s.types()) {
+		return
+	}
+	i, err := strconv.ParseInt(val.Value, 10, 64)
+	if err != nil {
+		v.fail(line, option, "value %s out of range [%d, %d]", val.Value, int64(math.MinInt64), int64(math.MaxInt64))
+		return
+	}
+
+	minimum, hasMin := s.Minimum.(int64)
+	maximum, hasMax := s.Maximum.(int64)
+	if (hasMin && i < minimum) || (hasMax && i > maximum) {
+		bounds := "[" + boundString(minimum, hasMin, "-inf") + ", " + boundString(maximum, hasMax, "inf") + "]"
+		v.fail(line, option, "value %d out of range %s", i, bounds)
+	}
+}
+
+func boundString(bound int64, ok bool, fallback string) string {
+	if !ok {
+		return fallback
+	}
+	return strconv.FormatInt(bound, 10)
+}
+
+func fieldLine(field interface{}, fallback int) int {
+	switch f := field.(type) {
+	case *ast.KeyValue:
+		return f.Line
+	case *ast.Table:
+		return f.Line
+	case []*ast.Table:
+		if len(f) > 0 {
+			return f[0].Line
+		}
+	}
+	return fallback
+}
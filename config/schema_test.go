@@ -0,0 +1,157 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func TestPluginSchema(t *testing.T) {
+	schema, err := config.PluginSchema("inputs", "schemamockup")
+	require.NoError(t, err)
+	require.Equal(t, config.SchemaDialect, schema.Schema)
+	require.Equal(t, "inputs.schemamockup", schema.Title)
+	require.Equal(t, false, schema.AdditionalProperties)
+
+	// Plugin options with their defaults
+	port := schema.Properties["port"]
+	require.Equal(t, "integer", port.Type)
+	require.Equal(t, "uint16", port.GoType)
+	require.Equal(t, int64(0), port.Minimum)
+	require.Equal(t, int64(65535), port.Maximum)
+	require.EqualValues(t, 8080, port.Default)
+
+	timeout := schema.Properties["timeout"]
+	require.Equal(t, "config.Duration", timeout.GoType)
+	require.Equal(t, "5s", timeout.Default)
+
+	require.Equal(t, "array", schema.Properties["servers"].Type)
+	require.Equal(t, "string", schema.Properties["servers"].Items.Type)
+	require.Equal(t, "object", schema.Properties["headers"].Type)
+	require.Equal(t, "object", schema.Properties["endpoint"].Items.Type)
+	require.Contains(t, schema.Properties["endpoint"].Items.Properties, "name")
+	require.Contains(t, schema.Properties, "command")
+	require.NotContains(t, schema.Properties, "log")
+
+	// Secrets never expose their default
+	require.Equal(t, "string", schema.Properties["password"].Type)
+	require.Nil(t, schema.Properties["password"].Default)
+
+	// Deprecated options
+	mode := schema.Properties["mode"]
+	require.True(t, mode.Deprecated)
+	require.Equal(t, &config.SchemaDeprecation{Since: "1.30.0", RemovalIn: "1.40.0", Notice: "use 'modes' instead"}, mode.Deprecation)
+	require.True(t, schema.Properties["fieldpass"].Deprecated)
+
+	// General input options
+	require.Contains(t, schema.Properties, "interval")
+	require.Contains(t, schema.Properties, "tagpass")
+	require.NotContains(t, schema.Properties, "flush_interval")
+
+	_, err = config.PluginSchema("inputs", "non_existing")
+	require.ErrorContains(t, err, "unknown plugin inputs.non_existing")
+	_, err = config.PluginSchema("foo", "bar")
+	require.ErrorContains(t, err, `invalid plugin category "foo"`)
+}
+
+func TestPluginSchemaDataFormat(t *testing.T) {
+	schema, err := config.PluginSchema("inputs", "parser")
+	require.NoError(t, err)
+	require.Equal(t, true, schema.AdditionalProperties)
+	require.Contains(t, schema.Properties["data_format"].Enum, "json")
+
+	schema, err = config.PluginSchema("parsers", "json")
+	require.NoError(t, err)
+	require.Contains(t, schema.Properties, "json_query")
+}
+
+func TestConfig_ValidateSchema(t *testing.T) {
+	c := config.NewConfig()
+	c.ValidateSchema = true
+	err := c.LoadConfig("./testdata/schema_invalid.toml")
+	require.Error(t, err)
+
+	// All violations are reported at once
+	joined, ok := errors.Unwrap(err).(interface{ Unwrap() []error })
+	require.True(t, ok)
+	var violations []*config.SchemaError
+	for _, e := range joined.Unwrap() {
+		var violation *config.SchemaError
+		if errors.As(e, &violation) {
+			violations = append(violations, violation)
+		}
+	}
+
+	expected := []*config.SchemaError{
+		{Line: 2, Option: "agent.not_an_agent_option", Message: "unknown option"},
+		{Line: 5, Option: "inputs.schemamockup.port", Message: "value 70000 out of range [0, 65535]"},
+		{Line: 6, Option: "inputs.schemamockup.servers", Message: "expected array but got string"},
+		{Line: 7, Option: "inputs.schemamockup.timeout", Message: `invalid value "5x": time: unknown unit "x" in duration "5x"`},
+		{Line: 8, Option: "inputs.schemamockup.not_an_option", Message: "unknown option"},
+		{Line: 9, Option: "inputs.schemamockup.interval", Message: "expected string or integer or number but got boolean"},
+		{Line: 11, Option: "inputs.schemamockup.endpoint.nmae", Message: "unknown option"},
+		{Line: 15, Option: "inputs.parser.json_query", Message: "expected string but got integer"},
+		{Line: 16, Option: "inputs.parser.csv_header_row_count", Message: "unknown option"},
+		{Line: 20, Option: "outputs.http.flush_interval", Message: "expected string or integer or number but got array"},
+	}
+	for _, e := range expected {
+		e.Source = "./testdata/schema_invalid.toml"
+	}
+	require.Equal(t, expected, violations)
+	require.ErrorContains(t, err, "./testdata/schema_invalid.toml:5: inputs.schemamockup.port: value 70000 out of range [0, 65535]")
+}
+
+func TestConfig_ValidateSchemaValid(t *testing.T) {
+	c := config.NewConfig()
+	c.ValidateSchema = true
+	require.NoError(t, c.LoadConfigData([]byte(`
+[agent]
+  interval = "10s"
+[[inputs.schemamockup]]
+  servers = ["localhost"]
+  port = 1234
+  timeout = 10
+  COMMAND = "ls"
+  interval = "1m"
+  flush_interval = "10s"
+  [inputs.schemamockup.headers]
+    key = "value"
+  [[inputs.schemamockup.endpoint]]
+    name = "foo"
+[[inputs.parser]]
+  data_format = "json"
+  json_query = "data"
+`), config.EmptySourcePath))
+	require.Len(t, c.Inputs, 2)
+}
+
+type MockupSchemaPlugin struct {
+	Servers  []string          `toml:"servers"`
+	Port     uint16            `toml:"port"`
+	Timeout  config.Duration   `toml:"timeout"`
+	Password config.Secret     `toml:"password"`
+	Mode     string            `toml:"mode" deprecated:"1.30.0;1.40.0;use 'modes' instead"`
+	Modes    []string          `toml:"modes"`
+	Headers  map[string]string `toml:"headers"`
+	Endpoint []struct {
+		Name string `toml:"name"`
+	} `toml:"endpoint"`
+	Command string
+	Log     telegraf.Logger `toml:"-"`
+}
+
+func (*MockupSchemaPlugin) SampleConfig() string                { return "Mockup test schema plugin" }
+func (*MockupSchemaPlugin) Gather(_ telegraf.Accumulator) error { return nil }
+
+// Register the mockup plugin on loading
+func init() {
+	inputs.Add("schemamockup", func() telegraf.Input {
+		return &MockupSchemaPlugin{Port: 8080, Timeout: config.Duration(5 * time.Second)}
+	})
+}
@@ -0,0 +1,401 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// expandedConfig is the content of a configuration file with all templates
+// expanded
+type expandedConfig struct {
+	source  string
+	content map[string]interface{}
+}
+
+// addTemplates adds the templates of the given [templates] table. Templates
+// can be used by all plugins loaded after the template definition.
+func (c *Config) addTemplates(val interface{}) error {
+	tbl, ok := val.(*ast.Table)
+	if !ok {
+		return errors.New("invalid configuration, templates must be defined as [templates.<name>] tables")
+	}
+
+	if c.templates == nil {
+		c.templates = make(map[string]*ast.Table, len(tbl.Fields))
+	}
+	for name, v := range tbl.Fields {
+		t, ok := v.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("line %d: invalid template %q, templates must be tables", tbl.Line, name)
+		}
+		if _, found := c.templates[name]; found {
+			return fmt.Errorf("line %d: duplicate template %q", t.Line, name)
+		}
+		if _, found := t.Fields["inherit"]; found {
+			return fmt.Errorf("line %d: template %q cannot inherit other templates", t.Line, name)
+		}
+		c.templates[name] = t
+	}
+	return nil
+}
+
+// expandTemplates merges the templates inherited by the plugins of the given
+// configuration into the plugin tables.
+func (c *Config) expandTemplates(tbl *ast.Table) error {
+	for name, val := range tbl.Fields {
+		switch name {
+		case "inputs", "outputs", "processors", "aggregators", "plugins", "secretstores":
+			// Invalid categories are reported when loading the plugins
+			if subTable, ok := val.(*ast.Table); ok {
+				if err := c.expandCategory(name, subTable); err != nil {
+					return err
+				}
+			}
+		case "pipeline":
+			tables, ok := val.([]*ast.Table)
+			if !ok {
+				continue
+			}
+			for _, pipeline := range tables {
+				for category, v := range pipeline.Fields {
+					if subTable, ok := v.(*ast.Table); ok {
+						if err := c.expandCategory(category, subTable); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) expandCategory(category string, tbl *ast.Table) error {
+	for name, val := range tbl.Fields {
+		var plugins []*ast.Table
+		switch v := val.(type) {
+		case *ast.Table:
+			plugins = []*ast.Table{v}
+		case []*ast.Table:
+			plugins = v
+		}
+		for _, plugin := range plugins {
+			if err := c.expandPlugin(plugin); err != nil {
+				return fmt.Errorf("plugin %s.%s: line %d: %w", category, name, plugin.Line, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expandPlugin merges the templates listed in the "inherit" setting into the
+// plugin table. Settings of the plugin take precedence over the ones of the
+// templates and later templates in the list take precedence over earlier ones.
+func (c *Config) expandPlugin(tbl *ast.Table) error {
+	val, found := tbl.Fields["inherit"]
+	if !found {
+		return nil
+	}
+	delete(tbl.Fields, "inherit")
+
+	names, err := inheritedTemplates(val)
+	if err != nil {
+		return err
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		template, found := c.templates[names[i]]
+		if !found {
+			return fmt.Errorf("unknown template %q", names[i])
+		}
+		mergeTable(tbl, template)
+	}
+	return nil
+}
+
+// inheritedTemplates returns the template names of the "inherit" setting
+func inheritedTemplates(val interface{}) ([]string, error) {
+	kv, ok := val.(*ast.KeyValue)
+	if !ok {
+		return nil, errors.New(`invalid "inherit" setting, expected a list of template names`)
+	}
+	array, ok := kv.Value.(*ast.Array)
+	if !ok {
+		return nil, errors.New(`invalid "inherit" setting, expected a list of template names`)
+	}
+
+	names := make([]string, 0, len(array.Value))
+	for _, elem := range array.Value {
+		s, ok := elem.(*ast.String)
+		if !ok {
+			return nil, errors.New(`invalid "inherit" setting, expected a list of template names`)
+		}
+		names = append(names, s.Value)
+	}
+	return names, nil
+}
+
+// mergeTable adds the fields of the source table missing in the destination
+// table, merging subtables existing in both tables.
+func mergeTable(dst, src *ast.Table) {
+	for key, val := range src.Fields {
+		existing, found := dst.Fields[key]
+		if !found {
+			dst.Fields[key] = copyValue(val)
+			continue
+		}
+
+		dstTable, dstOk := existing.(*ast.Table)
+		srcTable, srcOk := val.(*ast.Table)
+		if dstOk && srcOk {
+			mergeTable(dstTable, srcTable)
+		}
+	}
+}
+
+// copyValue copies the tables of a template to not modify the template when
+// modifying the plugin tables, values are immutable and can be shared.
+func copyValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case *ast.Table:
+		return copyTable(v)
+	case []*ast.Table:
+		tables := make([]*ast.Table, 0, len(v))
+		for _, t := range v {
+			tables = append(tables, copyTable(t))
+		}
+		return tables
+	}
+	return val
+}
+
+func copyTable(tbl *ast.Table) *ast.Table {
+	cp := *tbl
+	cp.Fields = make(map[string]interface{}, len(tbl.Fields))
+	for k, v := range tbl.Fields {
+		cp.Fields[k] = copyValue(v)
+	}
+	return &cp
+}
+
+// keepExpanded records the given configuration table with expanded templates
+func (c *Config) keepExpanded(source string, tbl *ast.Table) error {
+	var content map[string]interface{}
+	if err := toml.UnmarshalTable(tbl, &content); err != nil {
+		return err
+	}
+	delete(content, "templates")
+	maskExpandedSecrets(content)
+
+	c.expanded = append(c.expanded, expandedConfig{source: source, content: content})
+	return nil
+}
+
+// ExpandedConfig returns the loaded configuration files with all templates
+// expanded. The configuration must have been loaded with KeepExpandedConfig
+// enabled. Environment variables are replaced by their values, settings
+// holding secrets are masked.
+func (c *Config) ExpandedConfig() ([]byte, error) {
+	var buf bytes.Buffer
+	for i, cfg := range c.expanded {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		if cfg.source != EmptySourcePath {
+			fmt.Fprintf(&buf, "# Expanded configuration of %s\n", cfg.source)
+		}
+		out, err := toml.Marshal(cfg.content)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling configuration of %q failed: %w", cfg.source, err)
+		}
+		buf.Write(out)
+	}
+	return buf.Bytes(), nil
+}
+
+// maskExpandedSecrets masks all settings of the given configuration content
+// that are stored as secret by the agent or the plugins.
+func maskExpandedSecrets(content map[string]interface{}) {
+	for name, val := range content {
+		switch name {
+		case "agent":
+			maskSettings(val, reflect.TypeOf(AgentConfig{}))
+		case "global_tags", "tags", "routing":
+		case "pipeline":
+			for _, pipeline := range expandedTables(val) {
+				maskExpandedPlugins(pipeline)
+			}
+		case "inputs", "plugins", "outputs", "processors", "aggregators", "secretstores":
+			maskExpandedPlugins(map[string]interface{}{name: val})
+		default:
+			// Legacy configurations define inputs at the top level
+			maskExpandedPlugin("inputs", name, val)
+		}
+	}
+}
+
+// maskExpandedPlugins masks the secrets of the plugins in the given category
+// tables.
+func maskExpandedPlugins(content map[string]interface{}) {
+	for category, val := range content {
+		plugins, ok := val.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name, pluginVal := range plugins {
+			maskExpandedPlugin(category, name, pluginVal)
+		}
+	}
+}
+
+// maskExpandedPlugin masks the secrets in the given plugin tables including
+// the settings of the parser or serializer used by the plugin.
+func maskExpandedPlugin(category, name string, val interface{}) {
+	var plugin interface{}
+	switch category {
+	case "inputs", "plugins":
+		if creator, ok := inputs.Inputs[name]; ok {
+			plugin = creator()
+		}
+	case "outputs":
+		if creator, ok := outputs.Outputs[name]; ok {
+			plugin = creator()
+		}
+	case "processors":
+		if creator, ok := processors.Processors[name]; ok {
+			plugin = creator()
+		}
+	case "aggregators":
+		if creator, ok := aggregators.Aggregators[name]; ok {
+			plugin = creator()
+		}
+	case "secretstores":
+		if creator, ok := secretstores.SecretStores[name]; ok {
+			plugin = creator("")
+		}
+	}
+	if plugin == nil {
+		return
+	}
+
+	for _, tbl := range expandedTables(val) {
+		maskSettings(tbl, reflect.TypeOf(plugin))
+
+		dataFormat, _ := tbl["data_format"].(string)
+		if dataFormat == "" {
+			continue
+		}
+		switch category {
+		case "inputs", "plugins", "processors":
+			if creator, ok := parsers.Parsers[dataFormat]; ok {
+				maskSettings(tbl, reflect.TypeOf(creator("")))
+			}
+		case "outputs":
+			if creator, ok := serializers.Serializers[dataFormat]; ok {
+				maskSettings(tbl, reflect.TypeOf(creator()))
+			}
+		}
+	}
+}
+
+// maskSettings replaces the values of all settings in the given table that
+// are unmarshalled into a secret of the given struct type.
+func maskSettings(val interface{}, rt reflect.Type) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, tbl := range expandedTables(val) {
+		for key, setting := range tbl {
+			ft, found := settingType(rt, key)
+			if !found {
+				continue
+			}
+			if ft == secretType {
+				if s, ok := setting.(string); !ok || s != "" {
+					tbl[key] = maskedSecret
+				}
+				continue
+			}
+			for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array || ft.Kind() == reflect.Map {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				maskSettings(setting, ft)
+			}
+		}
+	}
+}
+
+// settingType returns the type of the struct field for the given TOML key
+// including the fields of embedded structs.
+func settingType(rt reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < rt.NumField(); i++ {
+		ft := rt.Field(i)
+		name, _, _ := strings.Cut(ft.Tag.Get("toml"), ",")
+		name = strings.TrimSpace(name)
+		if name == "-" {
+			continue
+		}
+
+		if ft.Anonymous && name == "" {
+			embedded := ft.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if t, found := settingType(embedded, key); found {
+					return t, true
+				}
+			}
+			continue
+		}
+		if ft.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = toml.DefaultConfig.FieldToKey(rt, ft.Name)
+		}
+		if name == key {
+			return ft.Type, true
+		}
+	}
+	return nil, false
+}
+
+// expandedTables returns the given value as list of tables
+func expandedTables(val interface{}) []map[string]interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		tables := make([]map[string]interface{}, 0, len(v))
+		for _, elem := range v {
+			if tbl, ok := elem.(map[string]interface{}); ok {
+				tables = append(tables, tbl)
+			}
+		}
+		return tables
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
@@ -83,6 +84,12 @@ type Buffer interface {
 	Close() error
 }
 
+// limitedBuffer is a buffer supporting limits for the size of the buffer on
+// disk and the age of the buffered metrics.
+type limitedBuffer interface {
+	SetLimits(maxBytes int64, maxAge time.Duration)
+}
+
 // BufferStats holds common metrics used for buffer implementations.
 // Implementations of Buffer should embed this struct in them.
 type BufferStats struct {
@@ -105,6 +112,8 @@ func NewBuffer(name, id, alias string, capacity int, strategy, path string) (Buf
 		return NewMemoryBuffer(capacity, bs)
 	case "disk":
 		return NewDiskBuffer(name, id, path, bs)
+	case "overflow":
+		return NewOverflowBuffer(name, id, path, capacity, bs)
 	}
 	return nil, fmt.Errorf("invalid buffer strategy %q", strategy)
 }
@@ -1,13 +1,16 @@
 package models
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"slices"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/tidwall/wal"
 
@@ -15,6 +18,15 @@ import (
 	"github.com/influxdata/telegraf/metric"
 )
 
+// Minimum number of metrics removed from the WAL file since the last
+// compaction before the WAL file is compacted again.
+const diskBufferCompactionThreshold = 1000
+
+// Name of the file next to the WAL segments recording the index of the
+// placeholder entry kept in a drained WAL file. The name is too short to be
+// mistaken for a segment by the WAL library.
+const diskBufferDrainedMarker = "drained"
+
 type DiskBuffer struct {
 	BufferStats
 	sync.Mutex
@@ -22,6 +34,20 @@ type DiskBuffer struct {
 	file *wal.Log
 	path string
 
+	// Limits for the WAL file, the oldest metrics are dropped if exceeded
+	maxBytes     int64
+	maxAge       time.Duration
+	lastAgeCheck time.Time
+
+	size    int64  // Approximate size of the WAL file in bytes
+	removed uint64 // Number of metrics removed since the last compaction
+
+	// Sizes of the entries written since opening the file, starting at index
+	// sizesFirst. The sizes of older entries are read from the file when
+	// truncating them.
+	sizes      []int64
+	sizesFirst uint64
+
 	batchFirst uint64 // Index of the first metric in the batch
 	batchSize  uint64 // Number of metrics currently in the batch
 
@@ -58,12 +84,30 @@ func NewDiskBuffer(name, id, path string, stats BufferStats) (*DiskBuffer, error
 		file:        walFile,
 		path:        filePath,
 	}
+	// Restore the empty state of a drained WAL file to not resend the
+	// placeholder entry
+	if index := readDrainedMarker(filePath); index != 0 && index == buf.readIndex() && buf.entries() == 1 {
+		buf.isEmpty = true
+	}
 	if buf.length() > 0 {
 		buf.originalEnd = buf.writeIndex()
 	}
+	buf.updateSize()
 	return buf, nil
 }
 
+// SetLimits sets the maximum size of the WAL file in bytes and the maximum age
+// of the metrics in the buffer. Zero values disable the respective limit.
+func (b *DiskBuffer) SetLimits(maxBytes int64, maxAge time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.maxBytes = maxBytes
+	b.maxAge = maxAge
+	b.enforceLimits()
+	b.BufferSize.Set(int64(b.length()))
+}
+
 func (b *DiskBuffer) Len() int {
 	b.Lock()
 	defer b.Unlock()
@@ -115,21 +159,42 @@ func (b *DiskBuffer) Add(metrics ...telegraf.Metric) int {
 		// as soon as a new metric is added, if this was empty, try to flush the "empty" metric out
 		b.handleEmptyFile()
 	}
+	dropped += b.enforceLimits()
 	b.BufferSize.Set(int64(b.length()))
 	return dropped
 }
 
 func (b *DiskBuffer) addSingleMetric(m telegraf.Metric) bool {
+	if b.writeMetric(m) {
+		b.metricAdded()
+		return true
+	}
+	return false
+}
+
+// writeMetric appends the metric to the WAL file without updating the stats.
+func (b *DiskBuffer) writeMetric(m telegraf.Metric) bool {
 	data, err := metric.ToBytes(m)
 	if err != nil {
 		panic(err)
 	}
-	err = b.file.Write(b.writeIndex(), data)
-	if err == nil {
-		b.metricAdded()
-		return true
+	index := b.writeIndex()
+	if err := b.file.Write(index, data); err != nil {
+		return false
 	}
-	return false
+	if len(b.sizes) == 0 {
+		b.sizesFirst = index
+	}
+	size := walEntrySize(data)
+	b.sizes = append(b.sizes, size)
+	b.size += size
+	return true
+}
+
+// walEntrySize returns the size of the given data in the WAL file including
+// the length prefix of the entry.
+func walEntrySize(data []byte) int64 {
+	return int64(len(data) + len(binary.AppendUvarint(nil, uint64(len(data)))))
 }
 
 func (b *DiskBuffer) BeginTransaction(batchSize int) *Transaction {
@@ -222,51 +287,195 @@ func (b *DiskBuffer) EndTransaction(tx *Transaction) {
 	sort.Ints(b.mask)
 
 	// Remove the metrics that are marked for removal from the front of the
-	// WAL file. All other metrics must be kept.
-	if len(b.mask) == 0 || b.mask[0] != 0 {
-		// Mask is empty or the first index is not the front of the file, so
-		// exit early as there is nothing to remove
-		return
-	}
-
-	// Determine up to which index we can remove the entries from the WAL file
-	var removeIdx int
+	// WAL file. All other metrics must be kept. Offsets start at one for the
+	// first metric in the file.
+	var count int
 	for i, offset := range b.mask {
-		if offset != i {
+		if offset != i+1 {
 			break
 		}
-		removeIdx = offset
+		count = offset
+	}
+	b.truncateFront(count)
+
+	// Compact the WAL file after draining a large number of metrics
+	if b.isEmpty && b.removed >= diskBufferCompactionThreshold {
+		b.compact()
+	}
+
+	b.resetBatch()
+	b.enforceLimits()
+	b.BufferSize.Set(int64(b.length()))
+}
+
+// truncateFront removes the given number of metrics from the front of the WAL
+// file and updates the mask accordingly.
+func (b *DiskBuffer) truncateFront(count int) {
+	if count == 0 {
+		return
 	}
 
-	// Remove the metrics in front from the WAL file
-	b.isEmpty = b.entries()-removeIdx-1 <= 0
+	b.isEmpty = b.entries()-count <= 0
 	if b.isEmpty {
 		// WAL files cannot be fully empty but need to contain at least one
 		// item to not throw an error
-		if err := b.file.TruncateFront(b.writeIndex()); err != nil {
-			log.Printf("E! batch length: %d, first: %d, size: %d", len(tx.Batch), b.batchFirst, b.batchSize)
+		b.releaseSizes(b.writeIndex() - 1)
+		if err := b.file.TruncateFront(b.writeIndex() - 1); err != nil {
+			log.Printf("E! count: %d, first: %d, size: %d", count, b.batchFirst, b.batchSize)
 			panic(err)
 		}
+		b.mask = b.mask[:0]
+		b.writeDrainedMarker()
 	} else {
-		if err := b.file.TruncateFront(b.batchFirst + uint64(removeIdx+1)); err != nil {
-			log.Printf("E! batch length: %d, first: %d, size: %d", len(tx.Batch), b.batchFirst, b.batchSize)
+		b.releaseSizes(b.readIndex() + uint64(count))
+		if err := b.file.TruncateFront(b.readIndex() + uint64(count)); err != nil {
+			log.Printf("E! count: %d, first: %d, size: %d", count, b.batchFirst, b.batchSize)
 			panic(err)
 		}
-	}
 
-	// Truncate the mask and update the relative offsets
-	b.mask = b.mask[:removeIdx]
-	for i := range b.mask {
-		b.mask[i] -= removeIdx
+		// Truncate the mask and update the relative offsets
+		mask := make([]int, 0, len(b.mask))
+		for _, offset := range b.mask {
+			if offset > count {
+				mask = append(mask, offset-count)
+			}
+		}
+		b.mask = mask
 	}
+	b.removed += uint64(count)
 
 	// check if the original end index is still valid, clear if not
 	if b.originalEnd < b.readIndex() {
 		b.originalEnd = 0
 	}
+}
 
-	b.resetBatch()
-	b.BufferSize.Set(int64(b.length()))
+// releaseSizes subtracts the size of the entries before the given index from
+// the size of the WAL file before truncating those entries.
+func (b *DiskBuffer) releaseSizes(index uint64) {
+	for i := b.readIndex(); i < index; i++ {
+		if i >= b.sizesFirst && len(b.sizes) > 0 {
+			b.size -= b.sizes[i-b.sizesFirst]
+			continue
+		}
+		data, err := b.file.Read(i)
+		if err != nil {
+			panic(err) // can only occur with a corrupt wal file
+		}
+		b.size -= walEntrySize(data)
+	}
+	if index > b.sizesFirst && len(b.sizes) > 0 {
+		b.sizes = b.sizes[min(index-b.sizesFirst, uint64(len(b.sizes))):]
+		b.sizesFirst = index
+	}
+}
+
+// enforceLimits drops the oldest metrics if the WAL file exceeds the maximum
+// size or the oldest metrics exceed the maximum age and returns the number of
+// dropped metrics. Metrics are never dropped during a running transaction.
+func (b *DiskBuffer) enforceLimits() int {
+	if b.batchSize > 0 || b.length() == 0 {
+		return 0
+	}
+
+	// Only check the age of the metrics periodically as this requires to
+	// decode the oldest metrics
+	checkAge := b.maxAge > 0 && time.Since(b.lastAgeCheck) >= time.Second
+	overSize := b.maxBytes > 0 && b.size > b.maxBytes
+	if !checkAge && !overSize {
+		return 0
+	}
+	if checkAge {
+		b.lastAgeCheck = time.Now()
+	}
+	cutoff := time.Now().Add(-b.maxAge)
+
+	var count, dropped int
+	size := b.size
+	end := b.writeIndex()
+	for index, offset := b.readIndex(), 1; index < end; index, offset = index+1, offset+1 {
+		overSize = b.maxBytes > 0 && size > b.maxBytes
+		if !overSize && !checkAge {
+			break
+		}
+
+		data, err := b.file.Read(index)
+		if err != nil {
+			panic(err)
+		}
+
+		// Undecodable metrics, e.g. tracking metrics of a previous instance,
+		// would be skipped anyway so drop them without accounting
+		m, err := metric.FromBytes(data)
+		if !overSize && err == nil && !m.Time().Before(cutoff) {
+			break
+		}
+		size -= walEntrySize(data)
+		count = offset
+
+		// Metrics already written in a previous transaction are masked
+		if err != nil || slices.Contains(b.mask, offset) {
+			continue
+		}
+		b.metricDropped(m)
+		dropped++
+	}
+	if count == 0 {
+		return 0
+	}
+
+	b.truncateFront(count)
+	if dropped > 0 {
+		log.Printf("W! Dropped %d metrics from disk buffer %s exceeding the limits", dropped, b.path)
+	}
+	return dropped
+}
+
+// updateSize determines the size of the WAL file on disk. This is only done
+// when opening the file, the size is tracked when writing and truncating
+// entries afterwards.
+func (b *DiskBuffer) updateSize() {
+	entries, err := os.ReadDir(b.path)
+	if err != nil {
+		log.Printf("E! Determining size of disk buffer %s failed: %v", b.path, err)
+		return
+	}
+
+	var size int64
+	for _, entry := range entries {
+		if entry.Name() == diskBufferDrainedMarker {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		size += info.Size()
+	}
+	b.size = size
+}
+
+// compact recreates the empty WAL file to remove all remaining segments and to
+// reset the indices. This must only be called if the buffer is empty.
+func (b *DiskBuffer) compact() {
+	if err := b.file.Close(); err != nil {
+		log.Printf("E! Closing disk buffer %s for compaction failed: %v", b.path, err)
+	}
+	if err := os.RemoveAll(b.path); err != nil {
+		log.Printf("E! Removing disk buffer %s for compaction failed: %v", b.path, err)
+	}
+	walFile, err := wal.Open(b.path, nil)
+	if err != nil {
+		panic(fmt.Errorf("failed to reopen wal file: %w", err))
+	}
+	b.file = walFile
+	b.isEmpty = false
+	b.mask = b.mask[:0]
+	b.originalEnd = 0
+	b.removed = 0
+	b.sizes = nil
+	b.sizesFirst = 0
+	b.updateSize()
 }
 
 func (b *DiskBuffer) Stats() BufferStats {
@@ -290,9 +499,91 @@ func (b *DiskBuffer) handleEmptyFile() {
 	if !b.isEmpty {
 		return
 	}
+	b.releaseSizes(b.readIndex() + 1)
 	if err := b.file.TruncateFront(b.readIndex() + 1); err != nil {
 		log.Printf("E! readIndex: %d, buffer len: %d", b.readIndex(), b.length())
 		panic(err)
 	}
 	b.isEmpty = false
+	b.removeDrainedMarker()
+}
+
+// writeDrainedMarker persists the index of the placeholder entry of the
+// drained WAL file so it is skipped after a restart or when replaying.
+func (b *DiskBuffer) writeDrainedMarker() {
+	index := make([]byte, 8)
+	binary.BigEndian.PutUint64(index, b.readIndex())
+	if err := os.WriteFile(filepath.Join(b.path, diskBufferDrainedMarker), index, 0640); err != nil {
+		log.Printf("E! Marking disk buffer %s as drained failed: %v", b.path, err)
+	}
+}
+
+func (b *DiskBuffer) removeDrainedMarker() {
+	err := os.Remove(filepath.Join(b.path, diskBufferDrainedMarker))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("E! Removing drained marker of disk buffer %s failed: %v", b.path, err)
+	}
+}
+
+// readDrainedMarker returns the index of the placeholder entry of a drained
+// WAL file at the given path or zero if the file is not drained.
+func readDrainedMarker(path string) uint64 {
+	index, err := os.ReadFile(filepath.Join(path, diskBufferDrainedMarker))
+	if err != nil || len(index) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(index)
+}
+
+// ReadDiskBuffer calls the given function for all metrics stored in the WAL
+// file of a disk buffer at the given path, from oldest to newest, without
+// modifying the file. Tracking metrics cannot be restored and are skipped as
+// is the placeholder entry of a drained file. As the position of partially
+// written batches is not persisted, the file might contain metrics already
+// written by the output.
+func ReadDiskBuffer(path string, fn func(telegraf.Metric) error) error {
+	registerGob()
+
+	// Opening the WAL file creates the directory if it does not exist
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	walFile, err := wal.Open(path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open wal file: %w", err)
+	}
+	defer walFile.Close()
+
+	first, err := walFile.FirstIndex()
+	if err != nil {
+		return err
+	}
+	last, err := walFile.LastIndex()
+	if err != nil {
+		return err
+	}
+	if first == 0 {
+		return nil
+	}
+	if readDrainedMarker(path) == first {
+		first++
+	}
+
+	for index := first; index <= last; index++ {
+		data, err := walFile.Read(index)
+		if err != nil {
+			return fmt.Errorf("reading entry %d failed: %w", index, err)
+		}
+		m, err := metric.FromBytes(data)
+		if err != nil {
+			if errors.Is(err, metric.ErrSkipTracking) {
+				continue
+			}
+			return fmt.Errorf("decoding entry %d failed: %w", index, err)
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -93,3 +93,179 @@ func TestDiskBufferTrackingDroppedFromOldWal(t *testing.T) {
 	}
 	testutil.RequireMetricsEqual(t, expected, tx.Batch)
 }
+
+func TestDiskBufferTruncatesWrittenMetrics(t *testing.T) {
+	buf, err := NewDiskBuffer("test", "123", t.TempDir(), NewBufferStats("test", "", 0))
+	require.NoError(t, err)
+	defer buf.Close()
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	buf.Add(m, m, m)
+
+	tx := buf.BeginTransaction(2)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, 1, buf.Len())
+	require.Equal(t, 1, buf.entries())
+	require.Empty(t, buf.mask)
+
+	tx = buf.BeginTransaction(2)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, 0, buf.Len())
+	require.True(t, buf.isEmpty)
+
+	// The buffer must be usable after being drained
+	buf.Add(m)
+	require.Equal(t, 1, buf.Len())
+	tx = buf.BeginTransaction(2)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, tx.Batch)
+}
+
+func TestDiskBufferDrainedAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	buf, err := NewDiskBuffer("test", "123", dir, NewBufferStats("test", "", 0))
+	require.NoError(t, err)
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	buf.Add(m, m)
+	tx := buf.BeginTransaction(2)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.True(t, buf.isEmpty)
+	require.NoError(t, buf.Close())
+
+	// The placeholder entry of the drained file must neither be replayed nor
+	// be sent again after a restart
+	var count int
+	require.NoError(t, ReadDiskBuffer(filepath.Join(dir, "123"), func(telegraf.Metric) error {
+		count++
+		return nil
+	}))
+	require.Zero(t, count)
+
+	buf, err = NewDiskBuffer("test", "123", dir, NewBufferStats("test", "", 0))
+	require.NoError(t, err)
+	defer buf.Close()
+	require.Equal(t, 0, buf.Len())
+
+	buf.Add(m)
+	require.Equal(t, 1, buf.Len())
+	tx = buf.BeginTransaction(2)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, tx.Batch)
+}
+
+func TestDiskBufferCompaction(t *testing.T) {
+	buf, err := NewDiskBuffer("test", "123", t.TempDir(), NewBufferStats("test", "", 0))
+	require.NoError(t, err)
+	defer buf.Close()
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	for range diskBufferCompactionThreshold {
+		buf.Add(m)
+	}
+	tx := buf.BeginTransaction(diskBufferCompactionThreshold)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+
+	// The WAL file must be reset after draining a large number of metrics
+	require.Equal(t, 0, buf.Len())
+	require.Equal(t, uint64(0), buf.readIndex())
+	require.Equal(t, uint64(0), buf.removed)
+
+	buf.Add(m)
+	require.Equal(t, 1, buf.Len())
+	tx = buf.BeginTransaction(2)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, tx.Batch)
+}
+
+func TestDiskBufferSizeTracking(t *testing.T) {
+	dir := t.TempDir()
+	buf, err := NewDiskBuffer("test", "123", dir, NewBufferStats("test", "", 0))
+	require.NoError(t, err)
+
+	// The tracked size must match the size on disk without scanning the
+	// directory on each modification
+	requireSize := func() {
+		t.Helper()
+		tracked := buf.size
+		buf.updateSize()
+		require.Equal(t, buf.size, tracked)
+	}
+
+	for i := range 5 {
+		buf.Add(metric.New("cpu", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0)))
+	}
+	requireSize()
+	require.NoError(t, buf.Close())
+
+	// Entries written before opening are read from the file when removed
+	buf, err = NewDiskBuffer("test", "123", dir, NewBufferStats("test", "", 0))
+	require.NoError(t, err)
+	defer buf.Close()
+	buf.Add(metric.New("mem", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(10, 0)))
+	requireSize()
+
+	tx := buf.BeginTransaction(3)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	requireSize()
+
+	tx = buf.BeginTransaction(10)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.True(t, buf.isEmpty)
+	requireSize()
+
+	buf.Add(metric.New("mem", map[string]string{}, map[string]interface{}{"value": 43}, time.Unix(11, 0)))
+	requireSize()
+}
+
+func TestDiskBufferMaxBytes(t *testing.T) {
+	buf, err := NewDiskBuffer("test", "123", t.TempDir(), NewBufferStats("test", "", 0))
+	require.NoError(t, err)
+	buf.Stats().MetricsDropped.Set(0)
+	defer buf.Close()
+
+	metrics := make([]telegraf.Metric, 0, 10)
+	for i := range 10 {
+		metrics = append(metrics, metric.New("cpu", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0)))
+	}
+	buf.Add(metrics...)
+	require.Equal(t, 10, buf.Len())
+
+	// Limit the size to roughly half of the metrics
+	limit := buf.size / 2
+	buf.SetLimits(limit, 0)
+	require.LessOrEqual(t, buf.size, limit)
+	require.Less(t, buf.Len(), 10)
+	require.Positive(t, buf.Len())
+	dropped := 10 - buf.Len()
+	require.Equal(t, int64(dropped), buf.Stats().MetricsDropped.Get())
+
+	// The newest metrics must be kept
+	tx := buf.BeginTransaction(10)
+	testutil.RequireMetricsEqual(t, metrics[dropped:], tx.Batch)
+}
+
+func TestDiskBufferMaxAge(t *testing.T) {
+	buf, err := NewDiskBuffer("test", "123", t.TempDir(), NewBufferStats("test", "", 0))
+	require.NoError(t, err)
+	buf.Stats().MetricsDropped.Set(0)
+	defer buf.Close()
+
+	now := time.Now()
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1}, now.Add(-3*time.Hour)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 2}, now.Add(-2*time.Hour)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 3}, now),
+	}
+	buf.Add(metrics...)
+
+	buf.SetLimits(0, 90*time.Minute)
+	require.Equal(t, 1, buf.Len())
+	require.Equal(t, int64(2), buf.Stats().MetricsDropped.Get())
+
+	tx := buf.BeginTransaction(10)
+	testutil.RequireMetricsEqual(t, metrics[2:], tx.Batch)
+}
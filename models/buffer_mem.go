@@ -127,6 +127,22 @@ func (b *MemoryBuffer) Stats() BufferStats {
 	return b.BufferStats
 }
 
+// drain removes all metrics not being part of a transaction from the buffer
+// and returns them from oldest to newest without updating the stats.
+func (b *MemoryBuffer) drain() []telegraf.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	metrics := make([]telegraf.Metric, 0, b.size)
+	for b.size > 0 {
+		metrics = append(metrics, b.buf[b.first])
+		b.buf[b.first] = nil
+		b.first = b.next(b.first)
+		b.size--
+	}
+	return metrics
+}
+
 func (b *MemoryBuffer) length() int {
 	return min(b.size+b.batchSize, b.cap)
 }
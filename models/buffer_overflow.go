@@ -0,0 +1,170 @@
+package models
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// OverflowBuffer keeps metrics in memory and only spills them to a WAL file
+// on disk if the memory buffer is full or when closing the buffer. Metrics
+// on disk are usually older than the ones in memory and are therefore written
+// first, draining the disk before using the memory buffer again.
+//
+// Metrics of a running memory transaction cannot be spilled as the batch might
+// be kept for a retry. If the memory buffer is full during such a transaction,
+// new metrics are put to disk and are thus newer than all metrics in memory.
+// In this case, the memory is drained first while all new metrics go to disk
+// to keep the order.
+type OverflowBuffer struct {
+	BufferStats
+	sync.Mutex
+
+	memory *MemoryBuffer
+	disk   *DiskBuffer
+	name   string
+
+	// Buffer the current transaction was started on
+	batchOnDisk bool
+	batchActive bool
+
+	// Metrics on disk are newer than the ones in memory
+	diskNewer bool
+}
+
+func NewOverflowBuffer(name, id, path string, capacity int, stats BufferStats) (*OverflowBuffer, error) {
+	memory, err := NewMemoryBuffer(capacity, stats)
+	if err != nil {
+		return nil, err
+	}
+	disk, err := NewDiskBuffer(name, id, path, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &OverflowBuffer{
+		BufferStats: stats,
+		memory:      memory,
+		disk:        disk,
+		name:        name,
+	}
+	buf.BufferSize.Set(int64(buf.length()))
+	return buf, nil
+}
+
+// SetLimits sets the limits of the metrics spilled to disk, see
+// DiskBuffer.SetLimits.
+func (b *OverflowBuffer) SetLimits(maxBytes int64, maxAge time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.disk.SetLimits(maxBytes, maxAge)
+	b.BufferSize.Set(int64(b.length()))
+}
+
+func (b *OverflowBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.length()
+}
+
+func (b *OverflowBuffer) length() int {
+	return b.memory.Len() + b.disk.Len()
+}
+
+func (b *OverflowBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := 0
+	for _, m := range metrics {
+		if b.diskNewer {
+			dropped += b.disk.Add(m)
+			continue
+		}
+
+		if b.memory.Len() >= b.memory.cap {
+			// Metrics of a running memory transaction cannot be spilled, so
+			// put the metric to disk making the disk newer than the memory.
+			if b.batchActive && !b.batchOnDisk {
+				b.diskNewer = true
+				dropped += b.disk.Add(m)
+				continue
+			}
+			b.spill()
+		}
+		dropped += b.memory.Add(m)
+	}
+
+	b.BufferSize.Set(int64(b.length()))
+	return dropped
+}
+
+func (b *OverflowBuffer) BeginTransaction(batchSize int) *Transaction {
+	b.Lock()
+	defer b.Unlock()
+
+	// Drain the older buffer first which is usually the disk
+	if b.diskNewer && b.memory.Len() == 0 {
+		b.diskNewer = false
+	}
+	b.batchActive = true
+	b.batchOnDisk = !b.diskNewer && b.disk.Len() > 0
+	if b.batchOnDisk {
+		return b.disk.BeginTransaction(batchSize)
+	}
+	return b.memory.BeginTransaction(batchSize)
+}
+
+func (b *OverflowBuffer) EndTransaction(tx *Transaction) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.batchOnDisk {
+		b.disk.EndTransaction(tx)
+	} else {
+		b.memory.EndTransaction(tx)
+	}
+	b.batchOnDisk = false
+	b.batchActive = false
+
+	b.BufferSize.Set(int64(b.length()))
+}
+
+func (b *OverflowBuffer) Stats() BufferStats {
+	return b.BufferStats
+}
+
+// Close spills all metrics in memory to disk to keep them for the next start.
+func (b *OverflowBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.diskNewer && b.memory.Len() > 0 {
+		log.Printf("W! Metrics of plugin outputs.%s spilled to disk on shutdown are written after newer metrics", b.name)
+	}
+	if n := b.spill(); n > 0 {
+		log.Printf("I! Spilled %d metrics of plugin outputs.%s to disk on shutdown", n, b.name)
+	}
+	return b.disk.Close()
+}
+
+// spill moves all metrics in memory, which are not part of a transaction, to
+// the end of the WAL file and returns the number of moved metrics.
+func (b *OverflowBuffer) spill() int {
+	metrics := b.memory.drain()
+	b.disk.Lock()
+	defer b.disk.Unlock()
+	for _, m := range metrics {
+		if !b.disk.writeMetric(m) {
+			b.metricDropped(m)
+			continue
+		}
+		b.disk.handleEmptyFile()
+	}
+	b.disk.enforceLimits()
+	return len(metrics)
+}
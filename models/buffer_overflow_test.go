@@ -0,0 +1,108 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestOverflowBufferSpillsWhenFull(t *testing.T) {
+	buf, err := NewOverflowBuffer("test", "123", t.TempDir(), 2, NewBufferStats("test", "", 2))
+	require.NoError(t, err)
+	buf.Stats().MetricsAdded.Set(0)
+	buf.Stats().MetricsWritten.Set(0)
+	buf.Stats().MetricsDropped.Set(0)
+	defer buf.Close()
+
+	metrics := make([]telegraf.Metric, 0, 5)
+	for i := range 5 {
+		metrics = append(metrics, metric.New("cpu", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0)))
+	}
+	require.Zero(t, buf.Add(metrics...))
+	require.Equal(t, 5, buf.Len())
+	require.Equal(t, 4, buf.disk.Len())
+	require.Equal(t, 1, buf.memory.Len())
+	require.Equal(t, int64(5), buf.Stats().MetricsAdded.Get())
+
+	// Metrics must be returned in order, starting with the ones on disk
+	tx := buf.BeginTransaction(3)
+	testutil.RequireMetricsEqual(t, metrics[:3], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+
+	tx = buf.BeginTransaction(3)
+	testutil.RequireMetricsEqual(t, metrics[3:4], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+
+	tx = buf.BeginTransaction(3)
+	testutil.RequireMetricsEqual(t, metrics[4:], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+
+	require.Equal(t, 0, buf.Len())
+	require.Equal(t, int64(5), buf.Stats().MetricsWritten.Get())
+	require.Equal(t, int64(0), buf.Stats().MetricsDropped.Get())
+}
+
+func TestOverflowBufferSpillsOnClose(t *testing.T) {
+	path := t.TempDir()
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+
+	buf, err := NewOverflowBuffer("test", "123", path, 5, NewBufferStats("test", "", 5))
+	require.NoError(t, err)
+	buf.Add(m)
+	require.Equal(t, 0, buf.disk.Len())
+	require.NoError(t, buf.Close())
+
+	// Metrics must be restored from disk on next start
+	buf, err = NewOverflowBuffer("test", "123", path, 5, NewBufferStats("test", "", 5))
+	require.NoError(t, err)
+	defer buf.Close()
+	require.Equal(t, 1, buf.Len())
+
+	tx := buf.BeginTransaction(5)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, tx.Batch)
+}
+
+func TestOverflowBufferOrderAfterFailedWrite(t *testing.T) {
+	buf, err := NewOverflowBuffer("test", "123", t.TempDir(), 2, NewBufferStats("test", "", 2))
+	require.NoError(t, err)
+	defer buf.Close()
+
+	metrics := make([]telegraf.Metric, 0, 5)
+	for i := range 5 {
+		metrics = append(metrics, metric.New("cpu", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0)))
+	}
+	require.Zero(t, buf.Add(metrics[:2]...))
+
+	// Fill the buffer while the memory batch is written
+	tx := buf.BeginTransaction(2)
+	testutil.RequireMetricsEqual(t, metrics[:2], tx.Batch)
+	require.Zero(t, buf.Add(metrics[2:4]...))
+	require.Equal(t, 2, buf.disk.Len())
+
+	// Fail the write and keep the batch for a retry
+	tx.KeepAll()
+	buf.EndTransaction(tx)
+
+	// The retried batch must be written before the newer metrics on disk
+	tx = buf.BeginTransaction(2)
+	testutil.RequireMetricsEqual(t, metrics[:2], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+
+	// Metrics added after the failure must be written after the ones on disk
+	require.Zero(t, buf.Add(metrics[4]))
+
+	tx = buf.BeginTransaction(5)
+	testutil.RequireMetricsEqual(t, metrics[2:], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, 0, buf.Len())
+}
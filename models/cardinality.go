@@ -0,0 +1,227 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// Default duration series are tracked by the cardinality guard
+	DefaultCardinalityWindow = time.Hour
+
+	// Value replacing the values of offending tags with the "aggregate" action
+	CardinalityAggregateValue = "other"
+)
+
+// cardinalityGuard limits the number of distinct series per measurement seen
+// within a sliding window. Once the limit is exceeded by a new series, the tag
+// with the most distinct values of the measurement is considered to be the
+// offending tag and is dropped or aggregated for all further metrics of the
+// measurement until the tag was not seen for a whole window.
+type cardinalityGuard struct {
+	limit  int
+	window time.Duration
+	action string
+	tags   map[string]string
+	log    telegraf.Logger
+
+	measurements map[string]*seriesTracker
+	lastExpiry   time.Time
+	sync.Mutex
+}
+
+// seriesTracker keeps the series and tag values of a measurement along with
+// the last time they were seen
+type seriesTracker struct {
+	series    map[uint64]time.Time
+	values    map[string]map[string]time.Time
+	offenders map[string]*cardinalityOffender
+}
+
+type cardinalityOffender struct {
+	lastSeen time.Time
+
+	distinctStat selfstat.Stat
+	modifiedStat selfstat.Stat
+}
+
+func newCardinalityGuard(config *OutputConfig, tags map[string]string, log telegraf.Logger) *cardinalityGuard {
+	window := config.CardinalityWindow
+	if window <= 0 {
+		window = DefaultCardinalityWindow
+	}
+	action := config.CardinalityAction
+	if action == "" {
+		action = "drop"
+	}
+
+	return &cardinalityGuard{
+		limit:        config.CardinalityLimit,
+		window:       window,
+		action:       action,
+		tags:         tags,
+		log:          log,
+		measurements: make(map[string]*seriesTracker),
+		lastExpiry:   time.Now(),
+	}
+}
+
+// apply limits the offending tags of the given metric in place
+func (g *cardinalityGuard) apply(m telegraf.Metric) {
+	now := time.Now()
+
+	g.Lock()
+	defer g.Unlock()
+
+	g.expire(now)
+
+	t, found := g.measurements[m.Name()]
+	if !found {
+		t = &seriesTracker{
+			series:    make(map[uint64]time.Time),
+			values:    make(map[string]map[string]time.Time),
+			offenders: make(map[string]*cardinalityOffender),
+		}
+		g.measurements[m.Name()] = t
+	}
+
+	// Limit the tags already known to exceed the cardinality
+	for key, offender := range t.offenders {
+		if _, found := m.GetTag(key); found {
+			g.limitTag(m, key)
+			offender.lastSeen = now
+			offender.modifiedStat.Incr(1)
+		}
+	}
+
+	for {
+		id := m.HashID()
+		if _, found := t.series[id]; found || len(t.series) < g.limit {
+			t.series[id] = now
+			t.observe(m, now)
+			return
+		}
+
+		// The new series exceeds the limit so find the tag to blame
+		key := t.offendingTag(m)
+		if key == "" {
+			// Nothing left to limit, accept the metric
+			t.series[id] = now
+			return
+		}
+		offender := g.addOffender(m.Name(), key, len(t.values[key]), now)
+		t.offenders[key] = offender
+		delete(t.values, key)
+
+		// Series containing the offending tag will not occur anymore, so
+		// restart counting with the limited series
+		t.series = make(map[uint64]time.Time)
+
+		g.limitTag(m, key)
+		offender.modifiedStat.Incr(1)
+	}
+}
+
+func (g *cardinalityGuard) limitTag(m telegraf.Metric, key string) {
+	if g.action == "aggregate" {
+		m.AddTag(key, CardinalityAggregateValue)
+		return
+	}
+	m.RemoveTag(key)
+}
+
+func (g *cardinalityGuard) addOffender(measurement, key string, distinct int, now time.Time) *cardinalityOffender {
+	g.log.Warnf("Measurement %q exceeds the limit of %d series; tag %q with %d distinct values is limited using action %q",
+		measurement, g.limit, key, distinct, g.action)
+
+	tags := make(map[string]string, len(g.tags)+2)
+	for k, v := range g.tags {
+		tags[k] = v
+	}
+	tags["measurement"] = measurement
+	tags["tag_key"] = key
+
+	offender := &cardinalityOffender{
+		lastSeen:     now,
+		distinctStat: selfstat.Register("cardinality", "distinct_values", tags),
+		modifiedStat: selfstat.Register("cardinality", "metrics_modified", tags),
+	}
+	offender.distinctStat.Set(int64(distinct))
+	return offender
+}
+
+// expire removes all series, tag values and offenders not seen within the
+// window. To reduce the overhead, expiry runs at most ten times per window.
+func (g *cardinalityGuard) expire(now time.Time) {
+	if now.Sub(g.lastExpiry) < g.window/10 {
+		return
+	}
+	g.lastExpiry = now
+
+	cutoff := now.Add(-g.window)
+	for name, t := range g.measurements {
+		for id, seen := range t.series {
+			if seen.Before(cutoff) {
+				delete(t.series, id)
+			}
+		}
+		for key, values := range t.values {
+			for v, seen := range values {
+				if seen.Before(cutoff) {
+					delete(values, v)
+				}
+			}
+			if len(values) == 0 {
+				delete(t.values, key)
+			}
+		}
+		for key, offender := range t.offenders {
+			if offender.lastSeen.Before(cutoff) {
+				g.log.Infof("Tag %q of measurement %q is no longer limited", key, name)
+				delete(t.offenders, key)
+			}
+		}
+		if len(t.series) == 0 && len(t.offenders) == 0 {
+			delete(g.measurements, name)
+		}
+	}
+}
+
+// observe records the values of the tags of the given metric except for the
+// offending tags
+func (t *seriesTracker) observe(m telegraf.Metric, now time.Time) {
+	for _, tag := range m.TagList() {
+		if _, found := t.offenders[tag.Key]; found {
+			continue
+		}
+		values, found := t.values[tag.Key]
+		if !found {
+			values = make(map[string]time.Time)
+			t.values[tag.Key] = values
+		}
+		values[tag.Value] = now
+	}
+}
+
+// offendingTag returns the tag of the metric with the most distinct values
+// not yet limited, ties are resolved by the key name.
+func (t *seriesTracker) offendingTag(m telegraf.Metric) string {
+	keys := make([]string, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		if _, found := t.offenders[tag.Key]; !found {
+			keys = append(keys, tag.Key)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return len(t.values[keys[i]]) > len(t.values[keys[j]])
+	})
+
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NewMetricEnv returns the CEL environment for expressions evaluated on
+// metrics. The metric is accessible via the "name", "tags", "fields" and
+// "time" variables, see MetricActivation.
+func NewMetricEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.VariableDecls(
+			decls.NewVariable("name", types.StringType),
+			decls.NewVariable("tags", types.NewMapType(types.StringType, types.StringType)),
+			decls.NewVariable("fields", types.NewMapType(types.StringType, types.DynType)),
+			decls.NewVariable("time", types.TimestampType),
+		),
+		cel.Function(
+			"now",
+			cel.Overload("now", nil, cel.TimestampType),
+			cel.SingletonFunctionBinding(func(_ ...ref.Val) ref.Val { return types.Timestamp{Time: time.Now()} }),
+		),
+		ext.Encoders(),
+		ext.Math(),
+		ext.Strings(),
+	)
+}
+
+// MetricActivation returns the variables of the metric for evaluating
+// programs of the environment returned by NewMetricEnv.
+func MetricActivation(metric telegraf.Metric) map[string]interface{} {
+	return map[string]interface{}{
+		"name":   metric.Name(),
+		"tags":   metric.Tags(),
+		"fields": metric.Fields(),
+		"time":   metric.Time(),
+	}
+}
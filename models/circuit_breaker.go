@@ -0,0 +1,154 @@
+package models
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// ErrCircuitOpen is returned when writing to an output is skipped because its
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open, skipping write")
+
+const (
+	// Default backoff limits of the circuit breaker
+	DefaultCircuitBreakerMinBackoff = 10 * time.Second
+	DefaultCircuitBreakerMaxBackoff = 5 * time.Minute
+)
+
+// States of the circuit breaker as reported in the statistics
+const (
+	circuitClosed int64 = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker stops writes to an output after a number of consecutive
+// failures. Writes are paused with an exponentially increasing and jittered
+// backoff. Once the backoff elapsed a single probe write is allowed deciding
+// whether to resume writing or to pause again.
+type circuitBreaker struct {
+	threshold  int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	log        telegraf.Logger
+
+	state    int64
+	failures int
+	trips    int
+	until    time.Time
+	sync.Mutex
+
+	stateStat   selfstat.Stat
+	tripsStat   selfstat.Stat
+	skippedStat selfstat.Stat
+}
+
+func newCircuitBreaker(config *OutputConfig, tags map[string]string, log telegraf.Logger) *circuitBreaker {
+	minBackoff := config.CircuitBreakerMinBackoff
+	if minBackoff <= 0 {
+		minBackoff = DefaultCircuitBreakerMinBackoff
+	}
+	maxBackoff := config.CircuitBreakerMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = max(DefaultCircuitBreakerMaxBackoff, minBackoff)
+	}
+
+	return &circuitBreaker{
+		threshold:   config.CircuitBreakerThreshold,
+		minBackoff:  minBackoff,
+		maxBackoff:  maxBackoff,
+		log:         log,
+		stateStat:   selfstat.Register("write", "circuit_breaker_state", tags),
+		tripsStat:   selfstat.Register("write", "circuit_breaker_trips", tags),
+		skippedStat: selfstat.Register("write", "circuit_breaker_skipped_writes", tags),
+	}
+}
+
+// allow checks if a write should be attempted and switches to the half-open
+// state once the backoff elapsed.
+func (b *circuitBreaker) allow() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.state == circuitOpen {
+		if time.Now().Before(b.until) {
+			b.skippedStat.Incr(1)
+			return false
+		}
+		b.log.Debug("Circuit breaker half-open, probing output")
+		b.setState(circuitHalfOpen)
+	}
+	return true
+}
+
+// record updates the breaker with the result of a write
+func (b *circuitBreaker) record(err error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if !isWriteFailure(err) {
+		if b.state != circuitClosed {
+			b.log.Infof("Circuit breaker closed after %d consecutive failed writes", b.failures)
+		}
+		b.failures = 0
+		b.trips = 0
+		b.setState(circuitClosed)
+		return
+	}
+
+	b.failures++
+	switch b.state {
+	case circuitClosed:
+		if b.failures < b.threshold {
+			return
+		}
+		b.trip()
+		b.log.Warnf("Circuit breaker opened after %d consecutive failed writes, pausing writes until %s",
+			b.failures, b.until.Format(time.RFC3339))
+	case circuitHalfOpen:
+		b.trip()
+		b.log.Warnf("Probe write failed, pausing writes until %s", b.until.Format(time.RFC3339))
+	}
+}
+
+// trip opens the breaker with a backoff doubled for each consecutive trip
+func (b *circuitBreaker) trip() {
+	backoff := b.minBackoff
+	for i := 0; i < b.trips && backoff < b.maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, b.maxBackoff)
+
+	// Use a jitter of up to half of the backoff to avoid all outputs
+	// retrying at the same time
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	b.trips++
+	b.until = time.Now().Add(backoff)
+	b.tripsStat.Incr(1)
+	b.setState(circuitOpen)
+}
+
+func (b *circuitBreaker) setState(state int64) {
+	b.state = state
+	b.stateStat.Set(state)
+}
+
+// isWriteFailure returns true if the error indicates the output could not
+// accept or reject any metric. Partial writes prove the endpoint to be alive.
+func isWriteFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var writeErr *internal.PartialWriteError
+	if errors.As(err, &writeErr) {
+		return len(writeErr.MetricsAccept) == 0 && len(writeErr.MetricsReject) == 0
+	}
+	return true
+}
@@ -3,13 +3,8 @@ package models
 import (
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/google/cel-go/cel"
-	"github.com/google/cel-go/common/decls"
-	"github.com/google/cel-go/common/types"
-	"github.com/google/cel-go/common/types/ref"
-	"github.com/google/cel-go/ext"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/filter"
@@ -142,12 +137,7 @@ func (f *Filter) Select(metric telegraf.Metric) (bool, error) {
 	}
 
 	if f.metricFilter != nil {
-		result, _, err := f.metricFilter.Eval(map[string]interface{}{
-			"name":   metric.Name(),
-			"tags":   metric.Tags(),
-			"fields": metric.Fields(),
-			"time":   metric.Time(),
-		})
+		result, _, err := f.metricFilter.Eval(MetricActivation(metric))
 		if err != nil {
 			return true, err
 		}
@@ -246,22 +236,7 @@ func (f *Filter) compileMetricFilter() error {
 	}
 
 	// Declare the computation environment for the filter including custom functions
-	env, err := cel.NewEnv(
-		cel.VariableDecls(
-			decls.NewVariable("name", types.StringType),
-			decls.NewVariable("tags", types.NewMapType(types.StringType, types.StringType)),
-			decls.NewVariable("fields", types.NewMapType(types.StringType, types.DynType)),
-			decls.NewVariable("time", types.TimestampType),
-		),
-		cel.Function(
-			"now",
-			cel.Overload("now", nil, cel.TimestampType),
-			cel.SingletonFunctionBinding(func(_ ...ref.Val) ref.Val { return types.Timestamp{Time: time.Now()} }),
-		),
-		ext.Encoders(),
-		ext.Math(),
-		ext.Strings(),
-	)
+	env, err := NewMetricEnv()
 	if err != nil {
 		return fmt.Errorf("creating environment failed: %w", err)
 	}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// AgentMetricsUnrouted counts the metrics not matching any route
+var AgentMetricsUnrouted = selfstat.Register("agent", "metrics_unrouted", make(map[string]string))
+
+// AgentRouteErrors counts the failed evaluations of route expressions
+var AgentRouteErrors = selfstat.Register("agent", "route_errors", make(map[string]string))
+
+// Minimum interval between logging evaluation errors of the same route
+const routeErrorLogInterval = time.Minute
+
+// Route sends the metrics matching the CEL expression to the outputs with
+// the given aliases
+type Route struct {
+	Name       string   `toml:"name"`
+	MetricPass string   `toml:"metricpass"`
+	Outputs    []string `toml:"outputs"`
+
+	filter Filter
+
+	lastErrorLog time.Time
+	sync.Mutex
+}
+
+// logError logs the given evaluation error if the last error of the route was
+// logged long enough ago to not flood the log
+func (r *Route) logError(err error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if time.Since(r.lastErrorLog) < routeErrorLogInterval {
+		return
+	}
+	r.lastErrorLog = time.Now()
+	log.Printf("W! [agent] Evaluating route %q failed, metrics are not routed by this route: %v", r.Name, err)
+}
+
+// Router selects the outputs of a metric using a routing table. Outputs not
+// referenced by any route or the default route are not restricted by the
+// router and receive all metrics.
+type Router struct {
+	Routes  []*Route
+	Default []string
+
+	routed map[string]bool
+}
+
+// Init compiles the routes and checks the routing table for issues
+func (r *Router) Init() error {
+	r.routed = make(map[string]bool)
+	names := make(map[string]bool, len(r.Routes))
+	for _, route := range r.Routes {
+		if route.Name == "" {
+			return errors.New("route without name")
+		}
+		if names[route.Name] {
+			return fmt.Errorf("duplicate route %q", route.Name)
+		}
+		names[route.Name] = true
+
+		if route.MetricPass == "" {
+			return fmt.Errorf("route %q without metricpass expression", route.Name)
+		}
+		if len(route.Outputs) == 0 {
+			return fmt.Errorf("route %q without outputs", route.Name)
+		}
+
+		route.filter = Filter{MetricPass: route.MetricPass}
+		if err := route.filter.Compile(); err != nil {
+			return fmt.Errorf("compiling route %q failed: %w", route.Name, err)
+		}
+		for _, alias := range route.Outputs {
+			r.routed[alias] = true
+		}
+	}
+	for _, alias := range r.Default {
+		r.routed[alias] = true
+	}
+
+	return nil
+}
+
+// Aliases returns the aliases of all outputs referenced by the routing table
+func (r *Router) Aliases() map[string]bool {
+	return r.routed
+}
+
+// Routed returns true if the output with the given alias only receives the
+// metrics routed to it
+func (r *Router) Routed(alias string) bool {
+	return r.routed[alias]
+}
+
+// Route returns the aliases of the outputs the metric is routed to. Metrics
+// not matching any route are sent to the outputs of the default route.
+func (r *Router) Route(m telegraf.Metric) map[string]bool {
+	targets := make(map[string]bool)
+	for _, route := range r.Routes {
+		ok, err := route.filter.Select(m)
+		if err != nil {
+			// Do not route on evaluation errors, e.g. due to missing tags
+			AgentRouteErrors.Incr(1)
+			route.logError(err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		for _, alias := range route.Outputs {
+			targets[alias] = true
+		}
+	}
+
+	if len(targets) == 0 {
+		AgentMetricsUnrouted.Incr(1)
+		for _, alias := range r.Default {
+			targets[alias] = true
+		}
+	}
+	return targets
+}
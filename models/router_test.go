@@ -0,0 +1,154 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestRouterRoute(t *testing.T) {
+	router := &Router{
+		Routes: []*Route{
+			{
+				Name:       "alerts",
+				MetricPass: `name == "alert"`,
+				Outputs:    []string{"pager", "archive"},
+			},
+			{
+				Name:       "production",
+				MetricPass: `"env" in tags && tags.env == "prod"`,
+				Outputs:    []string{"timeseries"},
+			},
+		},
+		Default: []string{"archive"},
+	}
+	require.NoError(t, router.Init())
+	require.True(t, router.Routed("pager"))
+	require.True(t, router.Routed("archive"))
+	require.True(t, router.Routed("timeseries"))
+	require.False(t, router.Routed("other"))
+
+	tests := []struct {
+		name     string
+		metric   string
+		tags     map[string]string
+		expected map[string]bool
+	}{
+		{
+			name:     "single route",
+			metric:   "alert",
+			expected: map[string]bool{"pager": true, "archive": true},
+		},
+		{
+			name:     "multiple routes",
+			metric:   "alert",
+			tags:     map[string]string{"env": "prod"},
+			expected: map[string]bool{"pager": true, "archive": true, "timeseries": true},
+		},
+		{
+			name:     "default route",
+			metric:   "cpu",
+			tags:     map[string]string{"env": "dev"},
+			expected: map[string]bool{"archive": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testutil.MustMetric(tt.metric, tt.tags, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+			require.Equal(t, tt.expected, router.Route(m))
+		})
+	}
+}
+
+func TestRouterUnrouted(t *testing.T) {
+	router := &Router{
+		Routes: []*Route{
+			{
+				Name:       "alerts",
+				MetricPass: `name == "alert"`,
+				Outputs:    []string{"pager"},
+			},
+		},
+	}
+	require.NoError(t, router.Init())
+
+	before := AgentMetricsUnrouted.Get()
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.Empty(t, router.Route(m))
+	require.Equal(t, before+1, AgentMetricsUnrouted.Get())
+
+	m = testutil.MustMetric("alert", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.Equal(t, map[string]bool{"pager": true}, router.Route(m))
+	require.Equal(t, before+1, AgentMetricsUnrouted.Get())
+}
+
+func TestRouterEvaluationError(t *testing.T) {
+	router := &Router{
+		Routes: []*Route{
+			{
+				Name:       "production",
+				MetricPass: `tags.env == "prod"`,
+				Outputs:    []string{"timeseries"},
+			},
+		},
+		Default: []string{"archive"},
+	}
+	require.NoError(t, router.Init())
+
+	// Metrics without the tag fail to evaluate and are counted
+	before := AgentRouteErrors.Get()
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.Equal(t, map[string]bool{"archive": true}, router.Route(m))
+	require.Equal(t, map[string]bool{"archive": true}, router.Route(m))
+	require.Equal(t, before+2, AgentRouteErrors.Get())
+
+	m = testutil.MustMetric("cpu", map[string]string{"env": "prod"}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.Equal(t, map[string]bool{"timeseries": true}, router.Route(m))
+	require.Equal(t, before+2, AgentRouteErrors.Get())
+}
+
+func TestRouterInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		routes   []*Route
+		expected string
+	}{
+		{
+			name:     "missing name",
+			routes:   []*Route{{MetricPass: "true", Outputs: []string{"a"}}},
+			expected: "route without name",
+		},
+		{
+			name: "duplicate name",
+			routes: []*Route{
+				{Name: "a", MetricPass: "true", Outputs: []string{"a"}},
+				{Name: "a", MetricPass: "true", Outputs: []string{"b"}},
+			},
+			expected: `duplicate route "a"`,
+		},
+		{
+			name:     "missing expression",
+			routes:   []*Route{{Name: "a", Outputs: []string{"a"}}},
+			expected: `route "a" without metricpass expression`,
+		},
+		{
+			name:     "missing outputs",
+			routes:   []*Route{{Name: "a", MetricPass: "true"}},
+			expected: `route "a" without outputs`,
+		},
+		{
+			name:     "invalid expression",
+			routes:   []*Route{{Name: "a", MetricPass: "foo(", Outputs: []string{"a"}}},
+			expected: `compiling route "a" failed`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := &Router{Routes: tt.routes}
+			require.ErrorContains(t, router.Init(), tt.expected)
+		})
+	}
+}
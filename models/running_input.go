@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -15,6 +16,7 @@ var (
 	GlobalMetricsGathered = selfstat.Register("agent", "metrics_gathered", make(map[string]string))
 	GlobalGatherErrors    = selfstat.Register("agent", "gather_errors", make(map[string]string))
 	GlobalGatherTimeouts  = selfstat.Register("agent", "gather_timeouts", make(map[string]string))
+	GlobalGatherTruncated = selfstat.Register("agent", "gather_truncated", make(map[string]string))
 )
 
 type RunningInput struct {
@@ -30,9 +32,17 @@ type RunningInput struct {
 	gatherStart time.Time
 	gatherEnd   time.Time
 
+	// State of the limits of the currently running gather cycle
+	gathering       atomic.Bool
+	gatherDeadline  atomic.Int64
+	gatherCount     atomic.Int64
+	gatherTruncated atomic.Bool
+
 	MetricsGathered selfstat.Stat
+	MetricsRejected selfstat.Stat
 	GatherTime      selfstat.Stat
 	GatherTimeouts  selfstat.Stat
+	GatherTruncated selfstat.Stat
 	StartupErrors   selfstat.Stat
 }
 
@@ -61,6 +71,11 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"metrics_gathered",
 			tags,
 		),
+		MetricsRejected: selfstat.Register(
+			"gather",
+			"metrics_rejected",
+			tags,
+		),
 		GatherTime: selfstat.RegisterTiming(
 			"gather",
 			"gather_time_ns",
@@ -71,6 +86,11 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"gather_timeouts",
 			tags,
 		),
+		GatherTruncated: selfstat.Register(
+			"gather",
+			"gather_truncated",
+			tags,
+		),
 		StartupErrors: selfstat.Register(
 			"write",
 			"startup_errors",
@@ -93,6 +113,8 @@ type InputConfig struct {
 	TimeSource           string
 	StartupErrorBehavior string
 	LogLevel             string
+	MaxMetricsPerGather  int
+	GatherDeadline       time.Duration
 
 	NameOverride            string
 	MeasurementPrefix       string
@@ -126,6 +148,13 @@ func (r *RunningInput) Init() error {
 		return fmt.Errorf("invalid 'time_source' setting %q", r.Config.TimeSource)
 	}
 
+	if r.Config.MaxMetricsPerGather < 0 {
+		return fmt.Errorf("invalid 'max_metrics_per_gather' setting %d", r.Config.MaxMetricsPerGather)
+	}
+	if r.Config.GatherDeadline < 0 {
+		return fmt.Errorf("invalid 'gather_deadline' setting %s", r.Config.GatherDeadline)
+	}
+
 	if p, ok := r.Input.(telegraf.Initializer); ok {
 		return p.Init()
 	}
@@ -234,11 +263,42 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 	default:
 	}
 
+	if r.gatherLimitReached() {
+		r.MetricsRejected.Incr(1)
+		metric.Drop()
+		return nil
+	}
+
 	r.MetricsGathered.Incr(1)
 	GlobalMetricsGathered.Incr(1)
 	return metric
 }
 
+// gatherLimitReached checks if a metric exceeds the limits of the running
+// gather cycle. The first violation in a cycle truncates the gather and all
+// further metrics of the cycle are rejected.
+func (r *RunningInput) gatherLimitReached() bool {
+	if !r.gathering.Load() {
+		return false
+	}
+
+	var reason string
+	if deadline := r.gatherDeadline.Load(); deadline > 0 && time.Now().UnixNano() > deadline {
+		reason = fmt.Sprintf("did not complete within the deadline of %s", r.Config.GatherDeadline)
+	} else if limit := r.Config.MaxMetricsPerGather; limit > 0 && r.gatherCount.Add(1) > int64(limit) {
+		reason = fmt.Sprintf("exceeded the limit of %d metrics", limit)
+	} else {
+		return false
+	}
+
+	if r.gatherTruncated.CompareAndSwap(false, true) {
+		r.log.Warnf("Collection %s; rejecting further metrics of this collection", reason)
+		r.GatherTruncated.Incr(1)
+		GlobalGatherTruncated.Incr(1)
+	}
+	return true
+}
+
 func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 	// Try to connect if we are not yet started up
 	if plugin, ok := r.Input.(telegraf.ServiceInput); ok && !r.started {
@@ -257,13 +317,33 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 	}
 
 	r.gatherStart = time.Now()
+	r.startGatherLimits()
 	err := r.Input.Gather(acc)
+	r.gathering.Store(false)
 	r.gatherEnd = time.Now()
 
 	r.GatherTime.Incr(r.gatherEnd.Sub(r.gatherStart).Nanoseconds())
 	return err
 }
 
+// startGatherLimits resets the limits for a new gather cycle. Limits only apply
+// to metrics added during Gather, so metrics added in the background by service
+// inputs are not affected.
+func (r *RunningInput) startGatherLimits() {
+	if r.Config.MaxMetricsPerGather == 0 && r.Config.GatherDeadline == 0 {
+		return
+	}
+
+	var deadline int64
+	if r.Config.GatherDeadline > 0 {
+		deadline = r.gatherStart.Add(r.Config.GatherDeadline).UnixNano()
+	}
+	r.gatherDeadline.Store(deadline)
+	r.gatherCount.Store(0)
+	r.gatherTruncated.Store(false)
+	r.gathering.Store(true)
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
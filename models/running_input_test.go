@@ -488,6 +488,72 @@ func TestRunningInputMakeMetricWithGatherEndTimeSource(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestRunningInputMaxMetricsPerGather(t *testing.T) {
+	ri := NewRunningInput(&mockInput{
+		gather: func(acc telegraf.Accumulator) {
+			for i := 0; i < 5; i++ {
+				acc.AddFields("test", map[string]interface{}{"value": i}, nil)
+			}
+		},
+	}, &InputConfig{
+		Name:                "TestRunningInputMaxMetricsPerGather",
+		MaxMetricsPerGather: 3,
+	})
+	require.NoError(t, ri.Init())
+	ri.log = testutil.Logger{}
+
+	// The limit is reset for each gather cycle
+	for i := 0; i < 2; i++ {
+		var acc testutil.Accumulator
+		require.NoError(t, ri.Gather(&makeMetricAccumulator{Accumulator: &acc, maker: ri}))
+		require.Len(t, acc.GetTelegrafMetrics(), 3)
+	}
+	require.Equal(t, int64(2), ri.GatherTruncated.Get())
+	require.Equal(t, int64(4), ri.MetricsRejected.Get())
+	require.Equal(t, int64(6), ri.MetricsGathered.Get())
+
+	// Metrics added outside of the gather cycle are not limited
+	for i := 0; i < 5; i++ {
+		require.NotNil(t, ri.MakeMetric(testutil.MockMetrics()[0]))
+	}
+}
+
+func TestRunningInputGatherDeadline(t *testing.T) {
+	ri := NewRunningInput(&mockInput{
+		gather: func(acc telegraf.Accumulator) {
+			acc.AddFields("test", map[string]interface{}{"value": 1}, nil)
+			time.Sleep(100 * time.Millisecond)
+			acc.AddFields("test", map[string]interface{}{"value": 2}, nil)
+			acc.AddFields("test", map[string]interface{}{"value": 3}, nil)
+		},
+	}, &InputConfig{
+		Name:           "TestRunningInputGatherDeadline",
+		GatherDeadline: 50 * time.Millisecond,
+	})
+	require.NoError(t, ri.Init())
+	ri.log = testutil.Logger{}
+
+	var acc testutil.Accumulator
+	require.NoError(t, ri.Gather(&makeMetricAccumulator{Accumulator: &acc, maker: ri}))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, int64(1), ri.GatherTruncated.Get())
+	require.Equal(t, int64(2), ri.MetricsRejected.Get())
+}
+
+func TestRunningInputInvalidLimits(t *testing.T) {
+	ri := NewRunningInput(&mockInput{}, &InputConfig{
+		Name:                "TestRunningInput",
+		MaxMetricsPerGather: -1,
+	})
+	require.ErrorContains(t, ri.Init(), "invalid 'max_metrics_per_gather' setting -1")
+
+	ri = NewRunningInput(&mockInput{}, &InputConfig{
+		Name:           "TestRunningInput",
+		GatherDeadline: -time.Second,
+	})
+	require.ErrorContains(t, ri.Init(), "invalid 'gather_deadline' setting -1s")
+}
+
 func TestRunningInputProbingFailure(t *testing.T) {
 	ri := NewRunningInput(&mockInput{
 		probeReturn: errors.New("probing error"),
@@ -518,7 +584,7 @@ func TestRunningInputProbingSuccess(t *testing.T) {
 		},
 		{
 			name:                 "probing plugin with probe value not set",
-			input:                &mockInput{probeErr},
+			input:                &mockInput{probeReturn: probeErr},
 			startupErrorBehavior: "ignore",
 		},
 	} {
@@ -535,6 +601,7 @@ func TestRunningInputProbingSuccess(t *testing.T) {
 
 type mockInput struct {
 	probeReturn error
+	gather      func(telegraf.Accumulator)
 }
 
 func (*mockInput) SampleConfig() string {
@@ -545,6 +612,22 @@ func (m *mockInput) Probe() error {
 	return m.probeReturn
 }
 
-func (*mockInput) Gather(telegraf.Accumulator) error {
+func (m *mockInput) Gather(acc telegraf.Accumulator) error {
+	if m.gather != nil {
+		m.gather(acc)
+	}
 	return nil
 }
+
+// makeMetricAccumulator passes the metrics through the running input like the
+// agent's accumulator does
+type makeMetricAccumulator struct {
+	*testutil.Accumulator
+	maker *RunningInput
+}
+
+func (a *makeMetricAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if m := a.maker.MakeMetric(metric.New(measurement, tags, fields, time.Now())); m != nil {
+		a.Accumulator.AddMetric(m)
+	}
+}
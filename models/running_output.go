@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -41,6 +42,16 @@ type OutputConfig struct {
 
 	BufferStrategy  string
 	BufferDirectory string
+	BufferMaxBytes  int64
+	BufferMaxAge    time.Duration
+
+	CircuitBreakerThreshold  int
+	CircuitBreakerMinBackoff time.Duration
+	CircuitBreakerMaxBackoff time.Duration
+
+	CardinalityLimit  int
+	CardinalityWindow time.Duration
+	CardinalityAction string
 
 	LogLevel string
 }
@@ -62,8 +73,10 @@ type RunningOutput struct {
 
 	BatchReady chan time.Time
 
-	buffer Buffer
-	log    telegraf.Logger
+	buffer  Buffer
+	breaker *circuitBreaker
+	guard   *cardinalityGuard
+	log     telegraf.Logger
 
 	started bool
 	retries uint64
@@ -104,6 +117,9 @@ func NewRunningOutput(output telegraf.Output, config *OutputConfig, batchSize, b
 	if err != nil {
 		panic(err)
 	}
+	if lb, ok := b.(limitedBuffer); ok && (config.BufferMaxBytes > 0 || config.BufferMaxAge > 0) {
+		lb.SetLimits(config.BufferMaxBytes, config.BufferMaxAge)
+	}
 
 	ro := &RunningOutput{
 		buffer:            b,
@@ -129,6 +145,12 @@ func NewRunningOutput(output telegraf.Output, config *OutputConfig, batchSize, b
 		),
 		log: logger,
 	}
+	if config.CircuitBreakerThreshold > 0 {
+		ro.breaker = newCircuitBreaker(config, tags, logger)
+	}
+	if config.CardinalityLimit > 0 {
+		ro.guard = newCardinalityGuard(config, tags, logger)
+	}
 
 	return ro
 }
@@ -156,6 +178,22 @@ func (r *RunningOutput) Init() error {
 		return fmt.Errorf("invalid 'startup_error_behavior' setting %q", r.Config.StartupErrorBehavior)
 	}
 
+	if r.Config.CircuitBreakerThreshold < 0 {
+		return fmt.Errorf("invalid 'circuit_breaker_threshold' setting %d", r.Config.CircuitBreakerThreshold)
+	}
+	if r.breaker != nil && r.breaker.minBackoff > r.breaker.maxBackoff {
+		return errors.New("'circuit_breaker_min_backoff' must not exceed 'circuit_breaker_max_backoff'")
+	}
+
+	if r.Config.CardinalityLimit < 0 {
+		return fmt.Errorf("invalid 'cardinality_limit' setting %d", r.Config.CardinalityLimit)
+	}
+	switch r.Config.CardinalityAction {
+	case "", "drop", "aggregate":
+	default:
+		return fmt.Errorf("invalid 'cardinality_action' setting %q", r.Config.CardinalityAction)
+	}
+
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -206,6 +244,14 @@ func (r *RunningOutput) Close() {
 	}
 }
 
+// Release frees the resources of an output that was never connected, e.g.
+// when a loaded configuration is discarded.
+func (r *RunningOutput) Release() {
+	if err := r.buffer.Close(); err != nil {
+		r.log.Errorf("Error closing output buffer: %v", err)
+	}
+}
+
 // AddMetric adds a metric to the output.
 // The given metric will be copied if the output selects the metric.
 func (r *RunningOutput) AddMetric(metric telegraf.Metric) {
@@ -260,6 +306,10 @@ func (r *RunningOutput) add(metric telegraf.Metric) {
 		metric.AddSuffix(r.Config.NameSuffix)
 	}
 
+	if r.guard != nil {
+		r.guard.apply(metric)
+	}
+
 	dropped := r.buffer.Add(metric)
 	atomic.AddInt64(&r.droppedMetrics, int64(dropped))
 
@@ -276,6 +326,11 @@ func (r *RunningOutput) add(metric telegraf.Metric) {
 // Write writes all metrics to the output, stopping when all have been sent on
 // or error.
 func (r *RunningOutput) Write() error {
+	// Skip writing while the circuit breaker is open
+	if r.breaker != nil && !r.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
 	// Try to connect if we are not yet started up
 	if !r.started {
 		r.retries++
@@ -283,6 +338,7 @@ func (r *RunningOutput) Write() error {
 			var serr *internal.StartupError
 			if !errors.As(err, &serr) || !serr.Retry || !serr.Partial {
 				r.StartupErrors.Incr(1)
+				r.recordWrite(internal.ErrNotConnected)
 				return internal.ErrNotConnected
 			}
 			r.log.Debugf("Partially connected after %d attempts", r.retries)
@@ -314,6 +370,7 @@ func (r *RunningOutput) Write() error {
 		err := r.writeMetrics(tx.Batch)
 		r.updateTransaction(tx, err)
 		r.buffer.EndTransaction(tx)
+		r.recordWrite(err)
 		if err != nil {
 			return err
 		}
@@ -323,11 +380,17 @@ func (r *RunningOutput) Write() error {
 
 // WriteBatch writes a single batch of metrics to the output.
 func (r *RunningOutput) WriteBatch() error {
+	// Skip writing while the circuit breaker is open
+	if r.breaker != nil && !r.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
 	// Try to connect if we are not yet started up
 	if !r.started {
 		r.retries++
 		if err := r.Output.Connect(); err != nil {
 			r.StartupErrors.Incr(1)
+			r.recordWrite(internal.ErrNotConnected)
 			return internal.ErrNotConnected
 		}
 		r.started = true
@@ -341,10 +404,18 @@ func (r *RunningOutput) WriteBatch() error {
 	err := r.writeMetrics(tx.Batch)
 	r.updateTransaction(tx, err)
 	r.buffer.EndTransaction(tx)
+	r.recordWrite(err)
 
 	return err
 }
 
+// recordWrite passes the result of a write attempt to the circuit breaker
+func (r *RunningOutput) recordWrite(err error) {
+	if r.breaker != nil {
+		r.breaker.record(err)
+	}
+}
+
 func (r *RunningOutput) writeMetrics(metrics []telegraf.Metric) error {
 	dropped := atomic.LoadInt64(&r.droppedMetrics)
 	if dropped > 0 {
@@ -385,7 +456,7 @@ func (*RunningOutput) updateTransaction(tx *Transaction, err error) {
 
 func (r *RunningOutput) LogBufferStatus() {
 	nBuffer := r.buffer.Len()
-	if r.Config.BufferStrategy == "disk" {
+	if r.Config.BufferStrategy == "disk" || r.Config.BufferStrategy == "overflow" {
 		r.log.Debugf("Buffer fullness: %d metrics", nBuffer)
 	} else {
 		r.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, r.MetricBufferLimit)
@@ -399,3 +470,24 @@ func (r *RunningOutput) Log() telegraf.Logger {
 func (r *RunningOutput) BufferLength() int {
 	return r.buffer.Len()
 }
+
+func (r *RunningOutput) BufferStats() BufferStats {
+	return r.buffer.Stats()
+}
+
+// BufferedMetrics returns all metrics currently in the buffer from oldest to
+// newest without removing them from the buffer.
+func (r *RunningOutput) BufferedMetrics() []telegraf.Metric {
+	tx := r.buffer.BeginTransaction(r.buffer.Len())
+	metrics := slices.Clone(tx.Batch)
+	tx.KeepAll()
+	r.buffer.EndTransaction(tx)
+	return metrics
+}
+
+// RestoreBuffer adds the given metrics, e.g. metrics of a previous run, to the
+// buffer without any further processing.
+func (r *RunningOutput) RestoreBuffer(metrics []telegraf.Metric) {
+	dropped := r.buffer.Add(metrics...)
+	atomic.AddInt64(&r.droppedMetrics, int64(dropped))
+}
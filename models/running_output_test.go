@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -271,6 +272,181 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	require.Len(t, m.Metrics(), 10)
 }
 
+func TestRunningOutputCircuitBreaker(t *testing.T) {
+	conf := &OutputConfig{
+		Name:                     "circuit_breaker_test",
+		CircuitBreakerThreshold:  2,
+		CircuitBreakerMinBackoff: 50 * time.Millisecond,
+		CircuitBreakerMaxBackoff: 100 * time.Millisecond,
+	}
+
+	m := &mockOutput{batchAcceptSize: -1}
+	ro := NewRunningOutput(m, conf, 4, 12)
+	require.NoError(t, ro.Init())
+
+	// Statistics are kept across test runs so only check the increase
+	trips := ro.breaker.tripsStat.Get()
+	skipped := ro.breaker.skippedStat.Get()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	// The breaker opens after the configured number of failures
+	require.Error(t, ro.Write())
+	require.Error(t, ro.Write())
+	require.Equal(t, 2, m.writes)
+	require.ErrorIs(t, ro.Write(), ErrCircuitOpen)
+	require.ErrorIs(t, ro.WriteBatch(), ErrCircuitOpen)
+	require.Equal(t, 2, m.writes)
+
+	// A failing probe opens the breaker again
+	time.Sleep(conf.CircuitBreakerMinBackoff)
+	err := ro.Write()
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, 3, m.writes)
+	require.ErrorIs(t, ro.Write(), ErrCircuitOpen)
+
+	// A successful probe closes the breaker
+	m.batchAcceptSize = 0
+	time.Sleep(conf.CircuitBreakerMaxBackoff)
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 5)
+
+	require.Equal(t, circuitClosed, ro.breaker.stateStat.Get())
+	require.Equal(t, int64(2), ro.breaker.tripsStat.Get()-trips)
+	require.Equal(t, int64(3), ro.breaker.skippedStat.Get()-skipped)
+}
+
+func TestRunningOutputCircuitBreakerInvalid(t *testing.T) {
+	ro := NewRunningOutput(&mockOutput{}, &OutputConfig{
+		CircuitBreakerThreshold:  1,
+		CircuitBreakerMinBackoff: time.Minute,
+		CircuitBreakerMaxBackoff: time.Second,
+	}, 4, 12)
+	require.ErrorContains(t, ro.Init(), "must not exceed")
+}
+
+func TestRunningOutputCardinalityGuard(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		expected []string
+	}{
+		{
+			name:   "drop",
+			action: "drop",
+			expected: []string{
+				"requests,host=a,request_id=1 value=1i",
+				"requests,host=a,request_id=2 value=1i",
+				"requests,host=a,request_id=3 value=1i",
+				"requests,host=b value=1i",
+				"requests,host=a value=1i",
+				"requests,host=c value=1i",
+				"other,request_id=4 value=1i",
+			},
+		},
+		{
+			name:   "aggregate",
+			action: "aggregate",
+			expected: []string{
+				"requests,host=a,request_id=1 value=1i",
+				"requests,host=a,request_id=2 value=1i",
+				"requests,host=a,request_id=3 value=1i",
+				"requests,host=b,request_id=other value=1i",
+				"requests,host=a,request_id=other value=1i",
+				"requests,host=c,request_id=other value=1i",
+				"other,request_id=4 value=1i",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockOutput{}
+			ro := NewRunningOutput(m, &OutputConfig{
+				Name:              "cardinality_" + tt.name,
+				CardinalityLimit:  3,
+				CardinalityAction: tt.action,
+			}, 10, 100)
+			require.NoError(t, ro.Init())
+
+			// The fourth series exceeds the limit and the tag with most
+			// distinct values is limited for all further metrics
+			input := []telegraf.Metric{
+				testutil.MustMetric("requests", map[string]string{"host": "a", "request_id": "1"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				testutil.MustMetric("requests", map[string]string{"host": "a", "request_id": "2"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				testutil.MustMetric("requests", map[string]string{"host": "a", "request_id": "3"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				testutil.MustMetric("requests", map[string]string{"host": "b", "request_id": "4"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				testutil.MustMetric("requests", map[string]string{"host": "a", "request_id": "5"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				testutil.MustMetric("requests", map[string]string{"host": "c", "request_id": "6"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				// Other measurements are tracked independently
+				testutil.MustMetric("other", map[string]string{"request_id": "4"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+			}
+			for _, metric := range input {
+				ro.AddMetric(metric)
+			}
+			require.NoError(t, ro.Write())
+
+			actual := make([]string, 0, len(m.Metrics()))
+			for _, metric := range m.Metrics() {
+				actual = append(actual, metricString(metric))
+			}
+			require.Equal(t, tt.expected, actual)
+
+			// Statistics of the offending tag
+			offender := ro.guard.measurements["requests"].offenders["request_id"]
+			require.NotNil(t, offender)
+			require.Equal(t, int64(3), offender.distinctStat.Get())
+			require.Equal(t, int64(3), offender.modifiedStat.Get())
+			require.NotContains(t, ro.guard.measurements["requests"].offenders, "host")
+		})
+	}
+}
+
+func TestRunningOutputCardinalityGuardExpiry(t *testing.T) {
+	ro := NewRunningOutput(&mockOutput{}, &OutputConfig{
+		Name:              "cardinality_expiry",
+		CardinalityLimit:  1,
+		CardinalityWindow: 50 * time.Millisecond,
+	}, 10, 100)
+	require.NoError(t, ro.Init())
+	ro.guard.log = testutil.Logger{}
+
+	ro.AddMetric(testutil.MustMetric("test", map[string]string{"id": "1"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)))
+	ro.AddMetric(testutil.MustMetric("test", map[string]string{"id": "2"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)))
+	require.Contains(t, ro.guard.measurements["test"].offenders, "id")
+
+	// Series and offenders not seen within the window are forgotten
+	time.Sleep(60 * time.Millisecond)
+	ro.AddMetric(testutil.MustMetric("test", map[string]string{"id": "3"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)))
+	require.Empty(t, ro.guard.measurements["test"].offenders)
+	require.Len(t, ro.guard.measurements["test"].series, 1)
+}
+
+func TestRunningOutputCardinalityGuardInvalid(t *testing.T) {
+	ro := NewRunningOutput(&mockOutput{}, &OutputConfig{
+		CardinalityLimit:  10,
+		CardinalityAction: "foo",
+	}, 4, 12)
+	require.ErrorContains(t, ro.Init(), `invalid 'cardinality_action' setting "foo"`)
+}
+
+func metricString(m telegraf.Metric) string {
+	s := m.Name()
+	for _, tag := range m.TagList() {
+		s += "," + tag.Key + "=" + tag.Value
+	}
+	for i, field := range m.FieldList() {
+		sep := " "
+		if i > 0 {
+			sep = ","
+		}
+		s += fmt.Sprintf("%s%s=%vi", sep, field.Key, field.Value)
+	}
+	return s
+}
+
 // Verify that the order of points is preserved during write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
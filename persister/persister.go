@@ -2,17 +2,33 @@ package persister
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 
 	"github.com/influxdata/telegraf"
 )
 
+// stateVersion is the version of the state-file format written. Increment the
+// version on incompatible changes and add a migration of older versions to
+// Load.
+const stateVersion = 1
+
+// stateFile is the content of the state-file
+type stateFile struct {
+	Version int               `json:"version"`
+	States  map[string][]byte `json:"states"`
+}
+
 type Persister struct {
 	Filename string
 
 	register map[string]telegraf.StatefulPlugin
+	sync.Mutex
 }
 
 func (p *Persister) Init() error {
@@ -22,6 +38,9 @@ func (p *Persister) Init() error {
 }
 
 func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
+	p.Lock()
+	defer p.Unlock()
+
 	if _, found := p.register[id]; found {
 		return fmt.Errorf("plugin with ID %q already registered", id)
 	}
@@ -30,6 +49,13 @@ func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
 	return nil
 }
 
+func (p *Persister) Unregister(id string) {
+	p.Lock()
+	defer p.Unlock()
+
+	delete(p.register, id)
+}
+
 func (p *Persister) Load() error {
 	// Read the states from disk
 	in, err := os.ReadFile(p.Filename)
@@ -37,12 +63,14 @@ func (p *Persister) Load() error {
 		return fmt.Errorf("reading states file failed: %w", err)
 	}
 
-	// Unmarshal the id to serialized states map
-	var states map[string][]byte
-	if err := json.Unmarshal(in, &states); err != nil {
-		return fmt.Errorf("unmarshalling states failed: %w", err)
+	states, err := decode(in)
+	if err != nil {
+		return err
 	}
 
+	p.Lock()
+	defer p.Unlock()
+
 	// Get the initialized state as blueprint for unmarshalling
 	for id, serialized := range states {
 		// Check if we have a plugin with that ID
@@ -69,7 +97,39 @@ func (p *Persister) Load() error {
 	return nil
 }
 
+// decode returns the id to serialized states map of the given state-file
+// content, migrating older versions of the format if necessary.
+func decode(in []byte) (map[string][]byte, error) {
+	var content map[string]json.RawMessage
+	if err := json.Unmarshal(in, &content); err != nil {
+		return nil, fmt.Errorf("unmarshalling states failed: %w", err)
+	}
+
+	// Files without version were written before the introduction of the
+	// version header and only contain the id to serialized states map.
+	if _, found := content["version"]; !found {
+		var states map[string][]byte
+		if err := json.Unmarshal(in, &states); err != nil {
+			return nil, fmt.Errorf("unmarshalling states failed: %w", err)
+		}
+		return states, nil
+	}
+
+	var file stateFile
+	if err := json.Unmarshal(in, &file); err != nil {
+		return nil, fmt.Errorf("unmarshalling states failed: %w", err)
+	}
+	if file.Version > stateVersion {
+		log.Printf("W! [persister] Ignoring states of unsupported version %d, expected version %d or lower", file.Version, stateVersion)
+		return nil, nil
+	}
+	return file.States, nil
+}
+
 func (p *Persister) Store() error {
+	p.Lock()
+	defer p.Unlock()
+
 	states := make(map[string][]byte)
 
 	// Collect the states and serialize the individual data chunks
@@ -83,20 +143,31 @@ func (p *Persister) Store() error {
 	}
 
 	// Serialize the states
-	serialized, err := json.Marshal(states)
+	serialized, err := json.Marshal(stateFile{Version: stateVersion, States: states})
 	if err != nil {
 		return fmt.Errorf("marshalling states failed: %w", err)
 	}
 
-	// Write the states to disk
-	f, err := os.Create(p.Filename)
+	// Write the states to a temporary file and replace the state-file
+	// afterwards to never leave a partially written file behind.
+	f, err := os.CreateTemp(filepath.Dir(p.Filename), filepath.Base(p.Filename)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("creating states file %q failed: %w", p.Filename, err)
+		return fmt.Errorf("creating temporary states file for %q failed: %w", p.Filename, err)
 	}
-	defer f.Close()
+	tmpfile := f.Name()
 
-	if _, err := f.Write(serialized); err != nil {
-		return fmt.Errorf("writing states failed: %w", err)
+	_, err = f.Write(serialized)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpfile, p.Filename)
+	}
+	if err != nil {
+		return errors.Join(fmt.Errorf("writing states failed: %w", err), os.Remove(tmpfile))
 	}
 
 	return nil
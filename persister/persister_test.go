@@ -0,0 +1,68 @@
+package persister
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreLoadRoundtrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "states.json")
+
+	p := &Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("id1", &mockPlugin{state: "foo"}))
+	require.NoError(t, p.Store())
+
+	// Storing again must replace the file without leaving temporary files
+	require.NoError(t, p.Store())
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	plugin := &mockPlugin{}
+	p = &Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("id1", plugin))
+	require.NoError(t, p.Load())
+	require.Equal(t, "foo", plugin.state)
+}
+
+func TestLoadUnversioned(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "states.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"id1":"ImZvbyI="}`), 0640))
+
+	plugin := &mockPlugin{}
+	p := &Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("id1", plugin))
+	require.NoError(t, p.Load())
+	require.Equal(t, "foo", plugin.state)
+}
+
+func TestLoadUnsupportedVersion(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "states.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"version":999,"states":{"id1":"ImZvbyI="}}`), 0640))
+
+	plugin := &mockPlugin{state: "bar"}
+	p := &Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("id1", plugin))
+	require.NoError(t, p.Load())
+	require.Equal(t, "bar", plugin.state)
+}
+
+type mockPlugin struct {
+	state string
+}
+
+func (m *mockPlugin) GetState() interface{} {
+	return m.state
+}
+
+func (m *mockPlugin) SetState(state interface{}) error {
+	m.state = state.(string)
+	return nil
+}
@@ -0,0 +1,94 @@
+package pulsar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common_tls "github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// ClientConfig contains the settings shared by all Pulsar plugins to connect
+// to the broker
+type ClientConfig struct {
+	URL               string          `toml:"url"`
+	Token             config.Secret   `toml:"token"`
+	Username          config.Secret   `toml:"username"`
+	Password          config.Secret   `toml:"password"`
+	ConnectionTimeout config.Duration `toml:"connection_timeout"`
+	OperationTimeout  config.Duration `toml:"operation_timeout"`
+	common_tls.ClientConfig
+}
+
+// Validate checks the client settings
+func (c *ClientConfig) Validate() error {
+	if c.URL == "" {
+		return errors.New("'url' required")
+	}
+	if !c.Token.Empty() && (!c.Username.Empty() || !c.Password.Empty()) {
+		return errors.New("either use 'token' or 'username' and 'password'")
+	}
+	if c.ConnectionTimeout < 0 {
+		return errors.New("'connection_timeout' must not be negative")
+	}
+	if c.OperationTimeout < 0 {
+		return errors.New("'operation_timeout' must not be negative")
+	}
+	return nil
+}
+
+// NewClient creates a new Pulsar client using the given logger for the
+// library messages
+func (c *ClientConfig) NewClient(log telegraf.Logger) (pulsar.Client, error) {
+	tlsCfg, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("creating TLS config failed: %w", err)
+	}
+
+	opts := pulsar.ClientOptions{
+		URL:               c.URL,
+		ConnectionTimeout: time.Duration(c.ConnectionTimeout),
+		OperationTimeout:  time.Duration(c.OperationTimeout),
+		TLSConfig:         tlsCfg,
+		Logger:            &logger{log: log},
+	}
+	if tlsCfg != nil {
+		opts.TLSAllowInsecureConnection = tlsCfg.InsecureSkipVerify
+	}
+
+	switch {
+	case !c.Token.Empty():
+		// Resolve the token on each authentication so rotated secrets are used
+		opts.Authentication = pulsar.NewAuthenticationTokenFromSupplier(func() (string, error) {
+			token, err := c.Token.Get()
+			if err != nil {
+				return "", fmt.Errorf("getting token failed: %w", err)
+			}
+			defer token.Destroy()
+			return token.String(), nil
+		})
+	case !c.Username.Empty() || !c.Password.Empty():
+		username, err := c.Username.Get()
+		if err != nil {
+			return nil, fmt.Errorf("getting username failed: %w", err)
+		}
+		defer username.Destroy()
+		password, err := c.Password.Get()
+		if err != nil {
+			return nil, fmt.Errorf("getting password failed: %w", err)
+		}
+		defer password.Destroy()
+
+		auth, err := pulsar.NewAuthenticationBasic(username.String(), password.String())
+		if err != nil {
+			return nil, fmt.Errorf("creating authentication failed: %w", err)
+		}
+		opts.Authentication = auth
+	}
+
+	return pulsar.NewClient(opts)
+}
@@ -0,0 +1,89 @@
+package pulsar
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// logger forwards the messages of the Pulsar library to the plugin logger.
+// The library is very chatty on info level, so those messages are logged as
+// debug messages and debug messages are logged on trace level.
+type logger struct {
+	log    telegraf.Logger
+	fields log.Fields
+}
+
+func (l *logger) SubLogger(fields log.Fields) log.Logger {
+	return l.with(fields)
+}
+
+func (l *logger) WithFields(fields log.Fields) log.Entry {
+	return l.with(fields)
+}
+
+func (l *logger) WithField(name string, value interface{}) log.Entry {
+	return l.with(log.Fields{name: value})
+}
+
+func (l *logger) WithError(err error) log.Entry {
+	return l.with(log.Fields{"error": err})
+}
+
+func (l *logger) Debug(args ...interface{}) {
+	l.log.Trace(l.message(fmt.Sprint(args...)))
+}
+
+func (l *logger) Info(args ...interface{}) {
+	l.log.Debug(l.message(fmt.Sprint(args...)))
+}
+
+func (l *logger) Warn(args ...interface{}) {
+	l.log.Warn(l.message(fmt.Sprint(args...)))
+}
+
+func (l *logger) Error(args ...interface{}) {
+	l.log.Error(l.message(fmt.Sprint(args...)))
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.log.Trace(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.log.Debug(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	l.log.Warn(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.log.Error(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *logger) with(fields log.Fields) *logger {
+	merged := make(log.Fields, len(l.fields)+len(fields))
+	maps.Copy(merged, l.fields)
+	maps.Copy(merged, fields)
+	return &logger{log: l.log, fields: merged}
+}
+
+// message appends the fields in a stable order to the message
+func (l *logger) message(msg string) string {
+	if len(l.fields) == 0 {
+		return msg
+	}
+
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for _, k := range slices.Sorted(maps.Keys(l.fields)) {
+		fmt.Fprintf(&sb, " %s=%v", k, l.fields[k])
+	}
+	return sb.String()
+}
@@ -0,0 +1,29 @@
+package pulsar
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestLogger(t *testing.T) {
+	var log testutil.CaptureLogger
+	l := &logger{log: &log}
+
+	l.Info("connecting")
+	sub := l.SubLogger(map[string]interface{}{"topic": "telegraf"})
+	sub.WithField("producer", "p1").Debugf("sent %d messages", 5)
+	sub.WithError(errors.New("timeout")).Warn("reconnecting")
+	l.Errorf("closing %s", "client")
+
+	expected := []testutil.Entry{
+		{Level: testutil.LevelDebug, Text: "connecting"},
+		{Level: testutil.LevelTrace, Text: "sent 5 messages producer=p1 topic=telegraf"},
+		{Level: testutil.LevelWarn, Text: "reconnecting error=timeout topic=telegraf"},
+		{Level: testutil.LevelError, Text: "closing client"},
+	}
+	require.Equal(t, expected, log.Messages())
+}
@@ -59,6 +59,7 @@ type CloudWatch struct {
 	queryDimensions map[string]*map[string]string
 	windowStart     time.Time
 	windowEnd       time.Time
+	windowLock      sync.Mutex
 }
 
 type cloudwatchMetric struct {
@@ -96,6 +97,27 @@ func (*CloudWatch) SampleConfig() string {
 	return sampleConfig
 }
 
+// GetState returns the end of the last query window to continue from there
+// after a restart without missing or duplicating data.
+func (c *CloudWatch) GetState() interface{} {
+	c.windowLock.Lock()
+	defer c.windowLock.Unlock()
+
+	return c.windowEnd
+}
+
+func (c *CloudWatch) SetState(state interface{}) error {
+	windowEnd, ok := state.(time.Time)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	c.windowLock.Lock()
+	defer c.windowLock.Unlock()
+	c.windowEnd = windowEnd
+	return nil
+}
+
 func (c *CloudWatch) Init() error {
 	// For backward compatibility
 	if len(c.Namespace) != 0 {
@@ -329,6 +351,9 @@ func (c *CloudWatch) getFilteredMetrics() ([]filteredMetric, error) {
 }
 
 func (c *CloudWatch) updateWindow(relativeTo time.Time) {
+	c.windowLock.Lock()
+	defer c.windowLock.Unlock()
+
 	windowEnd := relativeTo.Add(-time.Duration(c.Delay))
 
 	if c.windowEnd.IsZero() {
@@ -664,3 +664,31 @@ func (*mockClient) GetMetricData(
 		},
 	}, nil
 }
+
+func TestUpdateWindowWithState(t *testing.T) {
+	plugin := &CloudWatch{
+		Namespace: "AWS/ELB",
+		Delay:     config.Duration(1 * time.Minute),
+		Period:    config.Duration(1 * time.Minute),
+		BatchSize: 500,
+		Log:       testutil.Logger{},
+	}
+
+	now := time.Now()
+	plugin.updateWindow(now.Add(-10 * time.Minute))
+	state := plugin.GetState()
+
+	// restored window continues where the last window before the restart left off
+	plugin = &CloudWatch{
+		Namespace: "AWS/ELB",
+		Delay:     config.Duration(1 * time.Minute),
+		Period:    config.Duration(1 * time.Minute),
+		BatchSize: 500,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, plugin.SetState(state))
+	plugin.updateWindow(now)
+
+	require.EqualValues(t, now.Add(-11*time.Minute), plugin.windowStart)
+	require.EqualValues(t, now.Add(-time.Duration(plugin.Delay)), plugin.windowEnd)
+}
@@ -70,6 +70,29 @@ type DirectoryMonitor struct {
 	fileRegexesToMatch  []*regexp.Regexp
 	fileRegexesToIgnore []*regexp.Regexp
 	filesToProcess      chan string
+
+	// Delivery progress of partially processed files, the position of queued
+	// metrics and the number of metrics to skip for the file currently processed
+	offsets    map[string]*fileOffset
+	tracking   map[telegraf.TrackingID]trackedMetric
+	early      map[telegraf.TrackingID]bool
+	offsetsMtx sync.Mutex
+	skip       uint64
+}
+
+// fileOffset tracks the delivery of the metrics of a file. The offset is the
+// number of metrics delivered in order and is persisted to skip those metrics
+// after a restart. Metrics delivered out of order are kept until all previous
+// metrics are delivered.
+type fileOffset struct {
+	offset uint64
+	next   uint64
+	done   map[uint64]bool
+}
+
+type trackedMetric struct {
+	file string
+	seq  uint64
 }
 
 func (*DirectoryMonitor) SampleConfig() string {
@@ -80,6 +103,31 @@ func (monitor *DirectoryMonitor) SetParserFunc(fn telegraf.ParserFunc) {
 	monitor.parserFunc = fn
 }
 
+func (monitor *DirectoryMonitor) GetState() interface{} {
+	monitor.offsetsMtx.Lock()
+	defer monitor.offsetsMtx.Unlock()
+
+	offsets := make(map[string]uint64, len(monitor.offsets))
+	for k, v := range monitor.offsets {
+		offsets[k] = v.offset
+	}
+	return offsets
+}
+
+func (monitor *DirectoryMonitor) SetState(state interface{}) error {
+	offsets, ok := state.(map[string]uint64)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	monitor.offsetsMtx.Lock()
+	defer monitor.offsetsMtx.Unlock()
+	for k, v := range offsets {
+		monitor.offsets[k] = &fileOffset{offset: v, next: v, done: make(map[uint64]bool)}
+	}
+	return nil
+}
+
 func (monitor *DirectoryMonitor) Init() error {
 	if monitor.Directory == "" || monitor.FinishedDirectory == "" {
 		return errors.New("missing one of the following required config options: directory, finished_directory")
@@ -120,6 +168,9 @@ func (monitor *DirectoryMonitor) Init() error {
 	monitor.sem = semaphore.NewWeighted(int64(monitor.MaxBufferedMetrics))
 	monitor.context, monitor.cancel = context.WithCancel(context.Background())
 	monitor.filesToProcess = make(chan string, monitor.FileQueueSize)
+	monitor.offsets = make(map[string]*fileOffset)
+	monitor.tracking = make(map[telegraf.TrackingID]trackedMetric)
+	monitor.early = make(map[telegraf.TrackingID]bool)
 
 	// Establish file matching / exclusion regexes.
 	for _, matcher := range monitor.FilesToMonitor {
@@ -149,7 +200,8 @@ func (monitor *DirectoryMonitor) Start(acc telegraf.Accumulator) error {
 	// Use tracking to determine when more metrics can be added without overflowing the outputs.
 	monitor.acc = acc.WithTracking(monitor.MaxBufferedMetrics)
 	go func() {
-		for range monitor.acc.Delivered() {
+		for info := range monitor.acc.Delivered() {
+			monitor.onDelivery(info)
 			monitor.sem.Release(1)
 		}
 	}()
@@ -275,6 +327,16 @@ func (monitor *DirectoryMonitor) processFile(path string) {
 func (monitor *DirectoryMonitor) read(filePath string) {
 	// Open, read, and parse the contents of the file.
 	err := monitor.ingestFile(filePath)
+
+	// Keep the file if we've been cancelled via Stop() to continue after
+	// the already sent metrics on the next start.
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	monitor.offsetsMtx.Lock()
+	delete(monitor.offsets, filePath)
+	monitor.offsetsMtx.Unlock()
+
 	var pathErr *os.PathError
 	if errors.As(err, &pathErr) {
 		return
@@ -309,6 +371,19 @@ func (monitor *DirectoryMonitor) ingestFile(filePath string) error {
 		return fmt.Errorf("creating parser: %w", err)
 	}
 
+	// Continue after the metrics delivered before a restart, metrics queued
+	// but not delivered are sent again
+	monitor.offsetsMtx.Lock()
+	offset, found := monitor.offsets[filePath]
+	if !found {
+		offset = &fileOffset{done: make(map[uint64]bool)}
+		monitor.offsets[filePath] = offset
+	}
+	offset.next = offset.offset
+	clear(offset.done)
+	monitor.skip = offset.offset
+	monitor.offsetsMtx.Unlock()
+
 	// Handle gzipped files.
 	var reader io.Reader
 	if filepath.Ext(filePath) == ".gz" {
@@ -345,7 +420,7 @@ func (monitor *DirectoryMonitor) parseFile(parser telegraf.Parser, reader io.Rea
 			return err
 		}
 
-		if err := monitor.sendMetrics(metrics); err != nil {
+		if err := monitor.sendMetrics(metrics, fileName); err != nil {
 			return err
 		}
 	}
@@ -364,7 +439,7 @@ func (monitor *DirectoryMonitor) parseAtOnce(parser telegraf.Parser, reader io.R
 		return err
 	}
 
-	return monitor.sendMetrics(metrics)
+	return monitor.sendMetrics(metrics, fileName)
 }
 
 func (monitor *DirectoryMonitor) parseMetrics(parser telegraf.Parser, line []byte, fileName string) (metrics []telegraf.Metric, err error) {
@@ -391,18 +466,71 @@ func (monitor *DirectoryMonitor) parseMetrics(parser telegraf.Parser, line []byt
 	return metrics, err
 }
 
-func (monitor *DirectoryMonitor) sendMetrics(metrics []telegraf.Metric) error {
+func (monitor *DirectoryMonitor) sendMetrics(metrics []telegraf.Metric, fileName string) error {
 	// Report the metrics for the file.
 	for _, m := range metrics {
+		// Skip the metrics already sent before a restart.
+		if monitor.skip > 0 {
+			monitor.skip--
+			continue
+		}
+
 		// Block until metric can be written.
 		if err := monitor.sem.Acquire(monitor.context, 1); err != nil {
 			return err
 		}
-		monitor.acc.AddTrackingMetricGroup([]telegraf.Metric{m})
+		monitor.offsetsMtx.Lock()
+		offset := monitor.offsets[fileName]
+		tracked := trackedMetric{file: fileName, seq: offset.next}
+		offset.next++
+		monitor.offsetsMtx.Unlock()
+
+		id := monitor.acc.AddTrackingMetricGroup([]telegraf.Metric{m})
+
+		// The metric might have been delivered before we register it
+		monitor.offsetsMtx.Lock()
+		if monitor.early[id] {
+			delete(monitor.early, id)
+			monitor.resolve(tracked)
+		} else {
+			monitor.tracking[id] = tracked
+		}
+		monitor.offsetsMtx.Unlock()
 	}
 	return nil
 }
 
+// onDelivery advances the offset of the file over all metrics delivered in
+// order. The delivery is resolved for rejected metrics as well as those are
+// dropped by the output and not retried.
+func (monitor *DirectoryMonitor) onDelivery(info telegraf.DeliveryInfo) {
+	monitor.offsetsMtx.Lock()
+	defer monitor.offsetsMtx.Unlock()
+
+	tracked, found := monitor.tracking[info.ID()]
+	if !found {
+		monitor.early[info.ID()] = true
+		return
+	}
+	delete(monitor.tracking, info.ID())
+	monitor.resolve(tracked)
+}
+
+// resolve marks the metric as delivered and advances the offset of the file.
+// The caller must hold the offsets lock.
+func (monitor *DirectoryMonitor) resolve(tracked trackedMetric) {
+	// The file might have been finished in the meantime
+	offset, found := monitor.offsets[tracked.file]
+	if !found || tracked.seq < offset.offset {
+		return
+	}
+	offset.done[tracked.seq] = true
+	for offset.done[offset.offset] {
+		delete(offset.done, offset.offset)
+		offset.offset++
+	}
+}
+
 func (monitor *DirectoryMonitor) moveFile(srcPath, dstBaseDir string) {
 	// Appends any subdirectories in the srcPath to the dstBaseDir and
 	// creates those subdirectories.
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -680,3 +681,125 @@ func TestParseSubdirectoriesFilesIgnore(t *testing.T) {
 	_, err = os.Stat(filepath.Join(finishedDirectory, testJSONFile))
 	require.NoError(t, err)
 }
+
+func TestStateResume(t *testing.T) {
+	acc := testutil.Accumulator{}
+
+	// Establish process directory and finished directory.
+	finishedDirectory := t.TempDir()
+	processDirectory := t.TempDir()
+
+	// Init plugin.
+	r := DirectoryMonitor{
+		Directory:          processDirectory,
+		FinishedDirectory:  finishedDirectory,
+		MaxBufferedMetrics: defaultMaxBufferedMetrics,
+		FileQueueSize:      defaultFileQueueSize,
+		ParseMethod:        defaultParseMethod,
+		Log:                testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+	r.SetParserFunc(func() (telegraf.Parser, error) {
+		p := &json.Parser{NameKey: "Name"}
+		err := p.Init()
+		return p, err
+	})
+
+	// Write a file with three metrics already sent before the restart.
+	filename := processDirectory + "/test.json"
+	require.NoError(t, os.WriteFile(filename, []byte(
+		"{\"Name\": \"event1\",\"Speed\": 100.1}\n{\"Name\": \"event2\",\"Speed\": 500}\n"+
+			"{\"Name\": \"event3\",\"Speed\": 200}\n{\"Name\": \"event4\",\"Speed\": 80}\n"+
+			"{\"Name\": \"event5\",\"Speed\": 120.77}",
+	), 0640))
+	require.NoError(t, r.SetState(map[string]uint64{filename: 3}))
+
+	require.NoError(t, r.Start(&acc))
+	require.NoError(t, r.Gather(&acc))
+	acc.Wait(2)
+	r.Stop()
+
+	// Verify that only the remaining metrics are sent and the file is
+	// removed from the state.
+	require.Len(t, acc.Metrics, 2)
+	require.Equal(t, "event4", acc.Metrics[0].Measurement)
+	require.Equal(t, "event5", acc.Metrics[1].Measurement)
+	require.Empty(t, r.GetState())
+}
+
+func TestStateUndeliveredMetrics(t *testing.T) {
+	// Establish process directory and finished directory.
+	finishedDirectory := t.TempDir()
+	processDirectory := t.TempDir()
+	parserFunc := func() (telegraf.Parser, error) {
+		p := &json.Parser{NameKey: "Name"}
+		err := p.Init()
+		return p, err
+	}
+
+	// Init plugin with a buffer smaller than the number of metrics in the file
+	// to interrupt the processing.
+	r := DirectoryMonitor{
+		Directory:          processDirectory,
+		FinishedDirectory:  finishedDirectory,
+		MaxBufferedMetrics: 2,
+		FileQueueSize:      defaultFileQueueSize,
+		ParseMethod:        defaultParseMethod,
+		Log:                testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+	r.SetParserFunc(parserFunc)
+
+	filename := processDirectory + "/test.json"
+	require.NoError(t, os.WriteFile(filename, []byte(
+		"{\"Name\": \"event1\",\"Speed\": 100.1}\n{\"Name\": \"event2\",\"Speed\": 500}\n"+
+			"{\"Name\": \"event3\",\"Speed\": 200}\n{\"Name\": \"event4\",\"Speed\": 80}\n"+
+			"{\"Name\": \"event5\",\"Speed\": 120.77}",
+	), 0640))
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Start(&acc))
+	require.NoError(t, r.Gather(&acc))
+	acc.Wait(2)
+
+	// Deliver the second metric before the first one
+	acc.GetTelegrafMetrics()[1].Accept()
+	acc.Wait(3)
+	r.Stop()
+
+	// The file must be kept and the offset must not include the metrics
+	// queued but not delivered.
+	require.FileExists(t, filename)
+	require.Equal(t, map[string]uint64{filename: 0}, r.GetState())
+
+	// Delivering the first metric advances the offset over both metrics
+	acc.GetTelegrafMetrics()[0].Accept()
+	require.Eventually(t, func() bool {
+		return r.GetState().(map[string]uint64)[filename] == 2
+	}, 3*time.Second, 100*time.Millisecond)
+	state := r.GetState()
+
+	// Restart and verify that the undelivered metrics are sent again
+	var accRestart testutil.Accumulator
+	restarted := DirectoryMonitor{
+		Directory:          processDirectory,
+		FinishedDirectory:  finishedDirectory,
+		MaxBufferedMetrics: defaultMaxBufferedMetrics,
+		FileQueueSize:      defaultFileQueueSize,
+		ParseMethod:        defaultParseMethod,
+		Log:                testutil.Logger{},
+	}
+	require.NoError(t, restarted.Init())
+	restarted.SetParserFunc(parserFunc)
+	require.NoError(t, restarted.SetState(state))
+	require.NoError(t, restarted.Start(&accRestart))
+	require.NoError(t, restarted.Gather(&accRestart))
+	accRestart.Wait(3)
+	restarted.Stop()
+
+	require.Len(t, accRestart.Metrics, 3)
+	require.Equal(t, "event3", accRestart.Metrics[0].Measurement)
+	require.Equal(t, "event4", accRestart.Metrics[1].Measurement)
+	require.Equal(t, "event5", accRestart.Metrics[2].Measurement)
+	require.Empty(t, restarted.GetState())
+}
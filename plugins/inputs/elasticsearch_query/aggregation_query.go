@@ -25,7 +25,7 @@ type aggregationQueryData struct {
 
 func (e *ElasticsearchQuery) runAggregationQuery(ctx context.Context, aggregation esAggregation) (*elastic5.SearchResult, error) {
 	now := time.Now().UTC()
-	from := now.Add(time.Duration(-aggregation.QueryPeriod))
+	from := e.queryStart(aggregation, now)
 	filterQuery := aggregation.FilterQuery
 	if filterQuery == "" {
 		filterQuery = "*"
@@ -58,10 +58,35 @@ func (e *ElasticsearchQuery) runAggregationQuery(ctx context.Context, aggregatio
 	if err != nil && searchResult != nil {
 		return searchResult, fmt.Errorf("%s - %s", searchResult.Error.Type, searchResult.Error.Reason)
 	}
+	if err == nil && aggregation.QuerySinceLast {
+		e.lastQueryMtx.Lock()
+		e.lastQuery[aggregation.key()] = now
+		e.lastQueryMtx.Unlock()
+	}
 
 	return searchResult, err
 }
 
+// queryStart returns the start of the time window to query. By default, the
+// window covers the query period, for queries since the last query it starts
+// at the end of the last successful query if any.
+func (e *ElasticsearchQuery) queryStart(aggregation esAggregation, now time.Time) time.Time {
+	if aggregation.QuerySinceLast {
+		e.lastQueryMtx.Lock()
+		last, found := e.lastQuery[aggregation.key()]
+		e.lastQueryMtx.Unlock()
+		if found && last.Before(now) {
+			return last
+		}
+	}
+	return now.Add(time.Duration(-aggregation.QueryPeriod))
+}
+
+// key identifies the aggregation in the state
+func (aggregation *esAggregation) key() string {
+	return aggregation.Index + "/" + aggregation.MeasurementName
+}
+
 // getMetricFields function returns a map of fields and field types on Elasticsearch that matches field.MetricFields
 func (e *ElasticsearchQuery) getMetricFields(ctx context.Context, aggregation esAggregation) (map[string]string, error) {
 	mapMetricFields := make(map[string]string)
@@ -37,6 +37,10 @@ type ElasticsearchQuery struct {
 	common_http.HTTPClientConfig
 
 	esClient *elastic5.Client
+
+	// End of the last successful query per aggregation
+	lastQuery    map[string]time.Time
+	lastQueryMtx sync.Mutex
 }
 
 type esAggregation struct {
@@ -45,6 +49,7 @@ type esAggregation struct {
 	DateField            string          `toml:"date_field"`
 	DateFieldFormat      string          `toml:"date_field_custom_format"`
 	QueryPeriod          config.Duration `toml:"query_period"`
+	QuerySinceLast       bool            `toml:"query_since_last"`
 	FilterQuery          string          `toml:"filter_query"`
 	MetricFields         []string        `toml:"metric_fields"`
 	MetricFunction       string          `toml:"metric_function"`
@@ -59,10 +64,36 @@ func (*ElasticsearchQuery) SampleConfig() string {
 	return sampleConfig
 }
 
+func (e *ElasticsearchQuery) GetState() interface{} {
+	e.lastQueryMtx.Lock()
+	defer e.lastQueryMtx.Unlock()
+
+	lastQuery := make(map[string]time.Time, len(e.lastQuery))
+	for k, v := range e.lastQuery {
+		lastQuery[k] = v
+	}
+	return lastQuery
+}
+
+func (e *ElasticsearchQuery) SetState(state interface{}) error {
+	lastQuery, ok := state.(map[string]time.Time)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	e.lastQueryMtx.Lock()
+	defer e.lastQueryMtx.Unlock()
+	for k, v := range lastQuery {
+		e.lastQuery[k] = v
+	}
+	return nil
+}
+
 func (e *ElasticsearchQuery) Init() error {
 	if e.URLs == nil {
 		return errors.New("elasticsearch urls is not defined")
 	}
+	e.lastQuery = make(map[string]time.Time)
 
 	err := e.connectToES()
 	if err != nil {
@@ -758,3 +758,26 @@ func TestElasticsearchQuery_buildAggregationQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestElasticsearchQuery_queryStartWithState(t *testing.T) {
+	now := time.Now().UTC()
+	last := now.Add(-10 * time.Minute)
+
+	aggregation := esAggregation{
+		Index:           testindex,
+		MeasurementName: "measurement",
+		QueryPeriod:     config.Duration(time.Minute),
+		QuerySinceLast:  true,
+	}
+
+	plugin := &ElasticsearchQuery{lastQuery: make(map[string]time.Time)}
+	require.Equal(t, now.Add(-time.Minute), plugin.queryStart(aggregation, now))
+
+	// Continue from the restored end of the last query
+	require.NoError(t, plugin.SetState(map[string]time.Time{aggregation.key(): last}))
+	require.Equal(t, last, plugin.queryStart(aggregation, now))
+
+	// Queries with fixed period ignore the state
+	aggregation.QuerySinceLast = false
+	require.Equal(t, now.Add(-time.Minute), plugin.queryStart(aggregation, now))
+}
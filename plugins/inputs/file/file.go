@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/dimchansky/utfbom"
 
@@ -24,15 +25,25 @@ var sampleConfig string
 var once sync.Once
 
 type File struct {
-	Files             []string        `toml:"files"`
-	FileTag           string          `toml:"file_tag"`
-	FilePathTag       string          `toml:"file_path_tag"`
-	CharacterEncoding string          `toml:"character_encoding"`
-	Log               telegraf.Logger `toml:"-"`
+	Files              []string        `toml:"files"`
+	FileTag            string          `toml:"file_tag"`
+	FilePathTag        string          `toml:"file_path_tag"`
+	CharacterEncoding  string          `toml:"character_encoding"`
+	SkipUnchangedFiles bool            `toml:"skip_unchanged_files"`
+	Log                telegraf.Logger `toml:"-"`
 
 	parserFunc telegraf.ParserFunc
 	filenames  []string
 	decoder    *encoding.Decoder
+
+	processed    map[string]fileInfo
+	processedMtx sync.Mutex
+}
+
+// fileInfo identifies the version of a file processed last
+type fileInfo struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
 }
 
 func (*File) SampleConfig() string {
@@ -40,11 +51,38 @@ func (*File) SampleConfig() string {
 }
 
 func (f *File) Init() error {
+	f.processed = make(map[string]fileInfo)
+
 	var err error
 	f.decoder, err = encoding.NewDecoder(f.CharacterEncoding)
 	return err
 }
 
+func (f *File) GetState() interface{} {
+	f.processedMtx.Lock()
+	defer f.processedMtx.Unlock()
+
+	processed := make(map[string]fileInfo, len(f.processed))
+	for k, v := range f.processed {
+		processed[k] = v
+	}
+	return processed
+}
+
+func (f *File) SetState(state interface{}) error {
+	processed, ok := state.(map[string]fileInfo)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	f.processedMtx.Lock()
+	defer f.processedMtx.Unlock()
+	for k, v := range processed {
+		f.processed[k] = v
+	}
+	return nil
+}
+
 func (f *File) SetParserFunc(fn telegraf.ParserFunc) {
 	f.parserFunc = fn
 }
@@ -55,11 +93,33 @@ func (f *File) Gather(acc telegraf.Accumulator) error {
 		return err
 	}
 	for _, k := range f.filenames {
+		var info fileInfo
+		if f.SkipUnchangedFiles {
+			stat, err := os.Stat(k)
+			if err != nil {
+				return err
+			}
+			info = fileInfo{ModTime: stat.ModTime(), Size: stat.Size()}
+
+			f.processedMtx.Lock()
+			last, found := f.processed[k]
+			f.processedMtx.Unlock()
+			if found && last.ModTime.Equal(info.ModTime) && last.Size == info.Size {
+				continue
+			}
+		}
+
 		metrics, err := f.readMetric(k)
 		if err != nil {
 			return err
 		}
 
+		if f.SkipUnchangedFiles {
+			f.processedMtx.Lock()
+			f.processed[k] = info
+			f.processedMtx.Unlock()
+		}
+
 		for _, m := range metrics {
 			if f.FileTag != "" {
 				m.AddTag(f.FileTag, filepath.Base(k))
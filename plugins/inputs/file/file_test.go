@@ -114,6 +114,46 @@ func TestGrokParser(t *testing.T) {
 	require.Len(t, acc.Metrics, 2)
 }
 
+func TestSkipUnchangedFiles(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "metrics.log")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"value": 1}`), 0640))
+
+	newPlugin := func() *File {
+		r := &File{
+			Files:              []string{filename},
+			SkipUnchangedFiles: true,
+			Log:                testutil.Logger{},
+		}
+		require.NoError(t, r.Init())
+		r.SetParserFunc(func() (telegraf.Parser, error) {
+			p := &json.Parser{MetricName: "file"}
+			err := p.Init()
+			return p, err
+		})
+		return r
+	}
+
+	// Unchanged files are only parsed once
+	var acc testutil.Accumulator
+	r := newPlugin()
+	require.NoError(t, r.Gather(&acc))
+	require.NoError(t, r.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+
+	// The processed files are kept across restarts
+	state := r.GetState()
+	r = newPlugin()
+	require.NoError(t, r.SetState(state))
+	require.NoError(t, r.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+
+	// Modified files are parsed again
+	require.NoError(t, os.WriteFile(filename, []byte(`{"value": 42}`), 0640))
+	require.NoError(t, r.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+	require.InDelta(t, 42.0, acc.Metrics[1].Fields["value"], testutil.DefaultDelta)
+}
+
 func TestCharacterEncoding(t *testing.T) {
 	expected := []telegraf.Metric{
 		testutil.MustMetric("file",
@@ -36,12 +36,52 @@ type GitHub struct {
 	rateLimit       selfstat.Stat
 	rateLimitErrors selfstat.Stat
 	rateRemaining   selfstat.Stat
+
+	repositories    map[string]repositoryState
+	repositoriesMtx sync.Mutex
+}
+
+// repositoryState holds the last repository information received including
+// its ETag for conditional requests
+type repositoryState struct {
+	ETag       string             `json:"etag"`
+	Repository *github.Repository `json:"repository"`
 }
 
 func (*GitHub) SampleConfig() string {
 	return sampleConfig
 }
 
+func (g *GitHub) Init() error {
+	g.repositories = make(map[string]repositoryState)
+	return nil
+}
+
+func (g *GitHub) GetState() interface{} {
+	g.repositoriesMtx.Lock()
+	defer g.repositoriesMtx.Unlock()
+
+	repositories := make(map[string]repositoryState, len(g.repositories))
+	for k, v := range g.repositories {
+		repositories[k] = v
+	}
+	return repositories
+}
+
+func (g *GitHub) SetState(state interface{}) error {
+	repositories, ok := state.(map[string]repositoryState)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	g.repositoriesMtx.Lock()
+	defer g.repositoriesMtx.Unlock()
+	for k, v := range repositories {
+		g.repositories[k] = v
+	}
+	return nil
+}
+
 // Gather GitHub Metrics
 func (g *GitHub) Gather(acc telegraf.Accumulator) error {
 	ctx := context.Background()
@@ -76,7 +116,7 @@ func (g *GitHub) Gather(acc telegraf.Accumulator) error {
 				return
 			}
 
-			repositoryInfo, response, err := g.githubClient.Repositories.Get(ctx, owner, repository)
+			repositoryInfo, response, err := g.getRepository(ctx, owner, repository)
 			g.handleRateLimit(response, err)
 			if err != nil {
 				acc.AddError(err)
@@ -156,6 +196,42 @@ func (g *GitHub) handleRateLimit(response *github.Response, err error) {
 	}
 }
 
+// getRepository queries the repository information using a conditional request
+// if the information was received before. Unchanged information is not counted
+// against the rate-limit by GitHub.
+func (g *GitHub) getRepository(ctx context.Context, owner, repository string) (*github.Repository, *github.Response, error) {
+	key := owner + "/" + repository
+	g.repositoriesMtx.Lock()
+	cached, found := g.repositories[key]
+	g.repositoriesMtx.Unlock()
+
+	req, err := g.githubClient.NewRequest(http.MethodGet, fmt.Sprintf("repos/%v/%v", owner, repository), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if found && cached.ETag != "" && cached.Repository != nil {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	repositoryInfo := &github.Repository{}
+	response, err := g.githubClient.Do(ctx, req, repositoryInfo)
+	if response != nil && response.StatusCode == http.StatusNotModified {
+		return cached.Repository, response, nil
+	}
+	if err != nil {
+		return nil, response, err
+	}
+
+	g.repositoriesMtx.Lock()
+	g.repositories[key] = repositoryState{
+		ETag:       response.Header.Get("ETag"),
+		Repository: repositoryInfo,
+	}
+	g.repositoriesMtx.Unlock()
+
+	return repositoryInfo, response, nil
+}
+
 func splitRepositoryName(repositoryName string) (owner, repository string, err error) {
 	splits := strings.SplitN(repositoryName, "/", 2)
 
@@ -2,10 +2,13 @@ package github
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	gh "github.com/google/go-github/v32/github"
 	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
 )
 
 func TestNewGithubClient(t *testing.T) {
@@ -138,3 +141,49 @@ func TestGetFields(t *testing.T) {
 
 	require.Equal(t, getFieldsReturn, correctFieldReturn)
 }
+
+func TestConditionalRequestWithState(t *testing.T) {
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/influxdata/telegraf" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"name": "telegraf", "owner": {"login": "influxdata"}, "stargazers_count": 42}`))
+	}))
+	defer server.Close()
+
+	newPlugin := func() *GitHub {
+		g := &GitHub{
+			Repositories:      []string{"influxdata/telegraf"},
+			EnterpriseBaseURL: server.URL,
+		}
+		require.NoError(t, g.Init())
+		return g
+	}
+
+	var acc testutil.Accumulator
+	g := newPlugin()
+	require.NoError(t, g.Gather(&acc))
+
+	// Restore the state and make sure the information is not queried again
+	state := g.GetState()
+	g = newPlugin()
+	require.NoError(t, g.SetState(state))
+	require.NoError(t, g.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	require.Equal(t, 2, requests)
+	require.Equal(t, 1, notModified)
+	require.Len(t, acc.Metrics, 2)
+	for _, m := range acc.Metrics {
+		require.Equal(t, 42, m.Fields["stars"])
+	}
+}
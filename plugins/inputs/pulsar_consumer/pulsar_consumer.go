@@ -0,0 +1,286 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar_consumer
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_pulsar "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	defaultMaxUndeliveredMessages = 1000
+)
+
+type PulsarConsumer struct {
+	Topics                      []string        `toml:"topics"`
+	TopicsPattern               string          `toml:"topics_pattern"`
+	SubscriptionName            string          `toml:"subscription_name"`
+	SubscriptionType            string          `toml:"subscription_type"`
+	SubscriptionInitialPosition string          `toml:"subscription_initial_position"`
+	ConsumerName                string          `toml:"consumer_name"`
+	ReceiverQueueSize           int             `toml:"receiver_queue_size"`
+	NackRedeliveryDelay         config.Duration `toml:"nack_redelivery_delay"`
+	MaxUndeliveredMessages      int             `toml:"max_undelivered_messages"`
+	TopicTag                    string          `toml:"topic_tag"`
+	Log                         telegraf.Logger `toml:"-"`
+	common_pulsar.ClientConfig
+
+	parser          telegraf.Parser
+	consumerOptions pulsar.ConsumerOptions
+	client          pulsar.Client
+	consumer        pulsar.Consumer
+
+	mu       sync.Mutex
+	messages map[telegraf.TrackingID]pulsar.Message
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+}
+
+type (
+	empty     struct{}
+	semaphore chan empty
+)
+
+func (*PulsarConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PulsarConsumer) Init() error {
+	if err := p.ClientConfig.Validate(); err != nil {
+		return err
+	}
+
+	if len(p.Topics) == 0 && p.TopicsPattern == "" {
+		return errors.New("either 'topics' or 'topics_pattern' required")
+	}
+	if len(p.Topics) > 0 && p.TopicsPattern != "" {
+		return errors.New("'topics' and 'topics_pattern' are mutually exclusive")
+	}
+	if p.SubscriptionName == "" {
+		return errors.New("'subscription_name' required")
+	}
+
+	var subscriptionType pulsar.SubscriptionType
+	switch p.SubscriptionType {
+	case "exclusive":
+		subscriptionType = pulsar.Exclusive
+	case "", "shared":
+		subscriptionType = pulsar.Shared
+	case "failover":
+		subscriptionType = pulsar.Failover
+	case "key_shared":
+		subscriptionType = pulsar.KeyShared
+	default:
+		return fmt.Errorf("invalid 'subscription_type' setting %q", p.SubscriptionType)
+	}
+
+	var position pulsar.SubscriptionInitialPosition
+	switch p.SubscriptionInitialPosition {
+	case "", "latest":
+		position = pulsar.SubscriptionPositionLatest
+	case "earliest":
+		position = pulsar.SubscriptionPositionEarliest
+	default:
+		return fmt.Errorf("invalid 'subscription_initial_position' setting %q", p.SubscriptionInitialPosition)
+	}
+
+	if p.ReceiverQueueSize < 0 {
+		return fmt.Errorf("invalid 'receiver_queue_size' setting %d", p.ReceiverQueueSize)
+	}
+	if p.NackRedeliveryDelay < 0 {
+		return errors.New("'nack_redelivery_delay' must not be negative")
+	}
+	if p.MaxUndeliveredMessages <= 0 {
+		return fmt.Errorf("invalid 'max_undelivered_messages' setting %d", p.MaxUndeliveredMessages)
+	}
+
+	p.consumerOptions = pulsar.ConsumerOptions{
+		Topics:                      p.Topics,
+		TopicsPattern:               p.TopicsPattern,
+		SubscriptionName:            p.SubscriptionName,
+		Type:                        subscriptionType,
+		SubscriptionInitialPosition: position,
+		Name:                        p.ConsumerName,
+		ReceiverQueueSize:           p.ReceiverQueueSize,
+		NackRedeliveryDelay:         time.Duration(p.NackRedeliveryDelay),
+	}
+
+	return nil
+}
+
+// SetParser takes the data_format from the config and finds the right parser for that format
+func (p *PulsarConsumer) SetParser(parser telegraf.Parser) {
+	p.parser = parser
+}
+
+func (p *PulsarConsumer) Start(acc telegraf.Accumulator) error {
+	client, err := p.ClientConfig.NewClient(p.Log)
+	if err != nil {
+		return &internal.StartupError{Err: err, Retry: true}
+	}
+
+	consumer, err := client.Subscribe(p.consumerOptions)
+	if err != nil {
+		client.Close()
+		return &internal.StartupError{
+			Err:   fmt.Errorf("subscribing failed: %w", err),
+			Retry: true,
+		}
+	}
+	p.client = client
+	p.consumer = consumer
+
+	p.start(acc)
+	return nil
+}
+
+// start receives the messages of the consumer and acknowledges them once
+// the metrics are delivered to the outputs
+func (p *PulsarConsumer) start(ac telegraf.Accumulator) {
+	acc := ac.WithTracking(p.MaxUndeliveredMessages)
+	sem := make(semaphore, p.MaxUndeliveredMessages)
+	p.messages = make(map[telegraf.TrackingID]pulsar.Message, p.MaxUndeliveredMessages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		p.receive(ctx, acc, sem)
+	}()
+	go func() {
+		defer p.wg.Done()
+		p.onDelivery(ctx, acc, sem)
+	}()
+}
+
+func (*PulsarConsumer) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *PulsarConsumer) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+
+	// Undelivered messages are not acknowledged and thus redelivered by the
+	// broker to the next consumer of the subscription
+	if p.consumer != nil {
+		p.consumer.Close()
+		p.consumer = nil
+	}
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+}
+
+func (p *PulsarConsumer) receive(ctx context.Context, acc telegraf.TrackingAccumulator, sem semaphore) {
+	messages := p.consumer.Chan()
+	for {
+		// Wait for a free slot to limit the number of undelivered messages
+		select {
+		case <-ctx.Done():
+			return
+		case sem <- empty{}:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if !p.onMessage(acc, msg.Message) {
+				<-sem
+			}
+		}
+	}
+}
+
+// onMessage parses the message and adds the metrics as tracking group.
+// Messages without metrics are acknowledged immediately and false is returned.
+func (p *PulsarConsumer) onMessage(acc telegraf.TrackingAccumulator, msg pulsar.Message) bool {
+	metrics, err := p.parser.Parse(msg.Payload())
+	if err != nil {
+		acc.AddError(fmt.Errorf("parsing message from topic %q failed: %w", msg.Topic(), err))
+	}
+	if len(metrics) == 0 {
+		// Remove the message from the subscription as it would fail again
+		p.ack(msg)
+		return false
+	}
+
+	if p.TopicTag != "" {
+		for _, m := range metrics {
+			m.AddTag(p.TopicTag, msg.Topic())
+		}
+	}
+
+	p.mu.Lock()
+	id := acc.AddTrackingMetricGroup(metrics)
+	p.messages[id] = msg
+	p.mu.Unlock()
+	return true
+}
+
+func (p *PulsarConsumer) onDelivery(ctx context.Context, acc telegraf.TrackingAccumulator, sem semaphore) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case info := <-acc.Delivered():
+			p.mu.Lock()
+			msg, ok := p.messages[info.ID()]
+			if !ok {
+				p.mu.Unlock()
+				continue
+			}
+			<-sem
+			delete(p.messages, info.ID())
+			p.mu.Unlock()
+
+			if info.Delivered() {
+				p.ack(msg)
+			} else {
+				p.consumer.Nack(msg)
+			}
+		}
+	}
+}
+
+func (p *PulsarConsumer) ack(msg pulsar.Message) {
+	if err := p.consumer.Ack(msg); err != nil {
+		p.Log.Errorf("Acknowledging message %v failed: %v", msg.ID(), err)
+	}
+}
+
+func init() {
+	inputs.Add("pulsar_consumer", func() telegraf.Input {
+		return &PulsarConsumer{
+			ClientConfig: common_pulsar.ClientConfig{
+				ConnectionTimeout: config.Duration(10 * time.Second),
+				OperationTimeout:  config.Duration(30 * time.Second),
+			},
+			SubscriptionName:       "telegraf",
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
+	})
+}
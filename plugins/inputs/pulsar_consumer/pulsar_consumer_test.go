@@ -0,0 +1,353 @@
+package pulsar_consumer
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	common_pulsar "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &PulsarConsumer{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	client := common_pulsar.ClientConfig{URL: "pulsar://localhost:6650"}
+	tests := []struct {
+		name     string
+		plugin   *PulsarConsumer
+		expected string
+	}{
+		{
+			name:     "missing url",
+			plugin:   &PulsarConsumer{Topics: []string{"telegraf"}},
+			expected: "'url' required",
+		},
+		{
+			name: "token and basic auth",
+			plugin: &PulsarConsumer{
+				ClientConfig: common_pulsar.ClientConfig{
+					URL:      "pulsar://localhost:6650",
+					Token:    config.NewSecret([]byte("token")),
+					Username: config.NewSecret([]byte("user")),
+				},
+				Topics: []string{"telegraf"},
+			},
+			expected: "either use 'token' or 'username' and 'password'",
+		},
+		{
+			name:     "missing topics",
+			plugin:   &PulsarConsumer{ClientConfig: client},
+			expected: "either 'topics' or 'topics_pattern' required",
+		},
+		{
+			name:     "topics and pattern",
+			plugin:   &PulsarConsumer{ClientConfig: client, Topics: []string{"telegraf"}, TopicsPattern: "telegraf-.*"},
+			expected: "'topics' and 'topics_pattern' are mutually exclusive",
+		},
+		{
+			name:     "missing subscription name",
+			plugin:   &PulsarConsumer{ClientConfig: client, Topics: []string{"telegraf"}},
+			expected: "'subscription_name' required",
+		},
+		{
+			name: "invalid subscription type",
+			plugin: &PulsarConsumer{
+				ClientConfig:     client,
+				Topics:           []string{"telegraf"},
+				SubscriptionName: "telegraf",
+				SubscriptionType: "broadcast",
+			},
+			expected: `invalid 'subscription_type' setting "broadcast"`,
+		},
+		{
+			name: "invalid initial position",
+			plugin: &PulsarConsumer{
+				ClientConfig:                client,
+				Topics:                      []string{"telegraf"},
+				SubscriptionName:            "telegraf",
+				SubscriptionInitialPosition: "oldest",
+			},
+			expected: `invalid 'subscription_initial_position' setting "oldest"`,
+		},
+		{
+			name: "invalid max undelivered messages",
+			plugin: &PulsarConsumer{
+				ClientConfig:     client,
+				Topics:           []string{"telegraf"},
+				SubscriptionName: "telegraf",
+			},
+			expected: "invalid 'max_undelivered_messages' setting 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestConsume(t *testing.T) {
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+
+	plugin := &PulsarConsumer{
+		ClientConfig:           common_pulsar.ClientConfig{URL: "pulsar://localhost:6650"},
+		Topics:                 []string{"telegraf"},
+		SubscriptionName:       "telegraf",
+		SubscriptionType:       "key_shared",
+		TopicTag:               "topic",
+		MaxUndeliveredMessages: 10,
+		Log:                    testutil.Logger{},
+	}
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+	require.Equal(t, pulsar.KeyShared, plugin.consumerOptions.Type)
+
+	consumer := newMockConsumer()
+	plugin.consumer = consumer
+
+	var acc testutil.Accumulator
+	plugin.start(&acc)
+	defer plugin.Stop()
+
+	accepted := &mockMessage{topic: "telegraf", payload: "cpu value=42i 1\n"}
+	rejected := &mockMessage{topic: "telegraf", payload: "mem value=23i 2\n"}
+	invalid := &mockMessage{topic: "telegraf", payload: "not a metric"}
+	consumer.send(accepted, rejected, invalid)
+
+	// Invalid messages must be acknowledged immediately
+	require.Eventually(t, func() bool {
+		return consumer.acked(invalid)
+	}, 3*time.Second, 100*time.Millisecond)
+	require.Len(t, acc.Errors, 1)
+
+	acc.Wait(2)
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"topic": "telegraf"}, map[string]interface{}{"value": int64(42)}, time.Unix(0, 1)),
+		metric.New("mem", map[string]string{"topic": "telegraf"}, map[string]interface{}{"value": int64(23)}, time.Unix(0, 2)),
+	}
+	actual := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	// Messages must only be acknowledged after delivery
+	require.False(t, consumer.acked(accepted))
+	require.False(t, consumer.nacked(rejected))
+
+	actual[0].Accept()
+	actual[1].Reject()
+	require.Eventually(t, func() bool {
+		return consumer.acked(accepted) && consumer.nacked(rejected)
+	}, 3*time.Second, 100*time.Millisecond)
+	require.False(t, consumer.nacked(accepted))
+	require.False(t, consumer.acked(rejected))
+}
+
+func TestMaxUndeliveredMessages(t *testing.T) {
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+
+	plugin := &PulsarConsumer{
+		ClientConfig:           common_pulsar.ClientConfig{URL: "pulsar://localhost:6650"},
+		Topics:                 []string{"telegraf"},
+		SubscriptionName:       "telegraf",
+		MaxUndeliveredMessages: 1,
+		Log:                    testutil.Logger{},
+	}
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	consumer := newMockConsumer()
+	plugin.consumer = consumer
+
+	var acc testutil.Accumulator
+	plugin.start(&acc)
+	defer plugin.Stop()
+
+	consumer.send(
+		&mockMessage{topic: "telegraf", payload: "cpu value=1i 1\n"},
+		&mockMessage{topic: "telegraf", payload: "cpu value=2i 2\n"},
+	)
+
+	// The second message must not be processed before the first one is
+	// delivered
+	acc.Wait(1)
+	require.Never(t, func() bool {
+		return acc.NMetrics() > 1
+	}, 500*time.Millisecond, 100*time.Millisecond)
+
+	acc.GetTelegrafMetrics()[0].Accept()
+	acc.Wait(2)
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "6650"
+	container := testutil.Container{
+		Image:        "apachepulsar/pulsar:4.0.4",
+		ExposedPorts: []string{servicePort, "8080"},
+		Cmd:          []string{"bin/pulsar", "standalone", "--no-functions-worker", "--no-stream-storage"},
+		WaitingFor: wait.ForAll(
+			wait.NewHTTPStrategy("/admin/v2/clusters").WithPort(nat.Port("8080")).WithResponseMatcher(
+				func(body io.Reader) bool {
+					buf, err := io.ReadAll(body)
+					return err == nil && strings.Contains(string(buf), "standalone")
+				},
+			),
+			wait.ForLog("Successfully updated the policies on namespace public/default"),
+			wait.ForListeningPort(nat.Port(servicePort)),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	url := "pulsar://" + container.Address + ":" + container.Ports[servicePort]
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+
+	plugin := &PulsarConsumer{
+		ClientConfig:                common_pulsar.ClientConfig{URL: url},
+		Topics:                      []string{"telegraf"},
+		SubscriptionName:            "telegraf",
+		SubscriptionType:            "shared",
+		SubscriptionInitialPosition: "earliest",
+		TopicTag:                    "topic",
+		MaxUndeliveredMessages:      defaultMaxUndeliveredMessages,
+		Log:                         testutil.Logger{},
+	}
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Produce the messages using a separate client
+	client, err := pulsar.NewClient(pulsar.ClientOptions{URL: url})
+	require.NoError(t, err)
+	defer client.Close()
+	producer, err := client.CreateProducer(pulsar.ProducerOptions{Topic: "telegraf"})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	for _, payload := range []string{"cpu value=1i 1000000000\n", "cpu value=2i 2000000000\n"} {
+		_, err := producer.Send(t.Context(), &pulsar.ProducerMessage{Payload: []byte(payload)})
+		require.NoError(t, err)
+	}
+
+	acc.Wait(2)
+	expected := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"topic": "persistent://public/default/telegraf"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(1, 0),
+		),
+		metric.New("cpu",
+			map[string]string{"topic": "persistent://public/default/telegraf"},
+			map[string]interface{}{"value": int64(2)},
+			time.Unix(2, 0),
+		),
+	}
+	actual := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.SortMetrics())
+
+	// Acknowledge the messages by delivering the metrics
+	for _, m := range actual {
+		m.Accept()
+	}
+	require.Eventually(t, func() bool {
+		plugin.mu.Lock()
+		defer plugin.mu.Unlock()
+		return len(plugin.messages) == 0
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+type mockMessage struct {
+	pulsar.Message
+	topic   string
+	payload string
+}
+
+func (m *mockMessage) Topic() string {
+	return m.topic
+}
+
+func (m *mockMessage) Payload() []byte {
+	return []byte(m.payload)
+}
+
+func (*mockMessage) ID() pulsar.MessageID {
+	return pulsar.EarliestMessageID()
+}
+
+type mockConsumer struct {
+	pulsar.Consumer
+	messages chan pulsar.ConsumerMessage
+
+	acks  map[pulsar.Message]bool
+	nacks map[pulsar.Message]bool
+	sync.Mutex
+}
+
+func newMockConsumer() *mockConsumer {
+	return &mockConsumer{
+		messages: make(chan pulsar.ConsumerMessage, 100),
+		acks:     make(map[pulsar.Message]bool),
+		nacks:    make(map[pulsar.Message]bool),
+	}
+}
+
+func (c *mockConsumer) send(msgs ...pulsar.Message) {
+	for _, msg := range msgs {
+		c.messages <- pulsar.ConsumerMessage{Consumer: c, Message: msg}
+	}
+}
+
+func (c *mockConsumer) acked(msg pulsar.Message) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.acks[msg]
+}
+
+func (c *mockConsumer) nacked(msg pulsar.Message) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.nacks[msg]
+}
+
+func (c *mockConsumer) Chan() <-chan pulsar.ConsumerMessage {
+	return c.messages
+}
+
+func (c *mockConsumer) Ack(msg pulsar.Message) error {
+	c.Lock()
+	defer c.Unlock()
+	c.acks[msg] = true
+	return nil
+}
+
+func (c *mockConsumer) Nack(msg pulsar.Message) {
+	c.Lock()
+	defer c.Unlock()
+	c.nacks[msg] = true
+}
+
+func (*mockConsumer) Close() {}
@@ -0,0 +1,119 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// incrementalValue is the high-water mark of the incremental column of a query.
+// Only one of the values is set depending on the column type. The value is
+// serialized with its type to keep the type across restarts.
+type incrementalValue struct {
+	Int    *int64     `json:"int,omitempty"`
+	Float  *float64   `json:"float,omitempty"`
+	String *string    `json:"string,omitempty"`
+	Time   *time.Time `json:"time,omitempty"`
+}
+
+// parseIncrementalValue converts the configured start value to an integer,
+// a float or a RFC3339 timestamp if possible and uses a string otherwise.
+func parseIncrementalValue(s string) incrementalValue {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return incrementalValue{Int: &v}
+	}
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return incrementalValue{Float: &v}
+	}
+	if v, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return incrementalValue{Time: &v}
+	}
+	return incrementalValue{String: &s}
+}
+
+// value returns the value to pass as query argument
+func (v *incrementalValue) value() interface{} {
+	switch {
+	case v.Int != nil:
+		return *v.Int
+	case v.Float != nil:
+		return *v.Float
+	case v.Time != nil:
+		return *v.Time
+	case v.String != nil:
+		return *v.String
+	}
+	return nil
+}
+
+// update sets the value to the given column value if the latter is greater.
+// In case the types differ, the column value always takes precedence.
+func (v *incrementalValue) update(raw interface{}) error {
+	var n incrementalValue
+	switch c := raw.(type) {
+	case nil:
+		return nil
+	case int:
+		x := int64(c)
+		n.Int = &x
+	case int8:
+		x := int64(c)
+		n.Int = &x
+	case int16:
+		x := int64(c)
+		n.Int = &x
+	case int32:
+		x := int64(c)
+		n.Int = &x
+	case int64:
+		n.Int = &c
+	case uint:
+		x := int64(c)
+		n.Int = &x
+	case uint8:
+		x := int64(c)
+		n.Int = &x
+	case uint16:
+		x := int64(c)
+		n.Int = &x
+	case uint32:
+		x := int64(c)
+		n.Int = &x
+	case uint64:
+		x := int64(c)
+		n.Int = &x
+	case float32:
+		x := float64(c)
+		n.Float = &x
+	case float64:
+		n.Float = &c
+	case time.Time:
+		n.Time = &c
+	case string:
+		n.String = &c
+	case []byte:
+		x := string(c)
+		n.String = &x
+	default:
+		return fmt.Errorf("type \"%T\" unsupported", raw)
+	}
+
+	var greater bool
+	switch {
+	case n.Int != nil && v.Int != nil:
+		greater = *n.Int > *v.Int
+	case n.Float != nil && v.Float != nil:
+		greater = *n.Float > *v.Float
+	case n.Time != nil && v.Time != nil:
+		greater = n.Time.After(*v.Time)
+	case n.String != nil && v.String != nil:
+		greater = strings.Compare(*n.String, *v.String) > 0
+	default:
+		greater = true
+	}
+	if greater {
+		*v = n
+	}
+	return nil
+}
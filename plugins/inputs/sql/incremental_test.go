@@ -0,0 +1,72 @@
+//go:build !mips && !mipsle && !mips64 && !ppc64 && !riscv64 && !loong64 && !mips64le && !(windows && (386 || arm))
+
+package sql
+
+import (
+	dbsql "database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestIncrementalQueryWithState(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db, err := dbsql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE events (id INTEGER, value INTEGER)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO events VALUES (1, 10), (2, 20), (3, 30)")
+	require.NoError(t, err)
+
+	newPlugin := func() *SQL {
+		plugin := &SQL{
+			Driver: "sqlite",
+			Dsn:    config.NewSecret([]byte(dsn)),
+			Queries: []query{{
+				Query:               "SELECT id, value FROM events WHERE id > ? ORDER BY id",
+				FieldColumnsInclude: []string{"value"},
+				IncrementalColumn:   "id",
+			}},
+			Log: testutil.Logger{},
+		}
+		require.NoError(t, plugin.Init())
+		return plugin
+	}
+
+	// Query all rows initially
+	var acc testutil.Accumulator
+	plugin := newPlugin()
+	require.NoError(t, plugin.Start(&acc))
+	require.NoError(t, plugin.Gather(&acc))
+	plugin.Stop()
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+
+	// Only query the new rows after a restart
+	_, err = db.Exec("INSERT INTO events VALUES (4, 40), (5, 50)")
+	require.NoError(t, err)
+
+	state := plugin.GetState()
+	acc.ClearMetrics()
+	plugin = newPlugin()
+	require.NoError(t, plugin.SetState(state))
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, int64(40), metrics[0].Fields()["value"])
+	require.Equal(t, int64(50), metrics[1].Fields()["value"])
+
+	// Nothing new
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
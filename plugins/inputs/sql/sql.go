@@ -43,6 +43,10 @@ type SQL struct {
 	driverName      string
 	db              *dbsql.DB
 	serverConnected bool
+
+	// High-water marks of incremental queries indexed by the query
+	marks    map[string]incrementalValue
+	marksMtx sync.Mutex
 }
 
 type query struct {
@@ -61,7 +65,10 @@ type query struct {
 	FieldColumnsUint    []string `toml:"field_columns_uint"`
 	FieldColumnsBool    []string `toml:"field_columns_bool"`
 	FieldColumnsString  []string `toml:"field_columns_string"`
+	IncrementalColumn   string   `toml:"incremental_column"`
+	IncrementalStart    string   `toml:"incremental_start"`
 
+	incrementalStart  incrementalValue
 	statement         *dbsql.Stmt
 	tagFilter         filter.Filter
 	fieldFilter       filter.Filter
@@ -76,6 +83,31 @@ func (*SQL) SampleConfig() string {
 	return sampleConfig
 }
 
+func (s *SQL) GetState() interface{} {
+	s.marksMtx.Lock()
+	defer s.marksMtx.Unlock()
+
+	marks := make(map[string]incrementalValue, len(s.marks))
+	for k, v := range s.marks {
+		marks[k] = v
+	}
+	return marks
+}
+
+func (s *SQL) SetState(state interface{}) error {
+	marks, ok := state.(map[string]incrementalValue)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	s.marksMtx.Lock()
+	defer s.marksMtx.Unlock()
+	for k, v := range marks {
+		s.marks[k] = v
+	}
+	return nil
+}
+
 func (s *SQL) Init() error {
 	// Option handling
 	if s.Driver == "" {
@@ -166,7 +198,16 @@ func (s *SQL) Init() error {
 		if q.Measurement == "" {
 			s.Queries[i].Measurement = "sql"
 		}
+
+		// Initial value for incremental queries
+		if q.IncrementalColumn != "" {
+			if q.IncrementalStart == "" {
+				s.Queries[i].IncrementalStart = "0"
+			}
+			s.Queries[i].incrementalStart = parseIncrementalValue(s.Queries[i].IncrementalStart)
+		}
 	}
+	s.marks = make(map[string]incrementalValue)
 
 	// Derive the sql-framework driver name from our config name. This abstracts the actual driver
 	// from the database-type the user wants.
@@ -340,19 +381,33 @@ func (s *SQL) prepareStatements() {
 }
 
 func (s *SQL) executeQuery(ctx context.Context, acc telegraf.Accumulator, q query, tquery time.Time) error {
+	// Pass the high-water mark of incremental queries as argument
+	var args []interface{}
+	var mark *incrementalValue
+	if q.IncrementalColumn != "" {
+		s.marksMtx.Lock()
+		current, found := s.marks[q.Query]
+		s.marksMtx.Unlock()
+		if !found {
+			current = q.incrementalStart
+		}
+		mark = &current
+		args = append(args, mark.value())
+	}
+
 	// Execute the query either prepared or unprepared
 	var rows *dbsql.Rows
 	if q.statement != nil {
 		// Use the previously prepared query
 		var err error
-		rows, err = q.statement.QueryContext(ctx)
+		rows, err = q.statement.QueryContext(ctx, args...)
 		if err != nil {
 			return err
 		}
 	} else {
 		// Fallback to unprepared query
 		var err error
-		rows, err = s.db.Query(q.Query)
+		rows, err = s.db.Query(q.Query, args...)
 		if err != nil {
 			return err
 		}
@@ -364,10 +419,19 @@ func (s *SQL) executeQuery(ctx context.Context, acc telegraf.Accumulator, q quer
 	if err != nil {
 		return err
 	}
-	rowCount, err := q.parse(acc, rows, tquery, s.Log)
+	rowCount, err := q.parse(acc, rows, tquery, mark, s.Log)
 	s.Log.Debugf("Received %d rows and %d columns for query %q", rowCount, len(columnNames), q.Query)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Only advance the high-water mark if all rows were processed
+	if mark != nil {
+		s.marksMtx.Lock()
+		s.marks[q.Query] = *mark
+		s.marksMtx.Unlock()
+	}
+	return nil
 }
 
 func (s *SQL) checkDSN() error {
@@ -377,7 +441,7 @@ func (s *SQL) checkDSN() error {
 	return nil
 }
 
-func (q *query) parse(acc telegraf.Accumulator, rows *dbsql.Rows, t time.Time, logger telegraf.Logger) (int, error) {
+func (q *query) parse(acc telegraf.Accumulator, rows *dbsql.Rows, t time.Time, mark *incrementalValue, logger telegraf.Logger) (int, error) {
 	columnNames, err := rows.Columns()
 	if err != nil {
 		return 0, err
@@ -415,6 +479,12 @@ func (q *query) parse(acc telegraf.Accumulator, rows *dbsql.Rows, t time.Time, l
 				}
 			}
 
+			if mark != nil && name == q.IncrementalColumn {
+				if err := mark.update(columnData[i]); err != nil {
+					return 0, fmt.Errorf("incremental column %q: %w", name, err)
+				}
+			}
+
 			if q.TimeColumn != "" && name == q.TimeColumn {
 				var fieldvalue interface{}
 				var skipParsing bool
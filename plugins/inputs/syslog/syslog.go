@@ -36,6 +36,7 @@ type Syslog struct {
 	Trailer        nontransparent.TrailerType `toml:"trailer"`
 	BestEffort     bool                       `toml:"best_effort"`
 	Separator      string                     `toml:"sdparam_separator"`
+	DropOld        bool                       `toml:"drop_old_messages"`
 	Log            telegraf.Logger            `toml:"-"`
 	socket.Config
 
@@ -44,12 +45,44 @@ type Syslog struct {
 
 	url    *url.URL
 	socket *socket.Socket
+
+	// Timestamp of the newest message per sender and the timestamps
+	// restored from the state of a previous run
+	newest    map[string]int64
+	restored  map[string]int64
+	newestMtx sync.Mutex
 }
 
 func (*Syslog) SampleConfig() string {
 	return sampleConfig
 }
 
+func (s *Syslog) GetState() interface{} {
+	s.newestMtx.Lock()
+	defer s.newestMtx.Unlock()
+
+	newest := make(map[string]int64, len(s.newest))
+	for k, v := range s.newest {
+		newest[k] = v
+	}
+	return newest
+}
+
+func (s *Syslog) SetState(state interface{}) error {
+	newest, ok := state.(map[string]int64)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	s.newestMtx.Lock()
+	defer s.newestMtx.Unlock()
+	for k, v := range newest {
+		s.newest[k] = v
+		s.restored[k] = v
+	}
+	return nil
+}
+
 func (s *Syslog) Init() error {
 	// Check settings and set defaults
 	switch s.Framing {
@@ -71,6 +104,8 @@ func (s *Syslog) Init() error {
 	if s.Separator == "" {
 		s.Separator = "_"
 	}
+	s.newest = make(map[string]int64)
+	s.restored = make(map[string]int64)
 
 	// Check and parse address, set default if necessary
 	if s.Address == "" {
@@ -190,8 +225,7 @@ func (s *Syslog) createStreamDataHandler(acc telegraf.Accumulator) socket.Callba
 				return
 			}
 
-			// Extract message information
-			acc.AddFields("syslog", fields(r.Message, s.Separator), tags(r.Message, addr))
+			s.addMessage(acc, r.Message, addr)
 		})
 		parser.Parse(reader)
 	}
@@ -230,8 +264,36 @@ func (s *Syslog) createDatagramDataHandler(acc telegraf.Accumulator) socket.Call
 				addr = src.String()
 			}
 		}
-		acc.AddFields("syslog", fields(message, s.Separator), tags(message, addr))
+		s.addMessage(acc, message, addr)
+	}
+}
+
+func (s *Syslog) addMessage(acc telegraf.Accumulator, msg syslog.Message, src string) {
+	// Extract message information
+	fields := fields(msg, s.Separator)
+	tags := tags(msg, src)
+
+	if s.DropOld {
+		// Messages without timestamp cannot be compared
+		timestamp, ok := fields["timestamp"].(int64)
+		if ok {
+			sender := tags["source"] + "|" + tags["hostname"] + "|" + tags["appname"]
+
+			// Only drop messages already received before the restart but
+			// keep out-of-order messages received while running
+			s.newestMtx.Lock()
+			if restored, found := s.restored[sender]; found && timestamp <= restored {
+				s.newestMtx.Unlock()
+				return
+			}
+			if timestamp > s.newest[sender] {
+				s.newest[sender] = timestamp
+			}
+			s.newestMtx.Unlock()
+		}
 	}
+
+	acc.AddFields("syslog", fields, tags)
 }
 
 func tags(msg syslog.Message, src string) map[string]string {
@@ -355,3 +355,64 @@ func TestIssue10121(t *testing.T) {
 		return err != nil
 	}, 3*time.Second, 250*time.Millisecond)
 }
+
+func TestDropOldMessagesWithState(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test as unixgram is not supported on Windows")
+	}
+
+	send := func(plugin *Syslog, acc *testutil.Accumulator, sock string, timestamps []int64, expected int) {
+		client, err := net.Dial("unixgram", sock)
+		require.NoError(t, err)
+		defer client.Close()
+		for _, ts := range timestamps {
+			msg := "<29>1 " + time.Unix(ts, 0).UTC().Format(time.RFC3339) + " web1 someservice 2341 2 - hello"
+			_, err = client.Write([]byte(msg))
+			require.NoError(t, err)
+		}
+		require.Eventually(t, func() bool {
+			return int(acc.NMetrics()) >= expected
+		}, 3*time.Second, 100*time.Millisecond)
+		plugin.Stop()
+	}
+
+	newPlugin := func(sock string) *Syslog {
+		f, err := os.Create(sock)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		plugin := &Syslog{
+			Address: "unixgram://" + sock,
+			Trailer: nontransparent.LF,
+			DropOld: true,
+			Log:     testutil.Logger{},
+		}
+		require.NoError(t, plugin.Init())
+		return plugin
+	}
+
+	// Out-of-order messages received while running are kept
+	var acc testutil.Accumulator
+	sock := testutil.TempSocket(t)
+	plugin := newPlugin(sock)
+	require.NoError(t, plugin.Start(&acc))
+	send(plugin, &acc, sock, []int64{100, 50, 100, 200}, 4)
+
+	// Messages at or before the newest timestamp of the previous run are
+	// dropped after a restart
+	state := plugin.GetState()
+	require.Equal(t, map[string]int64{"|web1|someservice": 200 * int64(time.Second)}, state)
+	sock = testutil.TempSocket(t)
+	plugin = newPlugin(sock)
+	require.NoError(t, plugin.SetState(state))
+	require.NoError(t, plugin.Start(&acc))
+	send(plugin, &acc, sock, []int64{150, 200, 300, 250}, 6)
+
+	var actual []int64
+	for _, m := range acc.GetTelegrafMetrics() {
+		ts, ok := m.GetField("timestamp")
+		require.True(t, ok)
+		actual = append(actual, ts.(int64)/int64(time.Second))
+	}
+	require.Equal(t, []int64{100, 50, 100, 200, 300, 250}, actual)
+}
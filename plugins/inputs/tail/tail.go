@@ -52,6 +52,7 @@ type Tail struct {
 	Log        telegraf.Logger `toml:"-"`
 	tailers    map[string]*tail.Tail
 	offsets    map[string]int64
+	stateMtx   sync.Mutex
 	parserFunc telegraf.ParserFunc
 	wg         sync.WaitGroup
 
@@ -189,7 +190,23 @@ func (t *Tail) getSeekInfo(file string) (*tail.SeekInfo, error) {
 }
 
 func (t *Tail) GetState() interface{} {
-	return t.offsets
+	t.stateMtx.Lock()
+	defer t.stateMtx.Unlock()
+
+	state := make(map[string]int64, len(t.offsets)+len(t.tailers))
+	for k, v := range t.offsets {
+		state[k] = v
+	}
+
+	// Use the current offsets of running tailers to allow checkpointing
+	if !t.Pipe {
+		for _, tailer := range t.tailers {
+			if offset, err := tailer.Tell(); err == nil {
+				state[tailer.Filename] = offset
+			}
+		}
+	}
+	return state
 }
 
 func (t *Tail) SetState(state interface{}) error {
@@ -197,6 +214,9 @@ func (t *Tail) SetState(state interface{}) error {
 	if !ok {
 		return errors.New("state has to be of type 'map[string]int64'")
 	}
+
+	t.stateMtx.Lock()
+	defer t.stateMtx.Unlock()
 	for k, v := range offsetsState {
 		t.offsets[k] = v
 	}
@@ -208,6 +228,7 @@ func (t *Tail) Gather(_ telegraf.Accumulator) error {
 }
 
 func (t *Tail) Stop() {
+	t.stateMtx.Lock()
 	for _, tailer := range t.tailers {
 		if !t.Pipe {
 			// store offset for resume
@@ -224,6 +245,8 @@ func (t *Tail) Stop() {
 			t.Log.Errorf("Stopping tail on %q: %s", tailer.Filename, err.Error())
 		}
 	}
+	t.tailers = make(map[string]*tail.Tail)
+	t.stateMtx.Unlock()
 
 	t.cancel()
 	t.wg.Wait()
@@ -249,7 +272,10 @@ func (t *Tail) tailNewFiles() error {
 			t.Log.Errorf("Glob %q failed to compile: %s", filepath, err.Error())
 		}
 		for _, file := range g.Match() {
-			if _, ok := t.tailers[file]; ok {
+			t.stateMtx.Lock()
+			_, ok := t.tailers[file]
+			t.stateMtx.Unlock()
+			if ok {
 				// we're already tailing this file
 				continue
 			}
@@ -299,14 +325,18 @@ func (t *Tail) tailNewFiles() error {
 				if err := tailer.Err(); err != nil {
 					if strings.HasSuffix(err.Error(), "permission denied") {
 						t.Log.Errorf("Deleting tailer for %q due to: %v", tailer.Filename, err)
+						t.stateMtx.Lock()
 						delete(t.tailers, tailer.Filename)
+						t.stateMtx.Unlock()
 					} else {
 						t.Log.Errorf("Tailing %q: %s", tailer.Filename, err.Error())
 					}
 				}
 			}()
 
+			t.stateMtx.Lock()
 			t.tailers[tailer.Filename] = tailer
+			t.stateMtx.Unlock()
 		}
 	}
 	return nil
@@ -0,0 +1,5 @@
+//go:build !custom || outputs || outputs.failover
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/outputs/failover" // register plugin
@@ -0,0 +1,316 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package clickhouse
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	ch "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/choice"
+	common_tls "github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type ClickHouse struct {
+	Addresses       []string        `toml:"addresses"`
+	Database        string          `toml:"database"`
+	Username        config.Secret   `toml:"username"`
+	Password        config.Secret   `toml:"password"`
+	Compression     string          `toml:"compression"`
+	Timeout         config.Duration `toml:"timeout"`
+	TableMode       string          `toml:"table_mode"`
+	Table           string          `toml:"table"`
+	TimestampColumn string          `toml:"timestamp_column"`
+	CreateTables    bool            `toml:"create_tables"`
+	AddColumns      bool            `toml:"add_columns"`
+	Engine          string          `toml:"engine"`
+	PartitionBy     string          `toml:"partition_by"`
+	TTL             config.Duration `toml:"ttl"`
+	Log             telegraf.Logger `toml:"-"`
+	common_tls.ClientConfig
+
+	conn    driver.Conn
+	manager *tableManager
+}
+
+func (*ClickHouse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *ClickHouse) Init() error {
+	if len(c.Addresses) == 0 {
+		c.Addresses = []string{"localhost:9000"}
+	}
+	if c.Database == "" {
+		c.Database = "default"
+	}
+	if c.Compression == "" {
+		c.Compression = "lz4"
+	}
+	if !choice.Contains(c.Compression, []string{"none", "lz4", "zstd"}) {
+		return fmt.Errorf("invalid 'compression' setting %q", c.Compression)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = config.Duration(5 * time.Second)
+	}
+
+	switch c.TableMode {
+	case "":
+		c.TableMode = "measurement"
+	case "measurement":
+	case "wide":
+		if c.Table == "" {
+			return errors.New("'table' required in wide table mode")
+		}
+	default:
+		return fmt.Errorf("invalid 'table_mode' setting %q", c.TableMode)
+	}
+	if c.TimestampColumn == "" {
+		return errors.New("'timestamp_column' required")
+	}
+	if c.Engine == "" {
+		c.Engine = "MergeTree"
+	}
+	if c.TTL < 0 {
+		return errors.New("'ttl' must not be negative")
+	}
+
+	c.manager = newTableManager(c)
+
+	return nil
+}
+
+func (c *ClickHouse) Connect() error {
+	tlsCfg, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("creating TLS config failed: %w", err)
+	}
+
+	username, err := c.Username.Get()
+	if err != nil {
+		return fmt.Errorf("getting username failed: %w", err)
+	}
+	defer username.Destroy()
+	password, err := c.Password.Get()
+	if err != nil {
+		return fmt.Errorf("getting password failed: %w", err)
+	}
+	defer password.Destroy()
+
+	compression := &ch.Compression{Method: ch.CompressionLZ4}
+	switch c.Compression {
+	case "none":
+		compression.Method = ch.CompressionNone
+	case "zstd":
+		compression.Method = ch.CompressionZSTD
+	}
+
+	conn, err := ch.Open(&ch.Options{
+		Addr: c.Addresses,
+		Auth: ch.Auth{
+			Database: c.Database,
+			Username: username.String(),
+			Password: password.String(),
+		},
+		TLS:         tlsCfg,
+		Compression: compression,
+		DialTimeout: time.Duration(c.Timeout),
+		ReadTimeout: time.Duration(c.Timeout),
+	})
+	if err != nil {
+		return fmt.Errorf("opening connection failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout))
+	defer cancel()
+	if err := conn.Ping(ctx); err != nil {
+		//nolint:errcheck // Ignore the error as connecting already failed
+		conn.Close()
+		return fmt.Errorf("connecting to %v failed: %w", c.Addresses, err)
+	}
+	c.conn = conn
+
+	return nil
+}
+
+func (c *ClickHouse) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *ClickHouse) Write(metrics []telegraf.Metric) error {
+	// Group the metrics by table keeping the order of the tables
+	tables := make([]string, 0)
+	batches := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		table := m.Name()
+		if c.TableMode == "wide" {
+			table = c.Table
+		}
+		if _, found := batches[table]; !found {
+			tables = append(tables, table)
+		}
+		batches[table] = append(batches[table], m)
+	}
+
+	for _, table := range tables {
+		if err := c.writeTable(table, batches[table]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ClickHouse) writeTable(table string, metrics []telegraf.Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout))
+	defer cancel()
+
+	columns, err := c.manager.ensureTable(ctx, table, c.requiredColumns(metrics))
+	if err != nil {
+		return fmt.Errorf("updating table %q failed: %w", table, err)
+	}
+
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, quoteIdent(col.name))
+	}
+	query := fmt.Sprintf("INSERT INTO %s.%s (%s)", quoteIdent(c.Database), quoteIdent(table), strings.Join(names, ","))
+
+	batch, err := c.conn.PrepareBatch(ctx, query)
+	if err != nil {
+		return fmt.Errorf("preparing batch for table %q failed: %w", table, err)
+	}
+	for _, m := range metrics {
+		values, err := c.row(m, columns)
+		if err != nil {
+			c.Log.Warnf("Skipping metric %q: %v", m.Name(), err)
+			continue
+		}
+		if err := batch.Append(values...); err != nil {
+			//nolint:errcheck // Ignore the error as appending already failed
+			batch.Abort()
+			return fmt.Errorf("appending metric to table %q failed: %w", table, err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("sending batch to table %q failed: %w", table, err)
+	}
+	return nil
+}
+
+// requiredColumns returns the columns required to store the given metrics in
+// a single table. Tags and fields are sorted by name to create tables with a
+// stable column order.
+func (c *ClickHouse) requiredColumns(metrics []telegraf.Metric) []column {
+	tags := make(map[string]bool)
+	fields := make(map[string]string)
+	for _, m := range metrics {
+		if c.TableMode != "wide" {
+			for _, tag := range m.TagList() {
+				tags[tag.Key] = true
+			}
+		}
+		for _, field := range m.FieldList() {
+			if _, found := fields[field.Key]; found {
+				continue
+			}
+			if datatype := fieldType(field.Value); datatype != "" {
+				fields[field.Key] = datatype
+			}
+		}
+	}
+
+	columns := make([]column, 0, len(tags)+len(fields)+3)
+	if c.TableMode == "wide" {
+		columns = append(columns,
+			column{name: measurementColumn, datatype: "LowCardinality(String)", role: measurementRole},
+			column{name: c.TimestampColumn, datatype: "DateTime64(9)", role: timestampRole},
+			column{name: tagsColumn, datatype: "Map(LowCardinality(String), String)", role: tagsRole},
+		)
+	} else {
+		columns = append(columns, column{name: c.TimestampColumn, datatype: "DateTime64(9)", role: timestampRole})
+	}
+
+	tagColumns := make([]column, 0, len(tags))
+	for key := range tags {
+		tagColumns = append(tagColumns, column{name: key, datatype: "LowCardinality(String)", role: tagRole})
+	}
+	sort.Slice(tagColumns, func(i, j int) bool { return tagColumns[i].name < tagColumns[j].name })
+	columns = append(columns, tagColumns...)
+
+	fieldColumns := make([]column, 0, len(fields))
+	for key, datatype := range fields {
+		fieldColumns = append(fieldColumns, column{name: key, datatype: "Nullable(" + datatype + ")", role: fieldRole})
+	}
+	sort.Slice(fieldColumns, func(i, j int) bool { return fieldColumns[i].name < fieldColumns[j].name })
+	columns = append(columns, fieldColumns...)
+
+	return columns
+}
+
+// row returns the values of the metric for the given columns. Missing or
+// unconvertible fields are stored as NULL or, for columns not being nullable,
+// as the zero value of the column type.
+func (c *ClickHouse) row(m telegraf.Metric, columns []column) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(columns))
+	for _, col := range columns {
+		switch col.role {
+		case measurementRole:
+			values = append(values, m.Name())
+		case timestampRole:
+			values = append(values, m.Time())
+		case tagsRole:
+			values = append(values, m.Tags())
+		case tagRole:
+			v, _ := m.GetTag(col.name)
+			values = append(values, v)
+		case fieldRole:
+			var converted interface{}
+			if v, found := m.GetField(col.name); found {
+				var err error
+				converted, err = convertValue(v, col.datatype)
+				if err != nil {
+					c.Log.Warnf("Cannot store field %q of metric %q in column %q: %v", col.name, m.Name(), col.name, err)
+				}
+			}
+			if converted == nil && !nullable(col.datatype) {
+				zero, ok := zeroValue(col.datatype)
+				if !ok {
+					return nil, fmt.Errorf("no value for column %q of type %s", col.name, col.datatype)
+				}
+				converted = zero
+			}
+			values = append(values, converted)
+		}
+	}
+	return values, nil
+}
+
+func init() {
+	outputs.Add("clickhouse", func() telegraf.Output {
+		return &ClickHouse{
+			Database:        "default",
+			Compression:     "lz4",
+			Timeout:         config.Duration(5 * time.Second),
+			TableMode:       "measurement",
+			TimestampColumn: "timestamp",
+			CreateTables:    true,
+			AddColumns:      true,
+			Engine:          "MergeTree",
+		}
+	})
+}
@@ -0,0 +1,311 @@
+package clickhouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &ClickHouse{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *ClickHouse
+		expected string
+	}{
+		{
+			name:     "invalid compression",
+			plugin:   &ClickHouse{Compression: "gzip", TimestampColumn: "timestamp"},
+			expected: `invalid 'compression' setting "gzip"`,
+		},
+		{
+			name:     "invalid table mode",
+			plugin:   &ClickHouse{TableMode: "narrow", TimestampColumn: "timestamp"},
+			expected: `invalid 'table_mode' setting "narrow"`,
+		},
+		{
+			name:     "wide mode without table",
+			plugin:   &ClickHouse{TableMode: "wide", TimestampColumn: "timestamp"},
+			expected: "'table' required in wide table mode",
+		},
+		{
+			name:     "missing timestamp column",
+			plugin:   &ClickHouse{},
+			expected: "'timestamp_column' required",
+		},
+		{
+			name:     "negative ttl",
+			plugin:   &ClickHouse{TimestampColumn: "timestamp", TTL: config.Duration(-time.Hour)},
+			expected: "'ttl' must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestCreateTableQuery(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 99.5, "count": int64(1)},
+			time.Unix(0, 0),
+		),
+		metric.New("cpu",
+			map[string]string{"host": "b", "region": "eu"},
+			map[string]interface{}{"usage_idle": 98.5, "active": true, "state": "ok", "total": uint64(5)},
+			time.Unix(0, 0),
+		),
+	}
+
+	tests := []struct {
+		name     string
+		plugin   *ClickHouse
+		expected string
+	}{
+		{
+			name: "measurement",
+			plugin: &ClickHouse{
+				TimestampColumn: "timestamp",
+			},
+			expected: "CREATE TABLE IF NOT EXISTS `default`.`cpu` (`timestamp` DateTime64(9), " +
+				"`cpu` LowCardinality(String), `host` LowCardinality(String), `region` LowCardinality(String), " +
+				"`active` Nullable(Bool), `count` Nullable(Int64), `state` Nullable(String), " +
+				"`total` Nullable(UInt64), `usage_idle` Nullable(Float64)) " +
+				"ENGINE = MergeTree ORDER BY (`cpu`, `host`, `region`, `timestamp`)",
+		},
+		{
+			name: "wide",
+			plugin: &ClickHouse{
+				TableMode:       "wide",
+				Table:           "telegraf",
+				TimestampColumn: "time",
+			},
+			expected: "CREATE TABLE IF NOT EXISTS `default`.`cpu` (`measurement` LowCardinality(String), " +
+				"`time` DateTime64(9), `tags` Map(LowCardinality(String), String), " +
+				"`active` Nullable(Bool), `count` Nullable(Int64), `state` Nullable(String), " +
+				"`total` Nullable(UInt64), `usage_idle` Nullable(Float64)) " +
+				"ENGINE = MergeTree ORDER BY (`measurement`, `time`)",
+		},
+		{
+			name: "partition and ttl",
+			plugin: &ClickHouse{
+				Database:        "metrics",
+				TableMode:       "wide",
+				Table:           "telegraf",
+				TimestampColumn: "timestamp",
+				Engine:          "ReplacingMergeTree",
+				PartitionBy:     "toYYYYMM(timestamp)",
+				TTL:             config.Duration(30 * 24 * time.Hour),
+			},
+			expected: "CREATE TABLE IF NOT EXISTS `metrics`.`cpu` (`measurement` LowCardinality(String), " +
+				"`timestamp` DateTime64(9), `tags` Map(LowCardinality(String), String), " +
+				"`active` Nullable(Bool), `count` Nullable(Int64), `state` Nullable(String), " +
+				"`total` Nullable(UInt64), `usage_idle` Nullable(Float64)) " +
+				"ENGINE = ReplacingMergeTree PARTITION BY toYYYYMM(timestamp) ORDER BY (`measurement`, `timestamp`) " +
+				"TTL toDateTime(`timestamp`) + INTERVAL 2592000 SECOND",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.NoError(t, tt.plugin.Init())
+			query := tt.plugin.manager.createTableQuery("cpu", tt.plugin.requiredColumns(metrics))
+			require.Equal(t, tt.expected, query)
+		})
+	}
+}
+
+func TestAddColumnsQuery(t *testing.T) {
+	plugin := &ClickHouse{TimestampColumn: "timestamp"}
+	require.NoError(t, plugin.Init())
+
+	columns := []column{
+		{name: "zone", datatype: "LowCardinality(String)", role: tagRole},
+		{name: "we`ird", datatype: "Nullable(Float64)", role: fieldRole},
+	}
+	require.Equal(t,
+		"ALTER TABLE `default`.`cpu` ADD COLUMN IF NOT EXISTS `zone` LowCardinality(String), "+
+			"ADD COLUMN IF NOT EXISTS `we\\`ird` Nullable(Float64)",
+		plugin.manager.addColumnsQuery("cpu", columns),
+	)
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		datatype string
+		expected interface{}
+	}{
+		{
+			name:     "matching type",
+			value:    int64(42),
+			datatype: "Nullable(Int64)",
+			expected: int64(42),
+		},
+		{
+			name:     "integer to float",
+			value:    int64(42),
+			datatype: "Nullable(Float64)",
+			expected: float64(42),
+		},
+		{
+			name:     "float to smaller integer",
+			value:    42.0,
+			datatype: "Int32",
+			expected: int32(42),
+		},
+		{
+			name:     "bool to string",
+			value:    true,
+			datatype: "LowCardinality(String)",
+			expected: "true",
+		},
+		{
+			name:     "unconvertible",
+			value:    "foo",
+			datatype: "Nullable(UInt64)",
+			expected: nil,
+		},
+		{
+			name:     "unknown type",
+			value:    "2024-01-01",
+			datatype: "Date",
+			expected: "2024-01-01",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := convertValue(tt.value, tt.datatype)
+			if tt.expected == nil {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestRow(t *testing.T) {
+	logger := &testutil.CaptureLogger{}
+	plugin := &ClickHouse{Log: logger}
+
+	columns := []column{
+		{name: "timestamp", datatype: "DateTime64(9)", role: timestampRole},
+		{name: "host", datatype: "LowCardinality(String)", role: tagRole},
+		{name: "nullable", datatype: "Nullable(UInt64)", role: fieldRole},
+		{name: "value", datatype: "Int64", role: fieldRole},
+		{name: "missing", datatype: "Float64", role: fieldRole},
+	}
+	m := metric.New("cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"nullable": "foo", "value": "bar"},
+		time.Unix(1, 0),
+	)
+
+	// Unconvertible or missing fields are stored as NULL in nullable columns
+	// and as zero value otherwise
+	values, err := plugin.row(m, columns)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{time.Unix(1, 0), "server01", nil, int64(0), float64(0)}, values)
+
+	warnings := logger.Warnings()
+	require.Len(t, warnings, 2)
+	require.Contains(t, warnings[0], `field "nullable"`)
+	require.Contains(t, warnings[1], `field "value"`)
+
+	// Metrics without a value for a column of unknown type are skipped
+	_, err = plugin.row(m, append(columns, column{name: "day", datatype: "Date", role: fieldRole}))
+	require.ErrorContains(t, err, `no value for column "day"`)
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	password := testutil.GetRandomString(32)
+	servicePort := "9000"
+	container := testutil.Container{
+		Image:        "clickhouse",
+		ExposedPorts: []string{servicePort, "8123"},
+		Env: map[string]string{
+			"CLICKHOUSE_USER":     "clickhouse",
+			"CLICKHOUSE_PASSWORD": password,
+		},
+		WaitingFor: wait.ForAll(
+			wait.NewHTTPStrategy("/").WithPort(nat.Port("8123")),
+			wait.ForListeningPort(nat.Port(servicePort)),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	for _, mode := range []string{"measurement", "wide"} {
+		t.Run(mode, func(t *testing.T) {
+			plugin := &ClickHouse{
+				Addresses:       []string{container.Address + ":" + container.Ports[servicePort]},
+				Username:        config.NewSecret([]byte("clickhouse")),
+				Password:        config.NewSecret([]byte(password)),
+				TableMode:       mode,
+				Table:           "telegraf",
+				TimestampColumn: "timestamp",
+				CreateTables:    true,
+				AddColumns:      true,
+				Log:             testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			table := "cpu_" + mode
+			if mode == "wide" {
+				table = "telegraf"
+			}
+
+			require.NoError(t, plugin.Write([]telegraf.Metric{
+				metric.New("cpu_"+mode, map[string]string{"host": "a"}, map[string]interface{}{"value": 1.5}, time.Unix(1, 0)),
+				metric.New("cpu_"+mode, map[string]string{"host": "b"}, map[string]interface{}{"value": 2.5}, time.Unix(2, 0)),
+			}))
+
+			// Write a new field and tag to check adding columns
+			require.NoError(t, plugin.Write([]telegraf.Metric{
+				metric.New("cpu_"+mode, map[string]string{"host": "c", "zone": "z1"}, map[string]interface{}{"count": int64(3)}, time.Unix(3, 0)),
+			}))
+
+			ctx := context.Background()
+			var count uint64
+			require.NoError(t, plugin.conn.QueryRow(ctx, "SELECT count() FROM "+table).Scan(&count))
+			require.Equal(t, uint64(3), count)
+
+			var sum float64
+			require.NoError(t, plugin.conn.QueryRow(ctx, "SELECT sum(value) FROM "+table).Scan(&sum))
+			require.InDelta(t, 4.0, sum, 1e-9)
+
+			var zone string
+			query := "SELECT zone FROM " + table + " WHERE count = 3"
+			if mode == "wide" {
+				query = "SELECT tags['zone'] FROM " + table + " WHERE count = 3"
+			}
+			require.NoError(t, plugin.conn.QueryRow(ctx, query).Scan(&zone))
+			require.Equal(t, "z1", zone)
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+const (
+	measurementColumn = "measurement"
+	tagsColumn        = "tags"
+)
+
+type columnRole int
+
+const (
+	timestampRole columnRole = iota
+	measurementRole
+	tagsRole
+	tagRole
+	fieldRole
+)
+
+type column struct {
+	name     string
+	datatype string
+	role     columnRole
+}
+
+// tableManager creates tables and adds missing columns. The columns of the
+// tables are cached to avoid querying the database on every write.
+type tableManager struct {
+	*ClickHouse
+
+	// map[table]map[column]datatype
+	tables map[string]map[string]string
+}
+
+func newTableManager(c *ClickHouse) *tableManager {
+	return &tableManager{
+		ClickHouse: c,
+		tables:     make(map[string]map[string]string),
+	}
+}
+
+// ensureTable makes sure the table contains the given columns by creating the
+// table or adding the missing columns if enabled. The returned columns are
+// the columns available in the table using the datatypes of the database.
+func (tm *tableManager) ensureTable(ctx context.Context, table string, required []column) ([]column, error) {
+	current, found := tm.tables[table]
+	if !found || len(diffMissingColumns(current, required)) > 0 {
+		var err error
+		if current, err = tm.getColumns(ctx, table); err != nil {
+			return nil, err
+		}
+		tm.tables[table] = current
+	}
+
+	if len(current) == 0 {
+		if !tm.CreateTables {
+			return nil, errors.New("table does not exist and table creation is disabled")
+		}
+		if err := tm.exec(ctx, tm.createTableQuery(table, required)); err != nil {
+			return nil, fmt.Errorf("creating table failed: %w", err)
+		}
+		current = make(map[string]string, len(required))
+		for _, col := range required {
+			current[col.name] = col.datatype
+		}
+		tm.tables[table] = current
+	}
+
+	if missing := diffMissingColumns(current, required); len(missing) > 0 {
+		if tm.AddColumns {
+			if err := tm.exec(ctx, tm.addColumnsQuery(table, missing)); err != nil {
+				return nil, fmt.Errorf("adding columns failed: %w", err)
+			}
+			for _, col := range missing {
+				current[col.name] = col.datatype
+			}
+		} else {
+			names := make([]string, 0, len(missing))
+			for _, col := range missing {
+				names = append(names, col.name)
+			}
+			tm.Log.Warnf("Table %q is missing columns (omitting tags and fields): %s", table, strings.Join(names, ", "))
+		}
+	}
+
+	columns := make([]column, 0, len(required))
+	for _, col := range required {
+		datatype, found := current[col.name]
+		if !found {
+			continue
+		}
+		col.datatype = datatype
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// exec executes the given statement on the database
+func (tm *tableManager) exec(ctx context.Context, query string) error {
+	tm.Log.Debugf("Executing %q", query)
+	return tm.conn.Exec(ctx, query)
+}
+
+func (tm *tableManager) getColumns(ctx context.Context, table string) (map[string]string, error) {
+	rows, err := tm.conn.Query(ctx, "SELECT name, type FROM system.columns WHERE database = ? AND table = ?", tm.Database, table)
+	if err != nil {
+		return nil, fmt.Errorf("querying columns failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, datatype string
+		if err := rows.Scan(&name, &datatype); err != nil {
+			return nil, fmt.Errorf("reading columns failed: %w", err)
+		}
+		columns[name] = datatype
+	}
+	return columns, rows.Err()
+}
+
+func (tm *tableManager) createTableQuery(table string, columns []column) string {
+	definitions := make([]string, 0, len(columns))
+	orderBy := make([]string, 0, len(columns))
+	for _, col := range columns {
+		definitions = append(definitions, quoteIdent(col.name)+" "+col.datatype)
+		if col.role == measurementRole || col.role == tagRole {
+			orderBy = append(orderBy, quoteIdent(col.name))
+		}
+	}
+	orderBy = append(orderBy, quoteIdent(tm.TimestampColumn))
+
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (%s) ENGINE = %s",
+		quoteIdent(tm.Database), quoteIdent(table), strings.Join(definitions, ", "), tm.Engine)
+	if tm.PartitionBy != "" {
+		query += " PARTITION BY " + tm.PartitionBy
+	}
+	query += " ORDER BY (" + strings.Join(orderBy, ", ") + ")"
+	if tm.TTL > 0 {
+		query += fmt.Sprintf(" TTL toDateTime(%s) + INTERVAL %d SECOND",
+			quoteIdent(tm.TimestampColumn), int64(time.Duration(tm.TTL).Seconds()))
+	}
+	return query
+}
+
+func (tm *tableManager) addColumnsQuery(table string, columns []column) string {
+	clauses := make([]string, 0, len(columns))
+	for _, col := range columns {
+		clauses = append(clauses, "ADD COLUMN IF NOT EXISTS "+quoteIdent(col.name)+" "+col.datatype)
+	}
+	return fmt.Sprintf("ALTER TABLE %s.%s %s", quoteIdent(tm.Database), quoteIdent(table), strings.Join(clauses, ", "))
+}
+
+func diffMissingColumns(current map[string]string, required []column) []column {
+	missing := make([]column, 0)
+	for _, col := range required {
+		if _, found := current[col.name]; !found {
+			missing = append(missing, col)
+		}
+	}
+	return missing
+}
+
+// fieldType returns the ClickHouse datatype used to store the field value
+func fieldType(value interface{}) string {
+	switch value.(type) {
+	case int64:
+		return "Int64"
+	case uint64:
+		return "UInt64"
+	case float64:
+		return "Float64"
+	case bool:
+		return "Bool"
+	case string:
+		return "String"
+	}
+	return ""
+}
+
+// convertValue converts the field value to the given column datatype as the
+// datatype of a field can differ between metrics or the table might have been
+// created manually. Values that cannot be converted are returned as nil.
+func convertValue(value interface{}, datatype string) (interface{}, error) {
+	datatype = unwrapType(datatype, "Nullable")
+	datatype = unwrapType(datatype, "LowCardinality")
+
+	var v interface{}
+	var err error
+	switch datatype {
+	case "Int8":
+		v, err = internal.ToInt8(value)
+	case "Int16":
+		v, err = internal.ToInt16(value)
+	case "Int32":
+		v, err = internal.ToInt32(value)
+	case "Int64":
+		v, err = internal.ToInt64(value)
+	case "UInt8":
+		v, err = internal.ToUint8(value)
+	case "UInt16":
+		v, err = internal.ToUint16(value)
+	case "UInt32":
+		v, err = internal.ToUint32(value)
+	case "UInt64":
+		v, err = internal.ToUint64(value)
+	case "Float32":
+		v, err = internal.ToFloat32(value)
+	case "Float64":
+		v, err = internal.ToFloat64(value)
+	case "Bool":
+		v, err = internal.ToBool(value)
+	case "String":
+		v, err = internal.ToString(value)
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("converting %v to %s failed: %w", value, datatype, err)
+	}
+	return v, nil
+}
+
+// nullable returns if the given column type accepts NULL values
+func nullable(datatype string) bool {
+	return strings.HasPrefix(unwrapType(datatype, "LowCardinality"), "Nullable(")
+}
+
+// zeroValue returns the zero value of the given column type
+func zeroValue(datatype string) (interface{}, bool) {
+	switch unwrapType(datatype, "LowCardinality") {
+	case "Int8":
+		return int8(0), true
+	case "Int16":
+		return int16(0), true
+	case "Int32":
+		return int32(0), true
+	case "Int64":
+		return int64(0), true
+	case "UInt8":
+		return uint8(0), true
+	case "UInt16":
+		return uint16(0), true
+	case "UInt32":
+		return uint32(0), true
+	case "UInt64":
+		return uint64(0), true
+	case "Float32":
+		return float32(0), true
+	case "Float64":
+		return float64(0), true
+	case "Bool":
+		return false, true
+	case "String":
+		return "", true
+	}
+	return nil, false
+}
+
+func unwrapType(datatype, wrapper string) string {
+	if strings.HasPrefix(datatype, wrapper+"(") && strings.HasSuffix(datatype, ")") {
+		return datatype[len(wrapper)+1 : len(datatype)-1]
+	}
+	return datatype
+}
+
+func quoteIdent(name string) string {
+	return "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(name) + "`"
+}
@@ -0,0 +1,170 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package failover
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Failover struct {
+	RecoveryInterval config.Duration `toml:"recovery_interval"`
+	Log              telegraf.Logger `toml:"-"`
+
+	outputs    []*output
+	active     int
+	switchedAt time.Time
+}
+
+// output is a wrapped output with its connection state
+type output struct {
+	*outputs.ChildOutput
+	connected bool
+}
+
+func (*Failover) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *Failover) SetOutputs(children []*outputs.ChildOutput) {
+	f.outputs = make([]*output, 0, len(children))
+	for _, c := range children {
+		f.outputs = append(f.outputs, &output{ChildOutput: c})
+	}
+}
+
+func (f *Failover) Init() error {
+	if len(f.outputs) == 0 {
+		return errors.New("no outputs configured")
+	}
+
+	for _, o := range f.outputs {
+		if p, ok := o.Output.(telegraf.Initializer); ok {
+			if err := p.Init(); err != nil {
+				return fmt.Errorf("initializing output %q failed: %w", o.LogName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Failover) Connect() error {
+	// Outputs failing to connect are retried on write so we only fail if
+	// no output is available at all.
+	errs := make([]error, 0, len(f.outputs))
+	for _, o := range f.outputs {
+		if err := o.Output.Connect(); err != nil {
+			f.Log.Warnf("Connecting to output %q failed: %v", o.LogName(), err)
+			errs = append(errs, err)
+			continue
+		}
+		o.connected = true
+	}
+	if len(errs) == len(f.outputs) {
+		return &internal.StartupError{
+			Err:   fmt.Errorf("connecting to all outputs failed: %w", errors.Join(errs...)),
+			Retry: true,
+		}
+	}
+	return nil
+}
+
+func (f *Failover) Close() error {
+	for _, o := range f.outputs {
+		if !o.connected {
+			continue
+		}
+		if err := o.Output.Close(); err != nil {
+			f.Log.Errorf("Closing output %q failed: %v", o.LogName(), err)
+		}
+		o.connected = false
+	}
+	return nil
+}
+
+func (f *Failover) Write(metrics []telegraf.Metric) error {
+	// Only retry the outputs preceding the active one after the recovery
+	// interval to not hit a failed output on every write.
+	start := f.active
+	if start > 0 && time.Since(f.switchedAt) >= time.Duration(f.RecoveryInterval) {
+		start = 0
+	}
+	recovering := start < f.active
+
+	errs := make([]error, 0, len(f.outputs))
+	for i := 0; i < len(f.outputs); i++ {
+		// Try the preceding outputs last if we are not recovering
+		idx := (start + i) % len(f.outputs)
+		o := f.outputs[idx]
+
+		err := f.write(o, metrics)
+		if err == nil || isPartialWrite(err) {
+			f.activate(idx, recovering)
+			return err
+		}
+		f.Log.Warnf("Writing to output %q failed: %v", o.LogName(), err)
+		errs = append(errs, fmt.Errorf("output %q: %w", o.LogName(), err))
+	}
+
+	// Wait for the recovery interval before trying the preceding outputs again
+	if recovering {
+		f.switchedAt = time.Now()
+	}
+	return fmt.Errorf("writing to all outputs failed: %w", errors.Join(errs...))
+}
+
+// write connects the output if necessary and writes the metrics
+func (*Failover) write(o *output, metrics []telegraf.Metric) error {
+	if !o.connected {
+		if err := o.Output.Connect(); err != nil {
+			return fmt.Errorf("connecting failed: %w", err)
+		}
+		o.connected = true
+	}
+	return o.Output.Write(metrics)
+}
+
+// activate makes the output with the given index the one written to
+func (f *Failover) activate(idx int, recovering bool) {
+	switch {
+	case idx < f.active:
+		f.Log.Infof("Output %q recovered, switching back from output %q", f.outputs[idx].LogName(), f.outputs[f.active].LogName())
+	case idx > f.active:
+		f.Log.Warnf("Failing over from output %q to output %q", f.outputs[f.active].LogName(), f.outputs[idx].LogName())
+	case recovering:
+		// Restart the recovery interval as all preceding outputs failed
+		f.switchedAt = time.Now()
+		return
+	default:
+		return
+	}
+	f.active = idx
+	f.switchedAt = time.Now()
+}
+
+// isPartialWrite checks if the output handled some of the metrics and thus is
+// healthy. The error is passed on to keep track of the handled metrics.
+func isPartialWrite(err error) bool {
+	var writeErr *internal.PartialWriteError
+	if !errors.As(err, &writeErr) {
+		return false
+	}
+	return len(writeErr.MetricsAccept) > 0 || len(writeErr.MetricsReject) > 0
+}
+
+func init() {
+	outputs.Add("failover", func() telegraf.Output {
+		return &Failover{
+			RecoveryInterval: config.Duration(time.Minute),
+		}
+	})
+}
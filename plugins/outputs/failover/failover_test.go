@@ -0,0 +1,221 @@
+package failover
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	_ "github.com/influxdata/telegraf/plugins/outputs/file"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "primary.influx")
+	standby := filepath.Join(dir, "standby.influx")
+
+	cfg := `
+[[outputs.failover]]
+  recovery_interval = "10s"
+
+  [[outputs.failover.output.file]]
+    alias = "primary"
+    files = ["` + filepath.ToSlash(primary) + `"]
+    data_format = "influx"
+
+  [[outputs.failover.output.failover_mock]]
+    alias = "unused"
+
+  [[outputs.failover.output.file]]
+    alias = "standby"
+    files = ["` + filepath.ToSlash(standby) + `"]
+    data_format = "influx"
+`
+	outputs.Add("failover_mock", func() telegraf.Output { return &mockOutput{} })
+
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(cfg), config.EmptySourcePath))
+	require.Len(t, c.Outputs, 1)
+
+	plugin, ok := c.Outputs[0].Output.(*Failover)
+	require.True(t, ok)
+	require.Equal(t, config.Duration(10*time.Second), plugin.RecoveryInterval)
+	require.Len(t, plugin.outputs, 3)
+
+	names := make([]string, 0, len(plugin.outputs))
+	for _, o := range plugin.outputs {
+		names = append(names, o.LogName())
+	}
+	require.Equal(t, []string{"file::primary", "failover_mock::unused", "file::standby"}, names)
+
+	// Metrics must only be written to the primary output
+	plugin.Log = testutil.Logger{}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write([]telegraf.Metric{testutil.TestMetric(1)}))
+	require.NoError(t, plugin.Close())
+	require.FileExists(t, primary)
+	buf, err := os.ReadFile(standby)
+	require.NoError(t, err)
+	require.Empty(t, buf)
+}
+
+func TestConfigUnknownOption(t *testing.T) {
+	cfg := `
+[[outputs.failover]]
+  [[outputs.failover.output.file]]
+    files = ["stdout"]
+    foo = "bar"
+`
+	c := config.NewConfig()
+	require.ErrorContains(t, c.LoadConfigData([]byte(cfg), config.EmptySourcePath), "foo")
+}
+
+func TestFailoverAndRecovery(t *testing.T) {
+	primary := &mockOutput{}
+	standby := &mockOutput{}
+	plugin := &Failover{
+		RecoveryInterval: config.Duration(100 * time.Millisecond),
+		Log:              testutil.Logger{},
+	}
+	plugin.SetOutputs([]*outputs.ChildOutput{
+		{Name: "mock", Alias: "primary", Output: primary},
+		{Name: "mock", Alias: "standby", Output: standby},
+	})
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1)}
+
+	// Write to the primary if healthy
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, 1, primary.written())
+	require.Equal(t, 0, standby.written())
+
+	// Fail over to the standby and stick to it until the recovery interval
+	// elapsed
+	primary.setFail(true)
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, 2, primary.attempts())
+	require.Equal(t, 2, standby.written())
+
+	// Recovering fails if the primary is still down
+	time.Sleep(time.Duration(plugin.RecoveryInterval))
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, 3, primary.attempts())
+	require.Equal(t, 3, standby.written())
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, 3, primary.attempts())
+
+	// Switch back to the primary once it recovered
+	primary.setFail(false)
+	time.Sleep(time.Duration(plugin.RecoveryInterval))
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, 3, primary.written())
+	require.Equal(t, 4, standby.written())
+}
+
+func TestAllOutputsFailing(t *testing.T) {
+	primary := &mockOutput{fail: true}
+	standby := &mockOutput{fail: true}
+	plugin := &Failover{Log: testutil.Logger{}}
+	plugin.SetOutputs([]*outputs.ChildOutput{
+		{Name: "mock", Alias: "primary", Output: primary},
+		{Name: "mock", Alias: "standby", Output: standby},
+	})
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{testutil.TestMetric(1)}), "writing to all outputs failed")
+	require.Equal(t, 1, primary.attempts())
+	require.Equal(t, 1, standby.attempts())
+}
+
+func TestPartialWrite(t *testing.T) {
+	primary := &mockOutput{partial: true}
+	standby := &mockOutput{}
+	plugin := &Failover{Log: testutil.Logger{}}
+	plugin.SetOutputs([]*outputs.ChildOutput{
+		{Name: "mock", Alias: "primary", Output: primary},
+		{Name: "mock", Alias: "standby", Output: standby},
+	})
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// A partial write is passed on without failing over
+	var werr *internal.PartialWriteError
+	require.ErrorAs(t, plugin.Write([]telegraf.Metric{testutil.TestMetric(1), testutil.TestMetric(2)}), &werr)
+	require.Equal(t, []int{0}, werr.MetricsAccept)
+	require.Equal(t, 0, standby.attempts())
+}
+
+type mockOutput struct {
+	fail     bool
+	partial  bool
+	writes   int
+	received int
+	sync.Mutex
+}
+
+func (*mockOutput) SampleConfig() string {
+	return ""
+}
+
+func (*mockOutput) Connect() error {
+	return nil
+}
+
+func (*mockOutput) Close() error {
+	return nil
+}
+
+func (m *mockOutput) Write(metrics []telegraf.Metric) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.writes++
+	if m.fail {
+		return errors.New("failed")
+	}
+	if m.partial && len(metrics) > 1 {
+		m.received++
+		return &internal.PartialWriteError{
+			Err:           internal.ErrSizeLimitReached,
+			MetricsAccept: []int{0},
+		}
+	}
+	m.received += len(metrics)
+	return nil
+}
+
+func (m *mockOutput) setFail(fail bool) {
+	m.Lock()
+	defer m.Unlock()
+	m.fail = fail
+}
+
+func (m *mockOutput) attempts() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.writes
+}
+
+func (m *mockOutput) written() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.received
+}
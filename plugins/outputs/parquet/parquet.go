@@ -6,17 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
 	"github.com/apache/arrow-go/v18/parquet/pqarrow"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
@@ -25,20 +31,57 @@ var sampleConfig string
 
 var defaultTimestampFieldName = "timestamp"
 
+// Name of the Hive partition used for metrics without the partition tag
+const hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// Minimum interval between warnings about values written as null
+const conversionWarningInterval = time.Minute
+
+var compressionCodecs = map[string]compress.Compression{
+	"uncompressed": compress.Codecs.Uncompressed,
+	"snappy":       compress.Codecs.Snappy,
+	"gzip":         compress.Codecs.Gzip,
+	"brotli":       compress.Codecs.Brotli,
+	"zstd":         compress.Codecs.Zstd,
+	"lz4_raw":      compress.Codecs.Lz4Raw,
+}
+
 type metricGroup struct {
-	filename string
-	builder  *array.RecordBuilder
-	schema   *arrow.Schema
-	writer   *pqarrow.FileWriter
+	name          string
+	directory     string
+	timePartition string
+	filename      string
+	opened        time.Time
+	builder       *array.RecordBuilder
+	schema        *arrow.Schema
+	writer        *pqarrow.FileWriter
 }
 
-type Parquet struct {
-	Directory          string          `toml:"directory"`
-	RotationInterval   config.Duration `toml:"rotation_interval"`
-	TimestampFieldName string          `toml:"timestamp_field_name"`
-	Log                telegraf.Logger `toml:"-"`
+// conversionFailures counts the values of a column written as null because
+// they could not be converted to the column type
+type conversionFailures struct {
+	count int
+	err   error
+}
 
-	metricGroups map[string]*metricGroup
+type Parquet struct {
+	Directory           string          `toml:"directory"`
+	RotationInterval    config.Duration `toml:"rotation_interval"`
+	TimestampFieldName  string          `toml:"timestamp_field_name"`
+	Partitioning        string          `toml:"partitioning"`
+	PartitionTags       []string        `toml:"partition_tags"`
+	PartitionTimeKey    string          `toml:"partition_time_key"`
+	PartitionTimeLayout string          `toml:"partition_time_layout"`
+	Compression         string          `toml:"compression"`
+	RowGroupSize        int64           `toml:"row_group_size"`
+	SchemaEvolution     string          `toml:"schema_evolution"`
+	Log                 telegraf.Logger `toml:"-"`
+
+	writerProperties *parquet.WriterProperties
+	metricGroups     map[string]*metricGroup
+
+	conversionFailures    map[string]*conversionFailures
+	lastConversionWarning time.Time
 }
 
 func (*Parquet) SampleConfig() string {
@@ -50,6 +93,43 @@ func (p *Parquet) Init() error {
 		p.Directory = "."
 	}
 
+	switch p.Partitioning {
+	case "":
+		p.Partitioning = "none"
+	case "none":
+	case "hive":
+		if p.PartitionTimeLayout != "" && p.PartitionTimeKey == "" {
+			return errors.New("'partition_time_key' required for time partitioning")
+		}
+	default:
+		return fmt.Errorf("invalid 'partitioning' setting %q", p.Partitioning)
+	}
+
+	switch p.SchemaEvolution {
+	case "":
+		p.SchemaEvolution = "widen"
+	case "widen", "new_file":
+	default:
+		return fmt.Errorf("invalid 'schema_evolution' setting %q", p.SchemaEvolution)
+	}
+
+	if p.Compression == "" {
+		p.Compression = "uncompressed"
+	}
+	codec, found := compressionCodecs[p.Compression]
+	if !found {
+		return fmt.Errorf("invalid 'compression' setting %q", p.Compression)
+	}
+	props := []parquet.WriterProperty{parquet.WithCompression(codec)}
+
+	if p.RowGroupSize < 0 {
+		return fmt.Errorf("invalid 'row_group_size' setting %d", p.RowGroupSize)
+	}
+	if p.RowGroupSize > 0 {
+		props = append(props, parquet.WithMaxRowGroupLength(p.RowGroupSize))
+	}
+	p.writerProperties = parquet.NewWriterProperties(props...)
+
 	stat, err := os.Stat(p.Directory)
 	if os.IsNotExist(err) {
 		if err := os.MkdirAll(p.Directory, 0750); err != nil {
@@ -60,6 +140,7 @@ func (p *Parquet) Init() error {
 	}
 
 	p.metricGroups = make(map[string]*metricGroup)
+	p.conversionFailures = make(map[string]*conversionFailures)
 
 	return nil
 }
@@ -88,68 +169,156 @@ func (p *Parquet) Close() error {
 func (p *Parquet) Write(metrics []telegraf.Metric) error {
 	groupedMetrics := make(map[string][]telegraf.Metric)
 	for _, metric := range metrics {
-		groupedMetrics[metric.Name()] = append(groupedMetrics[metric.Name()], metric)
+		key := p.partition(metric)
+		groupedMetrics[key] = append(groupedMetrics[key], metric)
 	}
 
 	now := time.Now()
-	for name, metrics := range groupedMetrics {
-		if _, ok := p.metricGroups[name]; !ok {
-			filename := fmt.Sprintf("%s/%s-%s-%s.parquet", p.Directory, name, now.Format("2006-01-02"), strconv.FormatInt(now.Unix(), 10))
-			schema, err := p.createSchema(metrics)
-			if err != nil {
-				return fmt.Errorf("failed to create schema for file %q: %w", name, err)
+	for key, metrics := range groupedMetrics {
+		schema, err := p.createSchema(metrics)
+		if err != nil {
+			return fmt.Errorf("failed to create schema for %q: %w", key, err)
+		}
+
+		group, ok := p.metricGroups[key]
+		if !ok {
+			group = &metricGroup{
+				name:      metrics[0].Name(),
+				directory: p.Directory,
 			}
-			writer, err := p.createWriter(name, filename, schema)
-			if err != nil {
-				return fmt.Errorf("failed to create writer for file %q: %w", name, err)
+			if p.Partitioning == "hive" {
+				group.directory = filepath.Join(p.Directory, key)
+				if p.PartitionTimeLayout != "" {
+					group.timePartition = metrics[0].Time().UTC().Format(p.PartitionTimeLayout)
+				}
 			}
-			p.metricGroups[name] = &metricGroup{
-				builder:  array.NewRecordBuilder(memory.DefaultAllocator, schema),
-				filename: filename,
-				schema:   schema,
-				writer:   writer,
+			if err := p.openFile(group, schema); err != nil {
+				return err
 			}
-		}
-
-		if p.RotationInterval != 0 {
-			if err := p.rotateIfNeeded(name); err != nil {
-				return fmt.Errorf("failed to rotate file %q: %w", p.metricGroups[name].filename, err)
+			p.metricGroups[key] = group
+		} else if evolved := p.evolveSchema(group.schema, schema); evolved != nil {
+			p.Log.Debugf("Schema of %q changed, starting a new file", key)
+			if err := p.rotate(group, evolved); err != nil {
+				return err
+			}
+		} else if p.RotationInterval != 0 && now.Sub(group.opened) >= time.Duration(p.RotationInterval) {
+			if err := p.rotate(group, group.schema); err != nil {
+				return err
 			}
 		}
 
-		record, err := p.createRecord(metrics, p.metricGroups[name].builder, p.metricGroups[name].schema)
+		record, err := p.createRecord(metrics, group.builder, group.schema)
 		if err != nil {
-			return fmt.Errorf("failed to create record for file %q: %w", p.metricGroups[name].filename, err)
+			return fmt.Errorf("failed to create record for file %q: %w", group.filename, err)
 		}
-		if err = p.metricGroups[name].writer.WriteBuffered(record); err != nil {
-			return fmt.Errorf("failed to write to file %q: %w", p.metricGroups[name].filename, err)
+		if err = group.writer.WriteBuffered(record); err != nil {
+			return fmt.Errorf("failed to write to file %q: %w", group.filename, err)
 		}
 		record.Release()
 	}
 
+	p.closeOutdatedPartitions(groupedMetrics, now)
+	p.warnConversionFailures(now)
+
 	return nil
 }
 
-func (p *Parquet) rotateIfNeeded(name string) error {
-	fileInfo, err := os.Stat(p.metricGroups[name].filename)
-	if err != nil {
-		return fmt.Errorf("failed to stat file %q: %w", p.metricGroups[name].filename, err)
+// warnConversionFailures reports the values written as null since the last
+// warning, at most once per interval to not flood the log
+func (p *Parquet) warnConversionFailures(now time.Time) {
+	if len(p.conversionFailures) == 0 || now.Sub(p.lastConversionWarning) < conversionWarningInterval {
+		return
 	}
+	p.lastConversionWarning = now
 
-	expireTime := fileInfo.ModTime().Add(time.Duration(p.RotationInterval))
-	if time.Now().Before(expireTime) {
-		return nil
+	columns := make([]string, 0, len(p.conversionFailures))
+	for column := range p.conversionFailures {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		f := p.conversionFailures[column]
+		p.Log.Warnf("Converting %d value(s) of column %q failed, writing null instead: %v", f.count, column, f.err)
+	}
+	clear(p.conversionFailures)
+}
+
+// partition returns the key of the group the metric is written to, i.e. the
+// metric name or the relative directory of the Hive partition
+func (p *Parquet) partition(metric telegraf.Metric) string {
+	if p.Partitioning != "hive" {
+		return metric.Name()
+	}
+
+	segments := make([]string, 0, len(p.PartitionTags)+2)
+	segments = append(segments, hivePartition("measurement", metric.Name()))
+	for _, key := range p.PartitionTags {
+		value, _ := metric.GetTag(key)
+		segments = append(segments, hivePartition(key, value))
+	}
+	if p.PartitionTimeLayout != "" {
+		segments = append(segments, hivePartition(p.PartitionTimeKey, metric.Time().UTC().Format(p.PartitionTimeLayout)))
+	}
+	return filepath.Join(segments...)
+}
+
+// closeOutdatedPartitions closes the files of time partitions which did not
+// receive metrics in this write and are not the current time partition.
+// Late metrics will start a new file in the partition.
+func (p *Parquet) closeOutdatedPartitions(written map[string][]telegraf.Metric, now time.Time) {
+	if p.Partitioning != "hive" || p.PartitionTimeLayout == "" {
+		return
 	}
 
-	if err := p.metricGroups[name].writer.Close(); err != nil {
-		return fmt.Errorf("failed to close file for rotation %q: %w", p.metricGroups[name].filename, err)
+	current := now.UTC().Format(p.PartitionTimeLayout)
+	for key, group := range p.metricGroups {
+		if _, ok := written[key]; ok || group.timePartition == current {
+			continue
+		}
+		if err := group.writer.Close(); err != nil {
+			p.Log.Errorf("failed to close file %q: %v", group.filename, err)
+		}
+		delete(p.metricGroups, key)
 	}
+}
 
-	writer, err := p.createWriter(name, p.metricGroups[name].filename, p.metricGroups[name].schema)
+// rotate closes the current file of the group and continues with a new file
+// using the given schema
+func (p *Parquet) rotate(group *metricGroup, schema *arrow.Schema) error {
+	if err := group.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close file for rotation %q: %w", group.filename, err)
+	}
+	return p.openFile(group, schema)
+}
+
+func (p *Parquet) openFile(group *metricGroup, schema *arrow.Schema) error {
+	if err := os.MkdirAll(group.directory, 0750); err != nil {
+		return fmt.Errorf("failed to create directory %q: %w", group.directory, err)
+	}
+
+	now := time.Now()
+	base := fmt.Sprintf("%s-%s-%s", group.name, now.Format("2006-01-02"), strconv.FormatInt(now.Unix(), 10))
+	filename := filepath.Join(group.directory, base+".parquet")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			break
+		}
+		filename = filepath.Join(group.directory, fmt.Sprintf("%s-%d.parquet", base, i))
+	}
+
+	writer, err := p.createWriter(filename, schema)
 	if err != nil {
-		return fmt.Errorf("failed to create new writer for file %q: %w", p.metricGroups[name].filename, err)
+		return fmt.Errorf("failed to create writer for file %q: %w", filename, err)
 	}
-	p.metricGroups[name].writer = writer
+
+	if group.builder != nil {
+		group.builder.Release()
+	}
+	group.filename = filename
+	group.opened = now
+	group.schema = schema
+	group.builder = array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	group.writer = writer
 
 	return nil
 }
@@ -172,65 +341,19 @@ func (p *Parquet) createRecord(metrics []telegraf.Metric, builder *array.RecordB
 
 			// if neither field nor tag exists, append a null value
 			if !ok {
-				switch col.Type {
-				case arrow.PrimitiveTypes.Int8:
-					builder.Field(index).(*array.Int8Builder).AppendNull()
-				case arrow.PrimitiveTypes.Int16:
-					builder.Field(index).(*array.Int16Builder).AppendNull()
-				case arrow.PrimitiveTypes.Int32:
-					builder.Field(index).(*array.Int32Builder).AppendNull()
-				case arrow.PrimitiveTypes.Int64:
-					builder.Field(index).(*array.Int64Builder).AppendNull()
-				case arrow.PrimitiveTypes.Uint8:
-					builder.Field(index).(*array.Uint8Builder).AppendNull()
-				case arrow.PrimitiveTypes.Uint16:
-					builder.Field(index).(*array.Uint16Builder).AppendNull()
-				case arrow.PrimitiveTypes.Uint32:
-					builder.Field(index).(*array.Uint32Builder).AppendNull()
-				case arrow.PrimitiveTypes.Uint64:
-					builder.Field(index).(*array.Uint64Builder).AppendNull()
-				case arrow.PrimitiveTypes.Float32:
-					builder.Field(index).(*array.Float32Builder).AppendNull()
-				case arrow.PrimitiveTypes.Float64:
-					builder.Field(index).(*array.Float64Builder).AppendNull()
-				case arrow.BinaryTypes.String:
-					builder.Field(index).(*array.StringBuilder).AppendNull()
-				case arrow.FixedWidthTypes.Boolean:
-					builder.Field(index).(*array.BooleanBuilder).AppendNull()
-				default:
-					return nil, fmt.Errorf("unsupported type: %T", value)
-				}
-
+				builder.Field(index).AppendNull()
 				continue
 			}
 
-			switch col.Type {
-			case arrow.PrimitiveTypes.Int8:
-				builder.Field(index).(*array.Int8Builder).Append(value.(int8))
-			case arrow.PrimitiveTypes.Int16:
-				builder.Field(index).(*array.Int16Builder).Append(value.(int16))
-			case arrow.PrimitiveTypes.Int32:
-				builder.Field(index).(*array.Int32Builder).Append(value.(int32))
-			case arrow.PrimitiveTypes.Int64:
-				builder.Field(index).(*array.Int64Builder).Append(value.(int64))
-			case arrow.PrimitiveTypes.Uint8:
-				builder.Field(index).(*array.Uint8Builder).Append(value.(uint8))
-			case arrow.PrimitiveTypes.Uint16:
-				builder.Field(index).(*array.Uint16Builder).Append(value.(uint16))
-			case arrow.PrimitiveTypes.Uint32:
-				builder.Field(index).(*array.Uint32Builder).Append(value.(uint32))
-			case arrow.PrimitiveTypes.Uint64:
-				builder.Field(index).(*array.Uint64Builder).Append(value.(uint64))
-			case arrow.PrimitiveTypes.Float32:
-				builder.Field(index).(*array.Float32Builder).Append(value.(float32))
-			case arrow.PrimitiveTypes.Float64:
-				builder.Field(index).(*array.Float64Builder).Append(value.(float64))
-			case arrow.BinaryTypes.String:
-				builder.Field(index).(*array.StringBuilder).Append(value.(string))
-			case arrow.FixedWidthTypes.Boolean:
-				builder.Field(index).(*array.BooleanBuilder).Append(value.(bool))
-			default:
-				return nil, fmt.Errorf("unsupported type: %T", value)
+			if err := appendValue(builder.Field(index), value); err != nil {
+				f, found := p.conversionFailures[col.Name]
+				if !found {
+					f = &conversionFailures{}
+					p.conversionFailures[col.Name] = f
+				}
+				f.count++
+				f.err = err
+				builder.Field(index).AppendNull()
 			}
 		}
 	}
@@ -239,32 +362,128 @@ func (p *Parquet) createRecord(metrics []telegraf.Metric, builder *array.RecordB
 	return record, nil
 }
 
+// appendValue appends the value converted to the type of the column
+func appendValue(builder array.Builder, value interface{}) error {
+	switch b := builder.(type) {
+	case *array.Int8Builder:
+		v, err := internal.ToInt8(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int16Builder:
+		v, err := internal.ToInt16(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int32Builder:
+		v, err := internal.ToInt32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int64Builder:
+		v, err := internal.ToInt64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint8Builder:
+		v, err := internal.ToUint8(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint16Builder:
+		v, err := internal.ToUint16(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint32Builder:
+		v, err := internal.ToUint32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint64Builder:
+		v, err := internal.ToUint64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float32Builder:
+		v, err := internal.ToFloat32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float64Builder:
+		v, err := internal.ToFloat64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.StringBuilder:
+		v, err := internal.ToString(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.BooleanBuilder:
+		v, err := internal.ToBool(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+	return nil
+}
+
 func (p *Parquet) createSchema(metrics []telegraf.Metric) (*arrow.Schema, error) {
 	rawFields := make(map[string]arrow.DataType, 0)
 	for _, metric := range metrics {
 		for _, field := range metric.FieldList() {
-			if _, ok := rawFields[field.Key]; !ok {
-				arrowType, err := goToArrowType(field.Value)
-				if err != nil {
-					return nil, fmt.Errorf("error converting '%s=%s' field to arrow type: %w", field.Key, field.Value, err)
-				}
-				rawFields[field.Key] = arrowType
+			arrowType, err := goToArrowType(field.Value)
+			if err != nil {
+				return nil, fmt.Errorf("error converting '%s=%s' field to arrow type: %w", field.Key, field.Value, err)
 			}
+			if existing, ok := rawFields[field.Key]; ok {
+				arrowType = widenType(existing, arrowType)
+			}
+			rawFields[field.Key] = arrowType
 		}
 		for _, tag := range metric.TagList() {
+			if p.Partitioning == "hive" && slices.Contains(p.PartitionTags, tag.Key) {
+				continue
+			}
 			if _, ok := rawFields[tag.Key]; !ok {
 				rawFields[tag.Key] = arrow.BinaryTypes.String
 			}
 		}
 	}
 
-	fields := make([]arrow.Field, 0)
-	for key, value := range rawFields {
+	return p.buildSchema(rawFields), nil
+}
+
+// buildSchema creates a schema with the columns sorted by name followed by
+// the timestamp column
+func (p *Parquet) buildSchema(columns map[string]arrow.DataType) *arrow.Schema {
+	fields := make([]arrow.Field, 0, len(columns)+1)
+	for key, value := range columns {
+		if p.TimestampFieldName != "" && key == p.TimestampFieldName {
+			continue
+		}
 		fields = append(fields, arrow.Field{
-			Name: key,
-			Type: value,
+			Name:     key,
+			Type:     value,
+			Nullable: true,
 		})
 	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
 
 	if p.TimestampFieldName != "" {
 		fields = append(fields, arrow.Field{
@@ -273,23 +492,50 @@ func (p *Parquet) createSchema(metrics []telegraf.Metric) (*arrow.Schema, error)
 		})
 	}
 
-	return arrow.NewSchema(fields, nil), nil
+	return arrow.NewSchema(fields, nil)
 }
 
-func (p *Parquet) createWriter(name, filename string, schema *arrow.Schema) (*pqarrow.FileWriter, error) {
-	if _, err := os.Stat(filename); err == nil {
-		now := time.Now()
-		rotatedFilename := fmt.Sprintf("%s/%s-%s-%s.parquet", p.Directory, name, now.Format("2006-01-02"), strconv.FormatInt(now.Unix(), 10))
-		if err := os.Rename(filename, rotatedFilename); err != nil {
-			return nil, fmt.Errorf("failed to rename file %q: %w", filename, err)
+// evolveSchema returns the schema of a new file if the metrics of the batch
+// schema do not fit into the current schema or nil otherwise. Depending on
+// the setting, the new schema either widens the current schema or is the
+// schema of the batch.
+func (p *Parquet) evolveSchema(current, batch *arrow.Schema) *arrow.Schema {
+	columns := make(map[string]arrow.DataType, len(current.Fields()))
+	for _, field := range current.Fields() {
+		columns[field.Name] = field.Type
+	}
+
+	var changed bool
+	for _, field := range batch.Fields() {
+		existing, ok := columns[field.Name]
+		if !ok {
+			columns[field.Name] = field.Type
+			changed = true
+			continue
+		}
+		widened := widenType(existing, field.Type)
+		if !arrow.TypeEqual(existing, widened) {
+			columns[field.Name] = widened
+			changed = true
 		}
 	}
+
+	if !changed {
+		return nil
+	}
+	if p.SchemaEvolution == "new_file" {
+		return batch
+	}
+	return p.buildSchema(columns)
+}
+
+func (p *Parquet) createWriter(filename string, schema *arrow.Schema) (*pqarrow.FileWriter, error) {
 	file, err := os.Create(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file %q: %w", filename, err)
 	}
 
-	writer, err := pqarrow.NewFileWriter(schema, file, parquet.NewWriterProperties(), pqarrow.DefaultWriterProps())
+	writer, err := pqarrow.NewFileWriter(schema, file, p.writerProperties, pqarrow.DefaultWriterProps())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create parquet writer for file %q: %w", filename, err)
 	}
@@ -297,6 +543,61 @@ func (p *Parquet) createWriter(name, filename string, schema *arrow.Schema) (*pq
 	return writer, nil
 }
 
+// hivePartition returns the directory name of a Hive partition escaping the
+// characters not allowed by Hive
+func hivePartition(key, value string) string {
+	if value == "" {
+		value = hiveDefaultPartition
+	}
+	return hiveEscape(key) + "=" + hiveEscape(value)
+}
+
+func hiveEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte(`"#%'*/:=?\{[]^`, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// widenType returns a type able to hold the values of both given types
+func widenType(a, b arrow.DataType) arrow.DataType {
+	if arrow.TypeEqual(a, b) {
+		return a
+	}
+
+	switch {
+	case arrow.IsSignedInteger(a.ID()) && arrow.IsSignedInteger(b.ID()),
+		arrow.IsUnsignedInteger(a.ID()) && arrow.IsUnsignedInteger(b.ID()),
+		arrow.IsFloating(a.ID()) && arrow.IsFloating(b.ID()):
+		if bitWidth(a) >= bitWidth(b) {
+			return a
+		}
+		return b
+	case arrow.IsInteger(a.ID()) && arrow.IsInteger(b.ID()):
+		return arrow.PrimitiveTypes.Int64
+	case isNumeric(a) && isNumeric(b):
+		return arrow.PrimitiveTypes.Float64
+	}
+	return arrow.BinaryTypes.String
+}
+
+func isNumeric(t arrow.DataType) bool {
+	return arrow.IsInteger(t.ID()) || arrow.IsFloating(t.ID())
+}
+
+func bitWidth(t arrow.DataType) int {
+	if fw, ok := t.(arrow.FixedWidthDataType); ok {
+		return fw.BitWidth()
+	}
+	return 0
+}
+
 func goToArrowType(value interface{}) (arrow.DataType, error) {
 	switch value.(type) {
 	case int8:
@@ -331,7 +632,9 @@ func goToArrowType(value interface{}) (arrow.DataType, error) {
 func init() {
 	outputs.Add("parquet", func() telegraf.Output {
 		return &Parquet{
-			TimestampFieldName: defaultTimestampFieldName,
+			TimestampFieldName:  defaultTimestampFieldName,
+			PartitionTimeKey:    "date",
+			PartitionTimeLayout: "2006-01-02",
 		}
 	})
 }
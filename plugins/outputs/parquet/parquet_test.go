@@ -1,11 +1,14 @@
 package parquet
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
 	"github.com/apache/arrow-go/v18/parquet/file"
 	"github.com/stretchr/testify/require"
 
@@ -245,3 +248,267 @@ func TestTimestampDifferentName(t *testing.T) {
 	require.Equal(t, 1, int(metadata.NumRows))
 	require.Equal(t, 2, metadata.Schema.NumColumns())
 }
+
+func TestHivePartitioning(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"value": 1.0},
+			time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu1"},
+			map[string]interface{}{"value": 2.0},
+			time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "b/c", "cpu": "cpu0"},
+			map[string]interface{}{"value": 3.0},
+			time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"value": 4.0},
+			time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+		),
+	}
+
+	testDir := t.TempDir()
+	plugin := &Parquet{
+		Directory:           testDir,
+		TimestampFieldName:  defaultTimestampFieldName,
+		Partitioning:        "hive",
+		PartitionTags:       []string{"host"},
+		PartitionTimeKey:    "date",
+		PartitionTimeLayout: "2006-01-02",
+		Log:                 testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	expected := map[string]int{
+		filepath.Join("measurement=cpu", "host=a", "date=2026-10-16"):                          2,
+		filepath.Join("measurement=cpu", "host=b%2Fc", "date=2026-10-17"):                      1,
+		filepath.Join("measurement=cpu", "host=__HIVE_DEFAULT_PARTITION__", "date=2026-10-17"): 1,
+	}
+	for dir, rows := range expected {
+		files, err := os.ReadDir(filepath.Join(testDir, dir))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		reader, err := file.OpenParquetFile(filepath.Join(testDir, dir, files[0].Name()), false)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		// The partition tag is not stored in the file
+		metadata := reader.MetaData()
+		require.Equal(t, rows, int(metadata.NumRows))
+		require.Equal(t, 3, metadata.Schema.NumColumns())
+		require.Equal(t, -1, metadata.Schema.ColumnIndexByName("host"))
+	}
+}
+
+func TestSchemaEvolution(t *testing.T) {
+	first := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{},
+			map[string]interface{}{"value": int64(1), "old": "x"},
+			time.Now(),
+		),
+	}
+	second := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{},
+			map[string]interface{}{"value": 1.5, "new": true},
+			time.Now(),
+		),
+	}
+	third := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{},
+			map[string]interface{}{"value": int64(2)},
+			time.Now(),
+		),
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		expected []string
+	}{
+		{
+			name:     "widen",
+			mode:     "widen",
+			expected: []string{"new", "old", "value", "timestamp"},
+		},
+		{
+			name:     "new file",
+			mode:     "new_file",
+			expected: []string{"new", "value", "timestamp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			plugin := &Parquet{
+				Directory:          testDir,
+				TimestampFieldName: defaultTimestampFieldName,
+				SchemaEvolution:    tt.mode,
+				Log:                testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			require.NoError(t, plugin.Write(first))
+			require.NoError(t, plugin.Write(second))
+			// Metrics fitting into the schema are written to the same file
+			require.NoError(t, plugin.Write(third))
+			require.NoError(t, plugin.Close())
+
+			files, err := os.ReadDir(testDir)
+			require.NoError(t, err)
+			require.Len(t, files, 2)
+
+			// The first file only contains the first metric
+			var reader *file.Reader
+			for _, f := range files {
+				r, err := file.OpenParquetFile(filepath.Join(testDir, f.Name()), false)
+				require.NoError(t, err)
+				defer r.Close()
+				if r.MetaData().NumRows == 2 {
+					reader = r
+				}
+			}
+			require.NotNil(t, reader)
+
+			metadata := reader.MetaData()
+			require.Equal(t, 2, int(metadata.NumRows))
+			columns := make([]string, 0, metadata.Schema.NumColumns())
+			for i := 0; i < metadata.Schema.NumColumns(); i++ {
+				columns = append(columns, metadata.Schema.Column(i).Name())
+			}
+			require.Equal(t, tt.expected, columns)
+
+			// The integer values are widened to float
+			idx := metadata.Schema.ColumnIndexByName("value")
+			require.Equal(t, parquet.Types.Double, metadata.Schema.Column(idx).PhysicalType())
+		})
+	}
+}
+
+func TestConversionFailureWarning(t *testing.T) {
+	testDir := t.TempDir()
+	logger := &testutil.CaptureLogger{}
+	plugin := &Parquet{
+		Directory:          testDir,
+		TimestampFieldName: defaultTimestampFieldName,
+		Log:                logger,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	// Unsigned integers exceeding the signed column type are written as null
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("test", map[string]string{}, map[string]interface{}{"value": int64(1)}, time.Now()),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	overflow := []telegraf.Metric{
+		testutil.MustMetric("test", map[string]string{}, map[string]interface{}{"value": uint64(math.MaxUint64)}, time.Now()),
+	}
+	require.NoError(t, plugin.Write(overflow))
+	require.NoError(t, plugin.Write(overflow))
+
+	// Failures are reported once per interval
+	warnings := logger.Warnings()
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], `Converting 1 value(s) of column "value" failed`)
+	require.Len(t, plugin.conversionFailures, 1)
+	require.Equal(t, 1, plugin.conversionFailures["value"].count)
+	require.NoError(t, plugin.Close())
+}
+
+func TestCompressionAndRowGroupSize(t *testing.T) {
+	metrics := make([]telegraf.Metric, 0, 5)
+	for i := 0; i < 5; i++ {
+		metrics = append(metrics, testutil.MustMetric(
+			"test",
+			map[string]string{},
+			map[string]interface{}{"value": float64(i)},
+			time.Now(),
+		))
+	}
+
+	testDir := t.TempDir()
+	plugin := &Parquet{
+		Directory:          testDir,
+		TimestampFieldName: defaultTimestampFieldName,
+		Compression:        "zstd",
+		RowGroupSize:       2,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	files, err := os.ReadDir(testDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	reader, err := file.OpenParquetFile(filepath.Join(testDir, files[0].Name()), false)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	metadata := reader.MetaData()
+	require.Equal(t, 5, int(metadata.NumRows))
+	require.Equal(t, 3, metadata.NumRowGroups())
+	chunk, err := metadata.RowGroup(0).ColumnChunk(0)
+	require.NoError(t, err)
+	require.Equal(t, compress.Codecs.Zstd, chunk.Compression())
+}
+
+func TestInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Parquet
+		expected string
+	}{
+		{
+			name:     "partitioning",
+			plugin:   &Parquet{Partitioning: "foo"},
+			expected: `invalid 'partitioning' setting "foo"`,
+		},
+		{
+			name:     "compression",
+			plugin:   &Parquet{Compression: "foo"},
+			expected: `invalid 'compression' setting "foo"`,
+		},
+		{
+			name:     "row group size",
+			plugin:   &Parquet{RowGroupSize: -1},
+			expected: "invalid 'row_group_size' setting -1",
+		},
+		{
+			name:     "schema evolution",
+			plugin:   &Parquet{SchemaEvolution: "foo"},
+			expected: `invalid 'schema_evolution' setting "foo"`,
+		},
+		{
+			name:     "missing time key",
+			plugin:   &Parquet{Partitioning: "hive", PartitionTimeLayout: "2006-01-02"},
+			expected: "'partition_time_key' required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Directory = t.TempDir()
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package prometheus_remote_write
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
+)
+
+// series is a Prometheus time-series with its samples or histograms
+type series struct {
+	key    uint64
+	family string
+	prompb.TimeSeries
+}
+
+// histogramPoint collects the buckets, sum and count of a Telegraf histogram
+// spread across multiple fields and metrics to form a native histogram
+type histogramPoint struct {
+	timestamp int64
+	buckets   map[float64]uint64
+	sum       float64
+	count     uint64
+	hasCount  bool
+}
+
+// converter converts Telegraf metrics into Prometheus time-series
+type converter struct {
+	stringAsLabel    bool
+	nativeHistograms bool
+	schema           int32
+
+	series     map[uint64]*series
+	order      []uint64
+	metadata   map[string]prompb.MetricMetadata
+	histograms map[uint64]map[int64]*histogramPoint
+	lastErr    error
+	dropped    int
+}
+
+func newConverter(stringAsLabel, nativeHistograms bool, schema int32) *converter {
+	return &converter{
+		stringAsLabel:    stringAsLabel,
+		nativeHistograms: nativeHistograms,
+		schema:           schema,
+		series:           make(map[uint64]*series),
+		metadata:         make(map[string]prompb.MetricMetadata),
+		histograms:       make(map[uint64]map[int64]*histogramPoint),
+	}
+}
+
+func (c *converter) add(m telegraf.Metric) {
+	labels := c.commonLabels(m)
+	ts := m.Time().UnixMilli()
+
+	for _, field := range m.FieldList() {
+		rawName := prometheus.MetricName(m.Name(), field.Key, m.Type())
+		name, ok := prometheus.SanitizeMetricName(rawName)
+		if !ok {
+			c.drop(fmt.Errorf("invalid metric name %q", rawName))
+			continue
+		}
+
+		switch m.Type() {
+		case telegraf.Counter, telegraf.Gauge, telegraf.Untyped:
+			value, ok := prometheus.SampleValue(field.Value)
+			if !ok {
+				c.drop(fmt.Errorf("bad sample value %#v for %q", field.Value, name))
+				continue
+			}
+			c.addMetadata(name, m.Type())
+			c.addSample(name, name, labels, ts, value)
+		case telegraf.Histogram:
+			c.addMetadata(name, m.Type())
+			if c.nativeHistograms {
+				if err := c.addHistogramField(m, field, name, labels, ts); err != nil {
+					c.drop(err)
+				}
+				continue
+			}
+			if err := c.addClassicHistogramField(m, field, name, labels, ts); err != nil {
+				c.drop(err)
+			}
+		case telegraf.Summary:
+			c.addMetadata(name, m.Type())
+			if err := c.addSummaryField(m, field, name, labels, ts); err != nil {
+				c.drop(err)
+			}
+		default:
+			c.drop(fmt.Errorf("unknown type %v", m.Type()))
+		}
+	}
+}
+
+func (c *converter) addClassicHistogramField(m telegraf.Metric, field *telegraf.Field, name string, labels []prompb.Label, ts int64) error {
+	switch {
+	case strings.HasSuffix(field.Key, "_bucket"):
+		bound, err := boundTag(m, "le")
+		if err != nil {
+			return fmt.Errorf("invalid bucket for %q: %w", name, err)
+		}
+		count, ok := prometheus.SampleCount(field.Value)
+		if !ok {
+			return fmt.Errorf("bad bucket count %#v for %q", field.Value, name)
+		}
+		le := prompb.Label{Name: "le", Value: formatBound(bound)}
+		c.addSample(name, name+"_bucket", labels, ts, float64(count), le)
+	case strings.HasSuffix(field.Key, "_sum"):
+		sum, ok := prometheus.SampleSum(field.Value)
+		if !ok {
+			return fmt.Errorf("bad sum %#v for %q", field.Value, name)
+		}
+		c.addSample(name, name+"_sum", labels, ts, sum)
+	case strings.HasSuffix(field.Key, "_count"):
+		count, ok := prometheus.SampleCount(field.Value)
+		if !ok {
+			return fmt.Errorf("bad count %#v for %q", field.Value, name)
+		}
+		// The count equals the +Inf bucket
+		le := prompb.Label{Name: "le", Value: "+Inf"}
+		c.addSample(name, name+"_bucket", labels, ts, float64(count), le)
+		c.addSample(name, name+"_count", labels, ts, float64(count))
+	default:
+		return fmt.Errorf("series %q of %q should have `_count`, `_sum` or `_bucket` suffix", field.Key, name)
+	}
+	return nil
+}
+
+func (c *converter) addSummaryField(m telegraf.Metric, field *telegraf.Field, name string, labels []prompb.Label, ts int64) error {
+	switch {
+	case strings.HasSuffix(field.Key, "_sum"):
+		sum, ok := prometheus.SampleSum(field.Value)
+		if !ok {
+			return fmt.Errorf("bad sum %#v for %q", field.Value, name)
+		}
+		c.addSample(name, name+"_sum", labels, ts, sum)
+	case strings.HasSuffix(field.Key, "_count"):
+		count, ok := prometheus.SampleCount(field.Value)
+		if !ok {
+			return fmt.Errorf("bad count %#v for %q", field.Value, name)
+		}
+		c.addSample(name, name+"_count", labels, ts, float64(count))
+	default:
+		quantile, err := boundTag(m, "quantile")
+		if err != nil {
+			return fmt.Errorf("invalid quantile for %q: %w", name, err)
+		}
+		value, ok := prometheus.SampleValue(field.Value)
+		if !ok {
+			return fmt.Errorf("bad sample value %#v for %q", field.Value, name)
+		}
+		q := prompb.Label{Name: "quantile", Value: formatBound(quantile)}
+		c.addSample(name, name, labels, ts, value, q)
+	}
+	return nil
+}
+
+func (c *converter) addHistogramField(m telegraf.Metric, field *telegraf.Field, name string, labels []prompb.Label, ts int64) error {
+	s := c.getSeries(name, name, labels)
+	points, found := c.histograms[s.key]
+	if !found {
+		points = make(map[int64]*histogramPoint)
+		c.histograms[s.key] = points
+	}
+	p, found := points[ts]
+	if !found {
+		p = &histogramPoint{timestamp: ts, buckets: make(map[float64]uint64)}
+		points[ts] = p
+	}
+
+	switch {
+	case strings.HasSuffix(field.Key, "_bucket"):
+		bound, err := boundTag(m, "le")
+		if err != nil {
+			return fmt.Errorf("invalid bucket for %q: %w", name, err)
+		}
+		count, ok := prometheus.SampleCount(field.Value)
+		if !ok {
+			return fmt.Errorf("bad bucket count %#v for %q", field.Value, name)
+		}
+		if !math.IsInf(bound, 1) {
+			p.buckets[bound] = count
+		} else if !p.hasCount {
+			p.count = count
+		}
+	case strings.HasSuffix(field.Key, "_sum"):
+		sum, ok := prometheus.SampleSum(field.Value)
+		if !ok {
+			return fmt.Errorf("bad sum %#v for %q", field.Value, name)
+		}
+		p.sum = sum
+	case strings.HasSuffix(field.Key, "_count"):
+		count, ok := prometheus.SampleCount(field.Value)
+		if !ok {
+			return fmt.Errorf("bad count %#v for %q", field.Value, name)
+		}
+		p.count = count
+		p.hasCount = true
+	default:
+		return fmt.Errorf("series %q of %q should have `_count`, `_sum` or `_bucket` suffix", field.Key, name)
+	}
+	return nil
+}
+
+// result returns the converted series with their samples sorted by time
+func (c *converter) result() []*series {
+	for key, points := range c.histograms {
+		s := c.series[key]
+		for _, p := range points {
+			s.Histograms = append(s.Histograms, nativeHistogram(p, c.schema))
+		}
+		sort.Slice(s.Histograms, func(i, j int) bool { return s.Histograms[i].Timestamp < s.Histograms[j].Timestamp })
+	}
+
+	result := make([]*series, 0, len(c.order))
+	for _, key := range c.order {
+		s := c.series[key]
+		if len(s.Samples) == 0 && len(s.Histograms) == 0 {
+			continue
+		}
+		sort.SliceStable(s.Samples, func(i, j int) bool { return s.Samples[i].Timestamp < s.Samples[j].Timestamp })
+		result = append(result, s)
+	}
+	return result
+}
+
+func (c *converter) addSample(family, name string, labels []prompb.Label, ts int64, value float64, extra ...prompb.Label) {
+	s := c.getSeries(family, name, labels, extra...)
+
+	// Replace samples with the same timestamp as Prometheus does not accept
+	// multiple values for the same point in time
+	for i := range s.Samples {
+		if s.Samples[i].Timestamp == ts {
+			s.Samples[i].Value = value
+			return
+		}
+	}
+	s.Samples = append(s.Samples, prompb.Sample{Timestamp: ts, Value: value})
+}
+
+func (c *converter) getSeries(family, name string, labels []prompb.Label, extra ...prompb.Label) *series {
+	l := make([]prompb.Label, 0, len(labels)+len(extra)+1)
+	l = append(l, labels...)
+	l = append(l, extra...)
+	l = append(l, prompb.Label{Name: "__name__", Value: name})
+
+	// Prometheus requires the labels to be sorted by name
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+
+	key := seriesKey(l)
+	s, found := c.series[key]
+	if !found {
+		s = &series{key: key, family: family, TimeSeries: prompb.TimeSeries{Labels: l}}
+		c.series[key] = s
+		c.order = append(c.order, key)
+	}
+	return s
+}
+
+func (c *converter) addMetadata(family string, valueType telegraf.ValueType) {
+	if _, found := c.metadata[family]; found {
+		return
+	}
+
+	var t prompb.MetricMetadata_MetricType
+	switch valueType {
+	case telegraf.Counter:
+		t = prompb.MetricMetadata_COUNTER
+	case telegraf.Gauge:
+		t = prompb.MetricMetadata_GAUGE
+	case telegraf.Histogram:
+		t = prompb.MetricMetadata_HISTOGRAM
+	case telegraf.Summary:
+		t = prompb.MetricMetadata_SUMMARY
+	default:
+		t = prompb.MetricMetadata_UNKNOWN
+	}
+	c.metadata[family] = prompb.MetricMetadata{Type: t, MetricFamilyName: family}
+}
+
+func (c *converter) commonLabels(m telegraf.Metric) []prompb.Label {
+	labels := make([]prompb.Label, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		// Ignore special tags for histogram and summary types
+		switch {
+		case m.Type() == telegraf.Histogram && tag.Key == "le":
+			continue
+		case m.Type() == telegraf.Summary && tag.Key == "quantile":
+			continue
+		}
+
+		name, ok := prometheus.SanitizeLabelName(tag.Key)
+		if !ok || tag.Value == "" {
+			continue
+		}
+		labels = append(labels, prompb.Label{Name: name, Value: tag.Value})
+	}
+
+	if !c.stringAsLabel {
+		return labels
+	}
+
+	for _, field := range m.FieldList() {
+		value, ok := field.Value.(string)
+		if !ok {
+			continue
+		}
+		name, ok := prometheus.SanitizeLabelName(field.Key)
+		if !ok || hasLabel(name, labels) {
+			continue
+		}
+		labels = append(labels, prompb.Label{Name: name, Value: value})
+	}
+	return labels
+}
+
+func (c *converter) drop(err error) {
+	c.lastErr = err
+	c.dropped++
+}
+
+// nativeHistogram converts the cumulative buckets of a classic histogram into
+// a native histogram with exponential buckets of the given schema. The counts
+// of each classic bucket are assigned to the exponential bucket containing the
+// upper bound of the classic bucket. Buckets with a non-positive upper bound
+// are counted in the zero bucket and the observations above the largest bound
+// are assigned to the bucket following the one of the largest bound.
+func nativeHistogram(p *histogramPoint, schema int32) prompb.Histogram {
+	bounds := make([]float64, 0, len(p.buckets))
+	for bound := range p.buckets {
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+
+	counts := make(map[int32]int64)
+	var zeroCount, previous uint64
+	var index int32
+	for _, bound := range bounds {
+		// Ignore decreasing cumulative counts of inconsistent buckets
+		cumulative := max(p.buckets[bound], previous)
+		count := cumulative - previous
+		previous = cumulative
+
+		if bound <= 0 {
+			zeroCount += count
+			continue
+		}
+		index = bucketIndex(bound, schema)
+		if count > 0 {
+			counts[index] += int64(count)
+		}
+	}
+
+	total := max(p.count, previous)
+	if overflow := total - previous; overflow > 0 {
+		counts[index+1] += int64(overflow)
+	}
+
+	spans, deltas := encodeBuckets(counts)
+	return prompb.Histogram{
+		Count:          &prompb.Histogram_CountInt{CountInt: total},
+		Sum:            p.sum,
+		Schema:         schema,
+		ZeroCount:      &prompb.Histogram_ZeroCountInt{ZeroCountInt: zeroCount},
+		PositiveSpans:  spans,
+		PositiveDeltas: deltas,
+		Timestamp:      p.timestamp,
+	}
+}
+
+// bucketIndex returns the index of the exponential bucket containing the
+// given value, i.e. the bucket with index i covering (base^(i-1), base^i] with
+// base = 2^(2^-schema).
+func bucketIndex(value float64, schema int32) int32 {
+	return int32(math.Ceil(math.Log2(value) * math.Ldexp(1, int(schema))))
+}
+
+// encodeBuckets encodes the bucket counts as spans of consecutive buckets and
+// the deltas between the bucket counts as required by the protocol
+func encodeBuckets(counts map[int32]int64) ([]prompb.BucketSpan, []int64) {
+	indices := make([]int32, 0, len(counts))
+	for idx := range counts {
+		indices = append(indices, idx)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	spans := make([]prompb.BucketSpan, 0)
+	deltas := make([]int64, 0, len(indices))
+	var previousIndex int32
+	var previousCount int64
+	for i, idx := range indices {
+		switch {
+		case i == 0:
+			spans = append(spans, prompb.BucketSpan{Offset: idx, Length: 1})
+		case idx == previousIndex+1:
+			spans[len(spans)-1].Length++
+		default:
+			spans = append(spans, prompb.BucketSpan{Offset: idx - previousIndex - 1, Length: 1})
+		}
+		deltas = append(deltas, counts[idx]-previousCount)
+		previousIndex = idx
+		previousCount = counts[idx]
+	}
+	return spans, deltas
+}
+
+func boundTag(m telegraf.Metric, key string) (float64, error) {
+	v, ok := m.GetTag(key)
+	if !ok {
+		return 0, fmt.Errorf("missing %q tag", key)
+	}
+	bound, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q tag failed: %w", key, err)
+	}
+	if math.IsNaN(bound) {
+		return 0, errors.New("bound is not a number")
+	}
+	return bound, nil
+}
+
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'g', -1, 64)
+}
+
+func hasLabel(name string, labels []prompb.Label) bool {
+	for _, label := range labels {
+		if name == label.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func seriesKey(labels []prompb.Label) uint64 {
+	h := fnv.New64a()
+	for _, label := range labels {
+		h.Write([]byte(label.Name))
+		h.Write([]byte("\x00"))
+		h.Write([]byte(label.Value))
+		h.Write([]byte("\x00"))
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,381 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package prometheus_remote_write
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const maxErrMsgLen = 1024
+
+type PrometheusRemoteWrite struct {
+	URL                   string                    `toml:"url"`
+	Username              config.Secret             `toml:"username"`
+	Password              config.Secret             `toml:"password"`
+	BearerToken           config.Secret             `toml:"bearer_token"`
+	Headers               map[string]*config.Secret `toml:"headers"`
+	Shards                int                       `toml:"shards"`
+	MaxRequestSize        config.Size               `toml:"max_request_size"`
+	MaxRetries            int                       `toml:"max_retries"`
+	RetryBackoff          config.Duration           `toml:"retry_backoff"`
+	MaxRetryBackoff       config.Duration           `toml:"max_retry_backoff"`
+	SendMetadata          bool                      `toml:"send_metadata"`
+	StringAsLabel         bool                      `toml:"string_as_label"`
+	NativeHistograms      bool                      `toml:"native_histograms"`
+	NativeHistogramSchema int32                     `toml:"native_histogram_schema"`
+	Log                   telegraf.Logger           `toml:"-"`
+	common_http.HTTPClientConfig
+
+	client *http.Client
+
+	// Time before which no requests are sent as requested by the server
+	notBefore   time.Time
+	notBeforeMu sync.Mutex
+}
+
+// statusError is returned for requests rejected by the server including the
+// delay requested by the server before retrying
+type statusError struct {
+	url   string
+	code  int
+	body  string
+	delay time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("when writing to [%s] received status code: %d. body: %s", e.url, e.code, e.body)
+}
+
+// retryable returns true for rate-limiting and server errors
+func (e *statusError) retryable() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= 500
+}
+
+// rejected returns true if the server rejected the samples of the request,
+// e.g. due to out-of-order or duplicate samples, so sending them again will
+// never succeed
+func (e *statusError) rejected() bool {
+	return e.code == http.StatusBadRequest || e.code == http.StatusConflict
+}
+
+func (*PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Init() error {
+	if p.URL == "" {
+		return errors.New("'url' required")
+	}
+	if p.Shards <= 0 {
+		return fmt.Errorf("invalid 'shards' setting %d", p.Shards)
+	}
+	if p.MaxRequestSize <= 0 {
+		return fmt.Errorf("invalid 'max_request_size' setting %d", p.MaxRequestSize)
+	}
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("invalid 'max_retries' setting %d", p.MaxRetries)
+	}
+	if p.RetryBackoff <= 0 || p.MaxRetryBackoff < p.RetryBackoff {
+		return errors.New("'retry_backoff' must be positive and not exceed 'max_retry_backoff'")
+	}
+
+	// Valid schemas of exponential native histograms
+	if p.NativeHistogramSchema < -4 || p.NativeHistogramSchema > 8 {
+		return fmt.Errorf("invalid 'native_histogram_schema' setting %d", p.NativeHistogramSchema)
+	}
+
+	if !p.BearerToken.Empty() && (!p.Username.Empty() || !p.Password.Empty()) {
+		return errors.New("either use 'bearer_token' or 'username' and 'password'")
+	}
+
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Connect() error {
+	client, err := p.HTTPClientConfig.CreateClient(context.Background(), p.Log)
+	if err != nil {
+		return err
+	}
+	p.client = client
+
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Close() error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Write(metrics []telegraf.Metric) error {
+	p.notBeforeMu.Lock()
+	notBefore := p.notBefore
+	p.notBeforeMu.Unlock()
+	if wait := time.Until(notBefore); wait > 0 {
+		return fmt.Errorf("server requested to delay requests, retrying in %s", wait.Truncate(time.Second))
+	}
+
+	conv := newConverter(p.StringAsLabel, p.NativeHistograms, p.NativeHistogramSchema)
+	for _, m := range metrics {
+		conv.add(m)
+	}
+	if conv.dropped > 0 {
+		// Log only the last error as logging each of them could be too verbose
+		p.Log.Errorf("Dropped %d series, last error: %v", conv.dropped, conv.lastErr)
+	}
+
+	// Distribute the series across the shards so the samples of a series are
+	// always sent in order by the same shard
+	shards := make([][]*series, p.Shards)
+	for _, s := range conv.result() {
+		idx := s.key % uint64(p.Shards)
+		shards[idx] = append(shards[idx], s)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(shards))
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, shard []*series) {
+			defer wg.Done()
+			for _, req := range p.splitRequests(shard, conv.metadata) {
+				if err := p.send(req); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i, shard)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// splitRequests combines the series into requests not exceeding the maximum
+// request size before compression. Series exceeding the size on their own are
+// sent in a separate request. The metadata of the metric families is added to
+// each request containing series of the family.
+func (p *PrometheusRemoteWrite) splitRequests(shard []*series, metadata map[string]prompb.MetricMetadata) []*prompb.WriteRequest {
+	requests := make([]*prompb.WriteRequest, 0, 1)
+	var current *prompb.WriteRequest
+	var size int
+	var families map[string]bool
+	for _, s := range shard {
+		seriesSize := s.TimeSeries.Size()
+		if current == nil || (size > 0 && size+seriesSize > int(p.MaxRequestSize)) {
+			current = &prompb.WriteRequest{}
+			requests = append(requests, current)
+			size = 0
+			families = make(map[string]bool)
+		}
+
+		current.Timeseries = append(current.Timeseries, s.TimeSeries)
+		size += seriesSize
+
+		if p.SendMetadata && !families[s.family] {
+			if md, found := metadata[s.family]; found {
+				current.Metadata = append(current.Metadata, md)
+				families[s.family] = true
+				size += md.Size()
+			}
+		}
+	}
+	return requests
+}
+
+// send sends the request retrying on network errors, rate-limiting and server
+// errors with an exponential backoff or the delay requested by the server
+func (p *PrometheusRemoteWrite) send(req *prompb.WriteRequest) error {
+	data, err := req.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshalling request failed: %w", err)
+	}
+	body := snappy.Encode(nil, data)
+
+	backoff := time.Duration(p.RetryBackoff)
+	for attempt := 0; ; attempt++ {
+		httpReq, err := p.newRequest(body)
+		if err != nil {
+			return err
+		}
+
+		err = p.do(httpReq)
+		if err == nil {
+			return nil
+		}
+
+		var serr *statusError
+		isStatusErr := errors.As(err, &serr)
+		if isStatusErr && serr.rejected() {
+			// Retrying will not help so drop the series to not block the
+			// output with invalid data, Prometheus does the same
+			p.Log.Errorf("Dropping %d series: %v", len(req.Timeseries), err)
+			return nil
+		}
+		if isStatusErr && !serr.retryable() {
+			// Errors like authentication failures or a wrong URL need to be
+			// fixed by the user, so keep the metrics until then
+			return err
+		}
+		if attempt >= p.MaxRetries {
+			return err
+		}
+
+		delay := backoff
+		if isStatusErr && serr.delay > 0 {
+			// Do not block the output for longer delays but stop sending
+			// until the requested time
+			if serr.delay > time.Duration(p.MaxRetryBackoff) {
+				p.notBeforeMu.Lock()
+				p.notBefore = time.Now().Add(serr.delay)
+				p.notBeforeMu.Unlock()
+				return fmt.Errorf("%w; retrying after %s as requested by the server", err, serr.delay)
+			}
+			delay = serr.delay
+		}
+		p.Log.Debugf("Retrying request in %s: %v", delay, err)
+		time.Sleep(delay)
+		backoff = min(2*backoff, time.Duration(p.MaxRetryBackoff))
+	}
+}
+
+func (p *PrometheusRemoteWrite) newRequest(body []byte) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	if err := p.setAuth(req); err != nil {
+		return nil, err
+	}
+
+	for k, v := range p.Headers {
+		secret, err := v.Get()
+		if err != nil {
+			return nil, err
+		}
+
+		headerVal := secret.String()
+		if strings.EqualFold(k, "host") {
+			req.Host = headerVal
+		}
+		req.Header.Set(k, headerVal)
+
+		secret.Destroy()
+	}
+	return req, nil
+}
+
+func (p *PrometheusRemoteWrite) do(req *http.Request) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		//nolint:errcheck // Drain the body to reuse the connection
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	errorLine := ""
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxErrMsgLen))
+	if scanner.Scan() {
+		errorLine = scanner.Text()
+	}
+	return &statusError{
+		url:   p.URL,
+		code:  resp.StatusCode,
+		body:  errorLine,
+		delay: retryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func (p *PrometheusRemoteWrite) setAuth(req *http.Request) error {
+	if !p.BearerToken.Empty() {
+		token, err := p.BearerToken.Get()
+		if err != nil {
+			return fmt.Errorf("getting token failed: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.String())
+		token.Destroy()
+		return nil
+	}
+
+	if !p.Username.Empty() || !p.Password.Empty() {
+		username, err := p.Username.Get()
+		if err != nil {
+			return fmt.Errorf("getting username failed: %w", err)
+		}
+		password, err := p.Password.Get()
+		if err != nil {
+			username.Destroy()
+			return fmt.Errorf("getting password failed: %w", err)
+		}
+		req.SetBasicAuth(username.String(), password.String())
+		username.Destroy()
+		password.Destroy()
+	}
+	return nil
+}
+
+// retryAfter parses the value of a Retry-After header given either in seconds
+// or as HTTP date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func init() {
+	outputs.Add("prometheus_remote_write", func() telegraf.Output {
+		return &PrometheusRemoteWrite{
+			Shards:                4,
+			MaxRequestSize:        config.Size(1024 * 1024),
+			MaxRetries:            3,
+			RetryBackoff:          config.Duration(500 * time.Millisecond),
+			MaxRetryBackoff:       config.Duration(30 * time.Second),
+			SendMetadata:          true,
+			NativeHistogramSchema: 3,
+		}
+	})
+}
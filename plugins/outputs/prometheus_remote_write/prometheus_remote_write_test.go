@@ -0,0 +1,427 @@
+package prometheus_remote_write
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &PrometheusRemoteWrite{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*PrometheusRemoteWrite)
+		expected string
+	}{
+		{
+			name:     "missing url",
+			modify:   func(p *PrometheusRemoteWrite) { p.URL = "" },
+			expected: "'url' required",
+		},
+		{
+			name:     "invalid shards",
+			modify:   func(p *PrometheusRemoteWrite) { p.Shards = 0 },
+			expected: "invalid 'shards' setting 0",
+		},
+		{
+			name:     "invalid request size",
+			modify:   func(p *PrometheusRemoteWrite) { p.MaxRequestSize = 0 },
+			expected: "invalid 'max_request_size' setting 0",
+		},
+		{
+			name:     "invalid backoff",
+			modify:   func(p *PrometheusRemoteWrite) { p.MaxRetryBackoff = config.Duration(time.Millisecond) },
+			expected: "'retry_backoff' must be positive and not exceed 'max_retry_backoff'",
+		},
+		{
+			name:     "invalid schema",
+			modify:   func(p *PrometheusRemoteWrite) { p.NativeHistogramSchema = 9 },
+			expected: "invalid 'native_histogram_schema' setting 9",
+		},
+		{
+			name: "conflicting auth",
+			modify: func(p *PrometheusRemoteWrite) {
+				p.Username = config.NewSecret([]byte("user"))
+				p.BearerToken = config.NewSecret([]byte("token"))
+			},
+			expected: "either use 'bearer_token' or 'username' and 'password'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin("http://localhost:9090/api/v1/write")
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var mu sync.Mutex
+	var received []*prompb.WriteRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("X-Scope-OrgID") != "tenant" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		req, err := decodeRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, req)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.BearerToken = config.NewSecret([]byte("token"))
+	tenant := config.NewSecret([]byte("tenant"))
+	plugin.Headers = map[string]*config.Secret{"X-Scope-OrgID": &tenant}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	now := time.Unix(1700000000, 0)
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1.0}, now, telegraf.Gauge),
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 2.0}, now.Add(-time.Second), telegraf.Gauge),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"usage": 3.0}, now, telegraf.Gauge),
+		metric.New("http", map[string]string{"code": "200"}, map[string]interface{}{"requests": int64(42)}, now, telegraf.Counter),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	samples := make(map[string][]prompb.Sample)
+	metadata := make(map[string]prompb.MetricMetadata_MetricType)
+	for _, req := range received {
+		for _, s := range req.Timeseries {
+			samples[labelString(s.Labels)] = s.Samples
+		}
+		for _, md := range req.Metadata {
+			metadata[md.MetricFamilyName] = md.Type
+		}
+	}
+
+	expected := map[string][]prompb.Sample{
+		`{__name__="cpu_usage",host="a"}`: {
+			{Value: 2.0, Timestamp: now.Add(-time.Second).UnixMilli()},
+			{Value: 1.0, Timestamp: now.UnixMilli()},
+		},
+		`{__name__="cpu_usage",host="b"}`:       {{Value: 3.0, Timestamp: now.UnixMilli()}},
+		`{__name__="http_requests",code="200"}`: {{Value: 42.0, Timestamp: now.UnixMilli()}},
+	}
+	require.Equal(t, expected, samples)
+	require.Equal(t, map[string]prompb.MetricMetadata_MetricType{
+		"cpu_usage":     prompb.MetricMetadata_GAUGE,
+		"http_requests": prompb.MetricMetadata_COUNTER,
+	}, metadata)
+}
+
+func TestSplitRequests(t *testing.T) {
+	var count atomic.Int64
+	var series atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := decodeRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		count.Add(1)
+		series.Add(int64(len(req.Timeseries)))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.Shards = 1
+	plugin.MaxRequestSize = config.Size(1024)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := make([]telegraf.Metric, 0, 100)
+	for i := range 100 {
+		metrics = append(metrics, metric.New("cpu",
+			map[string]string{"host": "host-" + string(rune('a'+i%26)) + string(rune('a'+i/26))},
+			map[string]interface{}{"usage": float64(i)},
+			time.Unix(1700000000, 0),
+		))
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, int64(100), series.Load())
+	require.Greater(t, count.Load(), int64(1))
+}
+
+func TestRetry(t *testing.T) {
+	var attempts atomic.Int64
+	var unavailable atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := attempts.Add(1)
+		switch {
+		case unavailable.Load():
+			w.WriteHeader(http.StatusTooManyRequests)
+		case n == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case n == 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.RetryBackoff = config.Duration(time.Millisecond)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"usage": 1.0}, time.Unix(1700000000, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Equal(t, int64(3), attempts.Load())
+
+	// Exhausting the retries keeps the metrics
+	attempts.Store(0)
+	unavailable.Store(true)
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), "received status code: 429")
+	require.Equal(t, int64(4), attempts.Load())
+}
+
+func TestNonRetryableStatus(t *testing.T) {
+	var attempts atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"usage": 1.0}, time.Unix(1700000000, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Equal(t, int64(1), attempts.Load())
+}
+
+func TestClientErrorStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		dropped bool
+	}{
+		{name: "bad request", status: http.StatusBadRequest, dropped: true},
+		{name: "conflict", status: http.StatusConflict, dropped: true},
+		{name: "unauthorized", status: http.StatusUnauthorized},
+		{name: "forbidden", status: http.StatusForbidden},
+		{name: "not found", status: http.StatusNotFound},
+		{name: "too large", status: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int64
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			plugin := newPlugin(ts.URL)
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			// Client errors are never retried, only sample rejections drop
+			// the metrics while other errors keep them in the buffer
+			m := metric.New("cpu", map[string]string{}, map[string]interface{}{"usage": 1.0}, time.Unix(1700000000, 0))
+			err := plugin.Write([]telegraf.Metric{m})
+			if tt.dropped {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, fmt.Sprintf("received status code: %d", tt.status))
+			}
+			require.Equal(t, int64(1), attempts.Load())
+		})
+	}
+}
+
+func TestLongRetryAfter(t *testing.T) {
+	var attempts atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.RetryBackoff = config.Duration(time.Millisecond)
+	plugin.MaxRetryBackoff = config.Duration(time.Second)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// A delay exceeding the maximum backoff keeps the metrics without
+	// retrying and no requests are sent before the requested time
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"usage": 1.0}, time.Unix(1700000000, 0))
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), "retrying after 1m0s as requested by the server")
+	require.Equal(t, int64(1), attempts.Load())
+
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), "server requested to delay requests")
+	require.Equal(t, int64(1), attempts.Load())
+}
+
+func TestRetryAfter(t *testing.T) {
+	require.Equal(t, time.Duration(0), retryAfter(""))
+	require.Equal(t, 5*time.Second, retryAfter("5"))
+	require.Equal(t, time.Duration(0), retryAfter("soon"))
+
+	delay := retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	require.Greater(t, delay, 50*time.Second)
+	require.LessOrEqual(t, delay, time.Minute)
+}
+
+func TestClassicHistogram(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	conv := newConverter(false, false, 3)
+	for _, m := range histogramMetrics(now) {
+		conv.add(m)
+	}
+	require.Zero(t, conv.dropped)
+
+	actual := make(map[string]float64)
+	for _, s := range conv.result() {
+		require.Len(t, s.Samples, 1)
+		actual[labelString(s.Labels)] = s.Samples[0].Value
+	}
+	expected := map[string]float64{
+		`{__name__="latency_bucket",host="a",le="0.1"}`:  2,
+		`{__name__="latency_bucket",host="a",le="1"}`:    5,
+		`{__name__="latency_bucket",host="a",le="10"}`:   6,
+		`{__name__="latency_bucket",host="a",le="+Inf"}`: 8,
+		`{__name__="latency_sum",host="a"}`:              42.5,
+		`{__name__="latency_count",host="a"}`:            8,
+	}
+	require.Equal(t, expected, actual)
+	require.Equal(t, prompb.MetricMetadata_HISTOGRAM, conv.metadata["latency"].Type)
+}
+
+func TestNativeHistogram(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	conv := newConverter(false, true, 0)
+	for _, m := range histogramMetrics(now) {
+		conv.add(m)
+	}
+	require.Zero(t, conv.dropped)
+
+	result := conv.result()
+	require.Len(t, result, 1)
+	require.Equal(t, `{__name__="latency",host="a"}`, labelString(result[0].Labels))
+	require.Empty(t, result[0].Samples)
+	require.Len(t, result[0].Histograms, 1)
+
+	// With schema 0 the bucket i covers (2^(i-1), 2^i], so the classic bounds
+	// 0.1, 1 and 10 map to the buckets -3, 0 and 4 and the overflow to bucket 5
+	expected := prompb.Histogram{
+		Count:     &prompb.Histogram_CountInt{CountInt: 8},
+		Sum:       42.5,
+		Schema:    0,
+		ZeroCount: &prompb.Histogram_ZeroCountInt{ZeroCountInt: 0},
+		PositiveSpans: []prompb.BucketSpan{
+			{Offset: -3, Length: 1},
+			{Offset: 2, Length: 1},
+			{Offset: 3, Length: 2},
+		},
+		PositiveDeltas: []int64{2, 1, -2, 1},
+		Timestamp:      now.UnixMilli(),
+	}
+	require.Equal(t, expected, result[0].Histograms[0])
+}
+
+func TestEncodeBuckets(t *testing.T) {
+	spans, deltas := encodeBuckets(map[int32]int64{1: 3, 2: 5, 3: 1, 7: 4})
+	require.Equal(t, []prompb.BucketSpan{{Offset: 1, Length: 3}, {Offset: 3, Length: 1}}, spans)
+	require.Equal(t, []int64{3, 2, -4, 3}, deltas)
+}
+
+func newPlugin(url string) *PrometheusRemoteWrite {
+	return &PrometheusRemoteWrite{
+		URL:                   url,
+		Shards:                4,
+		MaxRequestSize:        config.Size(1024 * 1024),
+		MaxRetries:            3,
+		RetryBackoff:          config.Duration(10 * time.Millisecond),
+		MaxRetryBackoff:       config.Duration(100 * time.Millisecond),
+		SendMetadata:          true,
+		NativeHistogramSchema: 3,
+		Log:                   testutil.Logger{},
+	}
+}
+
+func histogramMetrics(now time.Time) []telegraf.Metric {
+	return []telegraf.Metric{
+		metric.New("prometheus", map[string]string{"host": "a"},
+			map[string]interface{}{"latency_sum": 42.5, "latency_count": uint64(8)}, now, telegraf.Histogram),
+		metric.New("prometheus", map[string]string{"host": "a", "le": "0.1"},
+			map[string]interface{}{"latency_bucket": uint64(2)}, now, telegraf.Histogram),
+		metric.New("prometheus", map[string]string{"host": "a", "le": "1"},
+			map[string]interface{}{"latency_bucket": uint64(5)}, now, telegraf.Histogram),
+		metric.New("prometheus", map[string]string{"host": "a", "le": "10"},
+			map[string]interface{}{"latency_bucket": uint64(6)}, now, telegraf.Histogram),
+		metric.New("prometheus", map[string]string{"host": "a", "le": "+Inf"},
+			map[string]interface{}{"latency_bucket": uint64(8)}, now, telegraf.Histogram),
+	}
+}
+
+func decodeRequest(r *http.Request) (*prompb.WriteRequest, error) {
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, err
+	}
+	var req prompb.WriteRequest
+	if err := req.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func labelString(labels []prompb.Label) string {
+	s := "{"
+	for i, l := range labels {
+		if i > 0 {
+			s += ","
+		}
+		s += l.Name + "=\"" + l.Value + "\""
+	}
+	return s + "}"
+}
@@ -0,0 +1,318 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/gofrs/uuid/v5"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_pulsar "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Pulsar struct {
+	Topic                   string          `toml:"topic"`
+	TopicTag                string          `toml:"topic_tag"`
+	ExcludeTopicTag         bool            `toml:"exclude_topic_tag"`
+	TopicSuffix             TopicSuffix     `toml:"topic_suffix"`
+	RoutingTag              string          `toml:"routing_tag"`
+	RoutingKey              string          `toml:"routing_key"`
+	HashingScheme           string          `toml:"hashing_scheme"`
+	MetricNameProperty      string          `toml:"metric_name_property"`
+	Compression             string          `toml:"compression"`
+	DisableBatching         bool            `toml:"disable_batching"`
+	BatchingMaxPublishDelay config.Duration `toml:"batching_max_publish_delay"`
+	BatchingMaxMessages     uint            `toml:"batching_max_messages"`
+	BatchingMaxSize         config.Size     `toml:"batching_max_size"`
+	SendTimeout             config.Duration `toml:"send_timeout"`
+	Log                     telegraf.Logger `toml:"-"`
+	common_pulsar.ClientConfig
+
+	serializer      telegraf.Serializer
+	client          pulsar.Client
+	producerOptions pulsar.ProducerOptions
+	producerFunc    func(pulsar.ProducerOptions) (pulsar.Producer, error)
+	producers       map[string]pulsar.Producer
+}
+
+type TopicSuffix struct {
+	Method    string   `toml:"method"`
+	Keys      []string `toml:"keys"`
+	Separator string   `toml:"separator"`
+}
+
+func (*Pulsar) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Pulsar) SetSerializer(serializer telegraf.Serializer) {
+	p.serializer = serializer
+}
+
+func (p *Pulsar) Init() error {
+	if err := p.ClientConfig.Validate(); err != nil {
+		return err
+	}
+	if p.Topic == "" && p.TopicTag == "" {
+		return errors.New("either 'topic' or 'topic_tag' required")
+	}
+
+	switch p.TopicSuffix.Method {
+	case "", "measurement", "tags":
+	default:
+		return fmt.Errorf("invalid 'topic_suffix.method' setting %q", p.TopicSuffix.Method)
+	}
+
+	var hashing pulsar.HashingScheme
+	switch p.HashingScheme {
+	case "", "java_string_hash":
+		hashing = pulsar.JavaStringHash
+	case "murmur3_32hash":
+		hashing = pulsar.Murmur3_32Hash
+	default:
+		return fmt.Errorf("invalid 'hashing_scheme' setting %q", p.HashingScheme)
+	}
+
+	var compression pulsar.CompressionType
+	switch p.Compression {
+	case "", "none":
+		compression = pulsar.NoCompression
+	case "lz4":
+		compression = pulsar.LZ4
+	case "zlib":
+		compression = pulsar.ZLib
+	case "zstd":
+		compression = pulsar.ZSTD
+	default:
+		return fmt.Errorf("invalid 'compression' setting %q", p.Compression)
+	}
+
+	if p.BatchingMaxPublishDelay < 0 {
+		return errors.New("'batching_max_publish_delay' must not be negative")
+	}
+	if p.SendTimeout < 0 {
+		return errors.New("'send_timeout' must not be negative")
+	}
+
+	p.producerOptions = pulsar.ProducerOptions{
+		SendTimeout:             time.Duration(p.SendTimeout),
+		HashingScheme:           hashing,
+		CompressionType:         compression,
+		DisableBatching:         p.DisableBatching,
+		BatchingMaxPublishDelay: time.Duration(p.BatchingMaxPublishDelay),
+		BatchingMaxMessages:     p.BatchingMaxMessages,
+		BatchingMaxSize:         uint(p.BatchingMaxSize),
+	}
+	p.producers = make(map[string]pulsar.Producer)
+
+	return nil
+}
+
+func (p *Pulsar) Connect() error {
+	client, err := p.ClientConfig.NewClient(p.Log)
+	if err != nil {
+		return &internal.StartupError{Err: err, Retry: true}
+	}
+	p.client = client
+	p.producerFunc = client.CreateProducer
+
+	// Create the producer of the static topic to check the connection and
+	// permissions early
+	if p.Topic != "" && p.TopicTag == "" && p.TopicSuffix.Method == "" {
+		if _, err := p.producer(p.Topic); err != nil {
+			client.Close()
+			p.client = nil
+			return &internal.StartupError{Err: err, Retry: true}
+		}
+	}
+
+	return nil
+}
+
+func (p *Pulsar) Close() error {
+	for _, producer := range p.producers {
+		producer.Close()
+	}
+	clear(p.producers)
+
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+	return nil
+}
+
+func (p *Pulsar) Write(metrics []telegraf.Metric) error {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	var writeErr error
+	used := make(map[string]pulsar.Producer)
+	for _, m := range metrics {
+		m, topic := p.topicName(m)
+		if topic == "" {
+			p.Log.Errorf("Dropping metric %q without topic", m.Name())
+			continue
+		}
+
+		buf, err := p.serializer.Serialize(m)
+		if err != nil {
+			p.Log.Debugf("Could not serialize metric: %v", err)
+			continue
+		}
+
+		// Stop sending on errors but do not return before all messages
+		// queued so far are flushed and acknowledged
+		key, err := p.routingKey(m)
+		if err != nil {
+			writeErr = fmt.Errorf("could not generate routing key: %w", err)
+			break
+		}
+
+		producer, err := p.producer(topic)
+		if err != nil {
+			writeErr = err
+			break
+		}
+		used[topic] = producer
+
+		msg := &pulsar.ProducerMessage{
+			Payload: buf,
+			Key:     key,
+		}
+		if p.MetricNameProperty != "" {
+			msg.Properties = map[string]string{p.MetricNameProperty: m.Name()}
+		}
+		// Event times must be positive as zero means "not set"
+		if m.Time().UnixMilli() > 0 {
+			msg.EventTime = m.Time()
+		}
+
+		wg.Add(1)
+		producer.SendAsync(ctx, msg, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+			defer wg.Done()
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("sending to topic %q failed: %w", topic, err))
+				mu.Unlock()
+			}
+		})
+	}
+
+	// Send out the pending batches immediately instead of waiting for the
+	// publish delay to elapse
+	for topic, producer := range used {
+		if err := producer.FlushWithCtx(ctx); err != nil {
+			p.Log.Debugf("Flushing producer of topic %q failed: %v", topic, err)
+		}
+	}
+	wg.Wait()
+
+	if writeErr != nil {
+		return writeErr
+	}
+	if len(errs) > 0 {
+		// Only return the first error as the errors are usually identical
+		return errs[0]
+	}
+	return nil
+}
+
+// producer returns the producer for the given topic creating a new one if
+// none exists yet
+func (p *Pulsar) producer(topic string) (pulsar.Producer, error) {
+	if producer, found := p.producers[topic]; found {
+		return producer, nil
+	}
+
+	opts := p.producerOptions
+	opts.Topic = topic
+	producer, err := p.producerFunc(opts)
+	if err != nil {
+		return nil, fmt.Errorf("creating producer for topic %q failed: %w", topic, err)
+	}
+	p.producers[topic] = producer
+	return producer, nil
+}
+
+func (p *Pulsar) topicName(metric telegraf.Metric) (telegraf.Metric, string) {
+	topic := p.Topic
+	if p.TopicTag != "" {
+		if t, ok := metric.GetTag(p.TopicTag); ok {
+			topic = t
+
+			// If excluding the topic tag, a copy is required to avoid modifying
+			// the metric buffer.
+			if p.ExcludeTopicTag {
+				metric = metric.Copy()
+				metric.Accept()
+				metric.RemoveTag(p.TopicTag)
+			}
+		}
+	}
+	if topic == "" {
+		return metric, ""
+	}
+
+	switch p.TopicSuffix.Method {
+	case "measurement":
+		return metric, topic + p.TopicSuffix.Separator + metric.Name()
+	case "tags":
+		components := []string{topic}
+		for _, key := range p.TopicSuffix.Keys {
+			if value, ok := metric.GetTag(key); ok && value != "" {
+				components = append(components, value)
+			}
+		}
+		return metric, strings.Join(components, p.TopicSuffix.Separator)
+	}
+	return metric, topic
+}
+
+func (p *Pulsar) routingKey(metric telegraf.Metric) (string, error) {
+	if p.RoutingTag != "" {
+		if key, ok := metric.GetTag(p.RoutingTag); ok {
+			return key, nil
+		}
+	}
+
+	if p.RoutingKey == "random" {
+		u, err := uuid.NewV4()
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+
+	return p.RoutingKey, nil
+}
+
+func init() {
+	outputs.Add("pulsar", func() telegraf.Output {
+		return &Pulsar{
+			ClientConfig: common_pulsar.ClientConfig{
+				ConnectionTimeout: config.Duration(10 * time.Second),
+				OperationTimeout:  config.Duration(30 * time.Second),
+			},
+			BatchingMaxPublishDelay: config.Duration(10 * time.Millisecond),
+			BatchingMaxMessages:     1000,
+			BatchingMaxSize:         config.Size(128 * 1024),
+			SendTimeout:             config.Duration(30 * time.Second),
+		}
+	})
+}
@@ -0,0 +1,402 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	common_pulsar "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &Pulsar{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	client := common_pulsar.ClientConfig{URL: "pulsar://localhost:6650"}
+	tests := []struct {
+		name     string
+		plugin   *Pulsar
+		expected string
+	}{
+		{
+			name:     "missing url",
+			plugin:   &Pulsar{Topic: "telegraf"},
+			expected: "'url' required",
+		},
+		{
+			name:     "missing topic",
+			plugin:   &Pulsar{ClientConfig: client},
+			expected: "either 'topic' or 'topic_tag' required",
+		},
+		{
+			name:     "invalid suffix method",
+			plugin:   &Pulsar{ClientConfig: client, Topic: "telegraf", TopicSuffix: TopicSuffix{Method: "fields"}},
+			expected: `invalid 'topic_suffix.method' setting "fields"`,
+		},
+		{
+			name:     "invalid hashing scheme",
+			plugin:   &Pulsar{ClientConfig: client, Topic: "telegraf", HashingScheme: "crc32"},
+			expected: `invalid 'hashing_scheme' setting "crc32"`,
+		},
+		{
+			name:     "invalid compression",
+			plugin:   &Pulsar{ClientConfig: client, Topic: "telegraf", Compression: "gzip"},
+			expected: `invalid 'compression' setting "gzip"`,
+		},
+		{
+			name:     "negative publish delay",
+			plugin:   &Pulsar{ClientConfig: client, Topic: "telegraf", BatchingMaxPublishDelay: -1},
+			expected: "'batching_max_publish_delay' must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestTopicName(t *testing.T) {
+	m := metric.New("cpu",
+		map[string]string{"topic": "xyzzy", "host": "server01", "region": "eu"},
+		map[string]interface{}{"time_idle": 42.0},
+		time.Unix(0, 0),
+	)
+
+	tests := []struct {
+		name     string
+		plugin   *Pulsar
+		expected string
+	}{
+		{
+			name:     "static topic",
+			plugin:   &Pulsar{Topic: "telegraf"},
+			expected: "telegraf",
+		},
+		{
+			name:     "topic tag",
+			plugin:   &Pulsar{Topic: "telegraf", TopicTag: "topic"},
+			expected: "xyzzy",
+		},
+		{
+			name:     "missing topic tag",
+			plugin:   &Pulsar{Topic: "telegraf", TopicTag: "unknown"},
+			expected: "telegraf",
+		},
+		{
+			name:     "measurement suffix",
+			plugin:   &Pulsar{Topic: "telegraf", TopicSuffix: TopicSuffix{Method: "measurement", Separator: "_"}},
+			expected: "telegraf_cpu",
+		},
+		{
+			name: "tags suffix",
+			plugin: &Pulsar{
+				Topic:       "persistent://public/default/telegraf",
+				TopicSuffix: TopicSuffix{Method: "tags", Keys: []string{"region", "missing", "host"}, Separator: "-"},
+			},
+			expected: "persistent://public/default/telegraf-eu-server01",
+		},
+		{
+			name: "topic tag with suffix",
+			plugin: &Pulsar{
+				TopicTag:    "topic",
+				TopicSuffix: TopicSuffix{Method: "measurement", Separator: "."},
+			},
+			expected: "xyzzy.cpu",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, topic := tt.plugin.topicName(m)
+			require.Equal(t, tt.expected, topic)
+		})
+	}
+}
+
+func TestRoutingKey(t *testing.T) {
+	m := metric.New("cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"time_idle": 42.0},
+		time.Unix(0, 0),
+	)
+
+	plugin := &Pulsar{RoutingTag: "host", RoutingKey: "static"}
+	key, err := plugin.routingKey(m)
+	require.NoError(t, err)
+	require.Equal(t, "server01", key)
+
+	plugin = &Pulsar{RoutingTag: "missing", RoutingKey: "static"}
+	key, err = plugin.routingKey(m)
+	require.NoError(t, err)
+	require.Equal(t, "static", key)
+
+	plugin = &Pulsar{RoutingKey: "random"}
+	key, err = plugin.routingKey(m)
+	require.NoError(t, err)
+	require.Len(t, key, 36)
+}
+
+func TestWrite(t *testing.T) {
+	plugin := &Pulsar{
+		ClientConfig:       common_pulsar.ClientConfig{URL: "pulsar://localhost:6650"},
+		Topic:              "telegraf",
+		TopicTag:           "topic",
+		ExcludeTopicTag:    true,
+		RoutingTag:         "host",
+		MetricNameProperty: "name",
+		Log:                testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	producers := make(map[string]*mockProducer)
+	plugin.producerFunc = func(opts pulsar.ProducerOptions) (pulsar.Producer, error) {
+		p := &mockProducer{topic: opts.Topic}
+		producers[opts.Topic] = p
+		return p, nil
+	}
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+
+	input := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(1, 0),
+		),
+		metric.New("mem",
+			map[string]string{"host": "server02", "topic": "memory"},
+			map[string]interface{}{"value": 23.0},
+			time.Unix(2, 0),
+		),
+		metric.New("cpu",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(3, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(input))
+
+	require.Len(t, producers, 2)
+	require.Len(t, producers["telegraf"].messages, 2)
+	require.Len(t, producers["memory"].messages, 1)
+	require.Equal(t, 1, producers["telegraf"].flushed)
+	require.Equal(t, 1, producers["memory"].flushed)
+
+	msg := producers["memory"].messages[0]
+	require.Equal(t, "mem,host=server02 value=23 2000000000\n", string(msg.Payload))
+	require.Equal(t, "server02", msg.Key)
+	require.Equal(t, map[string]string{"name": "mem"}, msg.Properties)
+	require.Equal(t, time.Unix(2, 0), msg.EventTime)
+
+	// The original metric must not be modified when excluding the topic tag
+	require.True(t, input[1].HasTag("topic"))
+
+	// Producers must be reused across writes
+	require.NoError(t, plugin.Write(input[:1]))
+	require.Len(t, producers, 2)
+	require.Len(t, producers["telegraf"].messages, 3)
+
+	require.NoError(t, plugin.Close())
+	require.True(t, producers["telegraf"].closed)
+	require.True(t, producers["memory"].closed)
+}
+
+func TestWriteError(t *testing.T) {
+	plugin := &Pulsar{
+		ClientConfig: common_pulsar.ClientConfig{URL: "pulsar://localhost:6650"},
+		Topic:        "telegraf",
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	plugin.producerFunc = func(opts pulsar.ProducerOptions) (pulsar.Producer, error) {
+		return &mockProducer{topic: opts.Topic, err: errors.New("timeout")}, nil
+	}
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+
+	require.ErrorContains(t, plugin.Write(testutil.MockMetrics()), `sending to topic "telegraf" failed: timeout`)
+}
+
+func TestWriteProducerError(t *testing.T) {
+	plugin := &Pulsar{
+		ClientConfig: common_pulsar.ClientConfig{URL: "pulsar://localhost:6650"},
+		Topic:        "telegraf",
+		TopicTag:     "topic",
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var producer *mockProducer
+	plugin.producerFunc = func(opts pulsar.ProducerOptions) (pulsar.Producer, error) {
+		if opts.Topic != "telegraf" {
+			return nil, errors.New("not authorized")
+		}
+		producer = &mockProducer{topic: opts.Topic}
+		return producer, nil
+	}
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(1, 0)),
+		metric.New("mem", map[string]string{"topic": "memory"}, map[string]interface{}{"value": 23.0}, time.Unix(2, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(input), "not authorized")
+
+	// Messages queued before the error must be flushed
+	require.NotNil(t, producer)
+	require.Len(t, producer.messages, 1)
+	require.Equal(t, 1, producer.flushed)
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "6650"
+	container := testutil.Container{
+		Image:        "apachepulsar/pulsar:4.0.4",
+		ExposedPorts: []string{servicePort, "8080"},
+		Cmd:          []string{"bin/pulsar", "standalone", "--no-functions-worker", "--no-stream-storage"},
+		WaitingFor: wait.ForAll(
+			wait.NewHTTPStrategy("/admin/v2/clusters").WithPort(nat.Port("8080")).WithResponseMatcher(
+				func(body io.Reader) bool {
+					buf, err := io.ReadAll(body)
+					return err == nil && strings.Contains(string(buf), "standalone")
+				},
+			),
+			wait.ForLog("Successfully updated the policies on namespace public/default"),
+			wait.ForListeningPort(nat.Port(servicePort)),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	url := "pulsar://" + container.Address + ":" + container.Ports[servicePort]
+
+	// Subscribe before writing to receive all messages
+	client, err := pulsar.NewClient(pulsar.ClientOptions{URL: url})
+	require.NoError(t, err)
+	defer client.Close()
+	consumer, err := client.Subscribe(pulsar.ConsumerOptions{
+		Topic:                       "telegraf",
+		SubscriptionName:            "test",
+		SubscriptionInitialPosition: pulsar.SubscriptionPositionEarliest,
+	})
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	plugin := &Pulsar{
+		ClientConfig: common_pulsar.ClientConfig{URL: url},
+		Topic:        "telegraf",
+		RoutingTag:   "host",
+		Compression:  "zstd",
+		Log:          testutil.Logger{},
+	}
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.5}, time.Unix(1, 0)),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.5}, time.Unix(2, 0)),
+	}
+	require.NoError(t, plugin.Write(input))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+
+	received := make([]string, 0, len(input))
+	for range input {
+		msg, err := consumer.Receive(ctx)
+		require.NoError(t, err)
+		require.NoError(t, consumer.Ack(msg))
+		received = append(received, msg.Key()+" "+string(msg.Payload()))
+	}
+	require.ElementsMatch(t, []string{
+		"a cpu,host=a value=1.5 1000000000\n",
+		"b cpu,host=b value=2.5 2000000000\n",
+	}, received)
+}
+
+type mockProducer struct {
+	topic    string
+	err      error
+	messages []*pulsar.ProducerMessage
+	flushed  int
+	closed   bool
+	sync.Mutex
+}
+
+func (p *mockProducer) Topic() string {
+	return p.topic
+}
+
+func (*mockProducer) Name() string {
+	return "mock"
+}
+
+func (p *mockProducer) Send(_ context.Context, msg *pulsar.ProducerMessage) (pulsar.MessageID, error) {
+	p.Lock()
+	defer p.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.messages = append(p.messages, msg)
+	return pulsar.EarliestMessageID(), nil
+}
+
+func (p *mockProducer) SendAsync(_ context.Context, msg *pulsar.ProducerMessage, callback func(pulsar.MessageID, *pulsar.ProducerMessage, error)) {
+	p.Lock()
+	if p.err == nil {
+		p.messages = append(p.messages, msg)
+	}
+	p.Unlock()
+	go callback(pulsar.EarliestMessageID(), msg, p.err)
+}
+
+func (*mockProducer) LastSequenceID() int64 {
+	return 0
+}
+
+func (p *mockProducer) Flush() error {
+	return p.FlushWithCtx(context.Background())
+}
+
+func (p *mockProducer) FlushWithCtx(context.Context) error {
+	p.Lock()
+	defer p.Unlock()
+	p.flushed++
+	return nil
+}
+
+func (p *mockProducer) Close() {
+	p.Lock()
+	defer p.Unlock()
+	p.closed = true
+}
@@ -11,3 +11,26 @@ var Outputs = make(map[string]Creator)
 func Add(name string, creator Creator) {
 	Outputs[name] = creator
 }
+
+// ParentOutput is implemented by outputs wrapping other outputs. The wrapped
+// outputs are configured as sub-tables of the plugin, e.g.
+// [[outputs.failover.output.influxdb_v2]], and are passed to the plugin in
+// the order of their definition before the plugin is initialized.
+type ParentOutput interface {
+	SetOutputs(outputs []*ChildOutput)
+}
+
+// ChildOutput is an output wrapped by a ParentOutput
+type ChildOutput struct {
+	Name   string
+	Alias  string
+	Output telegraf.Output
+}
+
+// LogName returns the name of the wrapped output used for logging
+func (c *ChildOutput) LogName() string {
+	if c.Alias == "" {
+		return c.Name
+	}
+	return c.Name + "::" + c.Alias
+}
@@ -25,6 +25,16 @@ import (
 //go:embed sample.conf
 var sampleConfig string
 
+const (
+	// Maximum number of rows in a multi-row insert, e.g. SQL Server does not
+	// support more than 1000 rows
+	maxInsertRows = 1000
+
+	// Maximum number of parameters of an insert, e.g. SQL Server does not
+	// support more than 2100 parameters
+	maxInsertParameters = 2000
+)
+
 var defaultConvert = ConvertStruct{
 	Integer:         "INT",
 	Real:            "DOUBLE",
@@ -55,6 +65,7 @@ type SQL struct {
 	TableExistsTemplate   string          `toml:"table_exists_template"`
 	TableUpdateTemplate   string          `toml:"table_update_template"`
 	InitSQL               string          `toml:"init_sql"`
+	Upsert                bool            `toml:"upsert"`
 	Convert               ConvertStruct   `toml:"convert"`
 	ConnectionMaxIdleTime config.Duration `toml:"connection_max_idle_time"`
 	ConnectionMaxLifetime config.Duration `toml:"connection_max_lifetime"`
@@ -64,7 +75,9 @@ type SQL struct {
 
 	db                       *gosql.DB
 	tables                   map[string]map[string]bool
+	tableKeys                map[string][]string
 	tableListColumnsTemplate string
+	tableListKeysTemplate    string
 }
 
 func (*SQL) SampleConfig() string {
@@ -78,16 +91,26 @@ func (p *SQL) Init() error {
 	}
 
 	if p.TableTemplate == "" {
-		if p.Driver == "clickhouse" {
+		switch {
+		case p.Driver == "clickhouse" && p.Upsert:
+			p.TableTemplate = "CREATE TABLE {TABLE}({COLUMNS}) ENGINE = ReplacingMergeTree ORDER BY ({TAG_COLUMN_NAMES}, {TIMESTAMP_COLUMN_NAME})"
+		case p.Driver == "clickhouse":
 			p.TableTemplate = "CREATE TABLE {TABLE}({COLUMNS}) ORDER BY ({TAG_COLUMN_NAMES}, {TIMESTAMP_COLUMN_NAME})"
-		} else {
+		case p.Upsert:
+			p.TableTemplate = "CREATE TABLE {TABLE}({COLUMNS}, PRIMARY KEY ({KEY_COLUMN_NAMES}))"
+		default:
 			p.TableTemplate = "CREATE TABLE {TABLE}({COLUMNS})"
 		}
 	}
 
 	p.tableListColumnsTemplate = "SELECT column_name FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_NAME={TABLE}"
+	p.tableListKeysTemplate = "SELECT kcu.column_name FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc " +
+		"JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu ON tc.constraint_name=kcu.constraint_name " +
+		"AND tc.table_schema=kcu.table_schema AND tc.table_name=kcu.table_name " +
+		"WHERE tc.constraint_type='PRIMARY KEY' AND tc.table_name={TABLE} ORDER BY kcu.ordinal_position"
 	if p.Driver == "sqlite" {
 		p.tableListColumnsTemplate = "SELECT name AS column_name FROM pragma_table_info({TABLE})"
+		p.tableListKeysTemplate = "SELECT name AS column_name FROM pragma_table_info({TABLE}) WHERE pk > 0 ORDER BY pk"
 	}
 
 	// Check for a valid driver
@@ -101,6 +124,15 @@ func (p *SQL) Init() error {
 		return fmt.Errorf("unknown driver %q", p.Driver)
 	}
 
+	// Upserts are done by the table engine for ClickHouse
+	if p.Upsert {
+		switch p.Driver {
+		case "clickhouse", "mysql", "pgx", "sqlite":
+		default:
+			return fmt.Errorf("upsert not supported for driver %q", p.Driver)
+		}
+	}
+
 	return nil
 }
 
@@ -127,6 +159,7 @@ func (p *SQL) Connect() error {
 
 	p.db = db
 	p.tables = make(map[string]map[string]bool)
+	p.tableKeys = make(map[string][]string)
 
 	return nil
 }
@@ -213,6 +246,12 @@ func (p *SQL) generateCreateTable(metric telegraf.Metric) string {
 	query = strings.ReplaceAll(query, "{TAG_COLUMN_NAMES}", strings.Join(tagColumnNames, ","))
 	query = strings.ReplaceAll(query, "{TIMESTAMP_COLUMN_NAME}", quoteIdent(p.TimestampColumn))
 
+	keyColumnNames := tagColumnNames
+	if p.TimestampColumn != "" {
+		keyColumnNames = append([]string{quoteIdent(p.TimestampColumn)}, tagColumnNames...)
+	}
+	query = strings.ReplaceAll(query, "{KEY_COLUMN_NAMES}", strings.Join(keyColumnNames, ","))
+
 	return query
 }
 
@@ -224,28 +263,60 @@ func (p *SQL) generateAddColumn(tablename, column, columnType string) string {
 	return query
 }
 
-func (p *SQL) generateInsert(tablename string, columns []string) string {
-	placeholders := make([]string, 0, len(columns))
+// generateInsert returns an insert statement for the given number of rows.
+// In upsert mode, existing rows with the same key are updated.
+func (p *SQL) generateInsert(tablename string, columns []string, keys, rows int) string {
 	quotedColumns := make([]string, 0, len(columns))
 	for _, column := range columns {
 		quotedColumns = append(quotedColumns, quoteIdent(column))
 	}
-	if p.Driver == "pgx" {
-		// Postgres uses $1 $2 $3 as placeholders
-		for i := 0; i < len(columns); i++ {
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
-		}
-	} else {
-		// Everything else uses ? ? ? as placeholders
-		for i := 0; i < len(columns); i++ {
-			placeholders = append(placeholders, "?")
+
+	values := make([]string, 0, rows)
+	placeholders := make([]string, 0, len(columns))
+	for row := 0; row < rows; row++ {
+		placeholders = placeholders[:0]
+		for i := range columns {
+			if p.Driver == "pgx" {
+				// Postgres uses $1 $2 $3 as placeholders
+				placeholders = append(placeholders, fmt.Sprintf("$%d", row*len(columns)+i+1))
+			} else {
+				// Everything else uses ? ? ? as placeholders
+				placeholders = append(placeholders, "?")
+			}
 		}
+		values = append(values, "("+strings.Join(placeholders, ",")+")")
 	}
 
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES(%s)",
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES%s",
 		quoteIdent(tablename),
 		strings.Join(quotedColumns, ","),
-		strings.Join(placeholders, ","))
+		strings.Join(values, ","))
+
+	// ClickHouse replaces rows using the table engine
+	if !p.Upsert || keys == 0 || p.Driver == "clickhouse" {
+		return query
+	}
+
+	updates := make([]string, 0, len(columns)-keys)
+	switch p.Driver {
+	case "mysql":
+		for _, column := range quotedColumns[keys:] {
+			updates = append(updates, fmt.Sprintf("%s=VALUES(%s)", column, column))
+		}
+		if len(updates) == 0 {
+			updates = append(updates, fmt.Sprintf("%s=%s", quotedColumns[0], quotedColumns[0]))
+		}
+		return query + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ",")
+	default:
+		for _, column := range quotedColumns[keys:] {
+			updates = append(updates, fmt.Sprintf("%s=excluded.%s", column, column))
+		}
+		query += " ON CONFLICT (" + strings.Join(quotedColumns[:keys], ",") + ")"
+		if len(updates) == 0 {
+			return query + " DO NOTHING"
+		}
+		return query + " DO UPDATE SET " + strings.Join(updates, ",")
+	}
 }
 
 func (p *SQL) createTable(metric telegraf.Metric) error {
@@ -326,36 +397,133 @@ func (p *SQL) updateTableCache(tablename string) error {
 	return nil
 }
 
-func (p *SQL) Write(metrics []telegraf.Metric) error {
-	var err error
+// keyColumns returns the primary key columns of the table used as conflict
+// target in upsert mode
+func (p *SQL) keyColumns(tablename string) ([]string, error) {
+	if keys, found := p.tableKeys[tablename]; found {
+		return keys, nil
+	}
 
-	for _, metric := range metrics {
-		tablename := metric.Name()
+	stmt := strings.ReplaceAll(p.tableListKeysTemplate, "{TABLE}", quoteStr(tablename))
+	rows, err := p.db.Query(stmt)
+	if err != nil {
+		return nil, fmt.Errorf("fetching key columns for table(%s) failed: %w", tablename, err)
+	}
+	defer rows.Close()
 
-		// create table if needed
-		if _, found := p.tables[tablename]; !found && !p.tableExists(tablename) {
-			if err := p.createTable(metric); err != nil {
-				return err
-			}
+	keys := make([]string, 0)
+	for rows.Next() {
+		var columnName string
+		if err := rows.Scan(&columnName); err != nil {
+			return nil, err
 		}
+		keys = append(keys, columnName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		var columns []string
-		var values []interface{}
+	if len(keys) == 0 {
+		p.Log.Warnf("Table %q has no primary key, inserting rows without upsert", tablename)
+	}
+	p.tableKeys[tablename] = keys
+	return keys, nil
+}
 
+// rowValues returns the columns and values of the metric with the key columns
+// first and the number of key columns. Without given key columns, the
+// timestamp and tags of the metric are used as key. Otherwise the key columns
+// are filled from the timestamp, tags or fields of the metric using empty
+// strings for missing values as key columns cannot be NULL.
+func (p *SQL) rowValues(metric telegraf.Metric, keyColumns []string) ([]string, []interface{}, int) {
+	columns := make([]string, 0, len(keyColumns)+len(metric.TagList())+len(metric.FieldList())+1)
+	values := make([]interface{}, 0, len(keyColumns)+len(metric.TagList())+len(metric.FieldList())+1)
+
+	if keyColumns == nil {
 		if p.TimestampColumn != "" {
 			columns = append(columns, p.TimestampColumn)
 			values = append(values, metric.Time())
 		}
+		for _, tag := range metric.TagList() {
+			columns = append(columns, tag.Key)
+			values = append(values, tag.Value)
+		}
+		keys := len(columns)
+
+		for _, field := range metric.FieldList() {
+			columns = append(columns, field.Key)
+			values = append(values, field.Value)
+		}
+		return columns, values, keys
+	}
+
+	used := make(map[string]bool, len(keyColumns))
+	for _, column := range keyColumns {
+		used[column] = true
+		columns = append(columns, column)
+		if column == p.TimestampColumn {
+			values = append(values, metric.Time())
+		} else if v, found := metric.GetTag(column); found {
+			values = append(values, v)
+		} else if v, found := metric.GetField(column); found {
+			values = append(values, v)
+		} else {
+			values = append(values, "")
+		}
+	}
+
+	if p.TimestampColumn != "" && !used[p.TimestampColumn] {
+		columns = append(columns, p.TimestampColumn)
+		values = append(values, metric.Time())
+	}
+	for _, tag := range metric.TagList() {
+		if !used[tag.Key] {
+			columns = append(columns, tag.Key)
+			values = append(values, tag.Value)
+		}
+	}
+	for _, field := range metric.FieldList() {
+		if !used[field.Key] {
+			columns = append(columns, field.Key)
+			values = append(values, field.Value)
+		}
+	}
+	return columns, values, len(keyColumns)
+}
+
+// insertBatch contains the rows of a table with the same columns
+type insertBatch struct {
+	table   string
+	columns []string
+	keys    int
+	rows    [][]interface{}
+}
 
-		for column, value := range metric.Tags() {
-			columns = append(columns, column)
-			values = append(values, value)
+func (p *SQL) Write(metrics []telegraf.Metric) error {
+	// Create the tables and columns before starting to insert as schema
+	// changes are not transactional in all databases
+	batches := make([]*insertBatch, 0)
+	index := make(map[string]*insertBatch)
+	for _, metric := range metrics {
+		tablename := metric.Name()
+
+		// create table if needed
+		if _, found := p.tables[tablename]; !found && !p.tableExists(tablename) {
+			if err := p.createTable(metric); err != nil {
+				return err
+			}
 		}
 
-		for column, value := range metric.Fields() {
-			columns = append(columns, column)
-			values = append(values, value)
+		// Use the primary key of the table as conflict target for upserts,
+		// ClickHouse replaces rows using the table engine instead
+		var keyColumns []string
+		if p.Upsert && p.Driver != "clickhouse" {
+			var err error
+			if keyColumns, err = p.keyColumns(tablename); err != nil {
+				return err
+			}
 		}
+		columns, values, keys := p.rowValues(metric, keyColumns)
 
 		// Modifying the table schema is opt-in
 		if p.TableUpdateTemplate != "" {
@@ -366,39 +534,118 @@ func (p *SQL) Write(metrics []telegraf.Metric) error {
 			}
 		}
 
-		sql := p.generateInsert(tablename, columns)
+		id := tablename + "\x00" + strings.Join(columns, "\x00")
+		batch, found := index[id]
+		if !found {
+			batch = &insertBatch{table: tablename, columns: columns, keys: keys}
+			index[id] = batch
+			batches = append(batches, batch)
+		}
+		batch.rows = append(batch.rows, values)
+	}
 
-		switch p.Driver {
-		case "clickhouse":
-			// ClickHouse needs to batch inserts with prepared statements
-			tx, err := p.db.Begin()
-			if err != nil {
-				return fmt.Errorf("begin failed: %w", err)
-			}
-			stmt, err := tx.Prepare(sql)
-			if err != nil {
-				return fmt.Errorf("prepare failed: %w", err)
+	// ClickHouse only sends the last prepared statement of a transaction so
+	// each batch requires its own transaction
+	if p.Driver == "clickhouse" {
+		for _, batch := range batches {
+			if err := p.writeTransaction([]*insertBatch{batch}); err != nil {
+				return err
 			}
-			defer stmt.Close() //nolint:revive,gocritic // done on purpose, closing will be executed properly
+		}
+		return nil
+	}
+	return p.writeTransaction(batches)
+}
 
-			_, err = stmt.Exec(values...)
-			if err != nil {
-				return fmt.Errorf("execution failed: %w", err)
-			}
-			err = tx.Commit()
-			if err != nil {
-				return fmt.Errorf("commit failed: %w", err)
+func (p *SQL) writeTransaction(batches []*insertBatch) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin failed: %w", err)
+	}
+
+	for _, batch := range batches {
+		if err := p.writeBatch(tx, batch); err != nil {
+			if rerr := tx.Rollback(); rerr != nil {
+				p.Log.Errorf("Rollback failed: %v", rerr)
 			}
-		default:
-			_, err = p.db.Exec(sql, values...)
-			if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+func (p *SQL) writeBatch(tx *gosql.Tx, batch *insertBatch) error {
+	rows := batch.rows
+	if p.Upsert && batch.keys > 0 {
+		rows = deduplicateRows(rows, batch.keys)
+	}
+
+	// ClickHouse batches the executions of a prepared statement
+	if p.Driver == "clickhouse" {
+		stmt, err := tx.Prepare(p.generateInsert(batch.table, batch.columns, batch.keys, 1))
+		if err != nil {
+			return fmt.Errorf("prepare failed: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, values := range rows {
+			if _, err := stmt.Exec(values...); err != nil {
 				return fmt.Errorf("execution failed: %w", err)
 			}
 		}
+		return nil
+	}
+
+	// Use multi-row inserts limited by the number of rows and parameters
+	// supported by the databases
+	limit := insertRowLimit(len(batch.columns))
+	for start := 0; start < len(rows); start += limit {
+		chunk := rows[start:min(start+limit, len(rows))]
+		args := make([]interface{}, 0, len(chunk)*len(batch.columns))
+		for _, values := range chunk {
+			args = append(args, values...)
+		}
+
+		if _, err := tx.Exec(p.generateInsert(batch.table, batch.columns, batch.keys, len(chunk)), args...); err != nil {
+			return fmt.Errorf("execution failed: %w", err)
+		}
 	}
 	return nil
 }
 
+// insertRowLimit returns the number of rows to insert per statement for the
+// given number of columns. Metrics exceeding the parameter limit are inserted
+// row by row and left for the database to accept or refuse.
+func insertRowLimit(columns int) int {
+	return max(1, min(maxInsertRows, maxInsertParameters/columns))
+}
+
+// deduplicateRows keeps the last row for each key as databases refuse to
+// update the same row twice within an upsert statement
+func deduplicateRows(rows [][]interface{}, keys int) [][]interface{} {
+	index := make(map[string]int, len(rows))
+	deduplicated := make([][]interface{}, 0, len(rows))
+	for _, values := range rows {
+		var sb strings.Builder
+		for _, v := range values[:keys] {
+			fmt.Fprint(&sb, v)
+			sb.WriteByte(0)
+		}
+		id := sb.String()
+		if i, found := index[id]; found {
+			deduplicated[i] = values
+			continue
+		}
+		index[id] = len(deduplicated)
+		deduplicated = append(deduplicated, values)
+	}
+	return deduplicated
+}
+
 // Convert a DSN possibly using v1 parameters to clickhouse-go v2 format
 func (p *SQL) convertClickHouseDsn() {
 	u, err := url.Parse(p.DataSourceName)
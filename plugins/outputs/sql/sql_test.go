@@ -814,3 +814,101 @@ func TestMysqlEmptyTimestampColumnIntegration(t *testing.T) {
 		}, 10*time.Second, 500*time.Millisecond, fn)
 	}
 }
+
+func TestGenerateInsert(t *testing.T) {
+	columns := []string{"timestamp", "host", "value"}
+	tests := []struct {
+		name     string
+		driver   string
+		upsert   bool
+		keys     int
+		expected string
+	}{
+		{
+			name:     "pgx",
+			driver:   "pgx",
+			keys:     2,
+			expected: `INSERT INTO "metric" ("timestamp","host","value") VALUES($1,$2,$3),($4,$5,$6)`,
+		},
+		{
+			name:     "mysql",
+			driver:   "mysql",
+			keys:     2,
+			expected: `INSERT INTO "metric" ("timestamp","host","value") VALUES(?,?,?),(?,?,?)`,
+		},
+		{
+			name:   "pgx upsert",
+			driver: "pgx",
+			upsert: true,
+			keys:   2,
+			expected: `INSERT INTO "metric" ("timestamp","host","value") VALUES($1,$2,$3),($4,$5,$6)` +
+				` ON CONFLICT ("timestamp","host") DO UPDATE SET "value"=excluded."value"`,
+		},
+		{
+			name:   "sqlite upsert without values",
+			driver: "sqlite",
+			upsert: true,
+			keys:   3,
+			expected: `INSERT INTO "metric" ("timestamp","host","value") VALUES(?,?,?),(?,?,?)` +
+				` ON CONFLICT ("timestamp","host","value") DO NOTHING`,
+		},
+		{
+			name:   "mysql upsert",
+			driver: "mysql",
+			upsert: true,
+			keys:   2,
+			expected: `INSERT INTO "metric" ("timestamp","host","value") VALUES(?,?,?),(?,?,?)` +
+				` ON DUPLICATE KEY UPDATE "value"=VALUES("value")`,
+		},
+		{
+			name:     "clickhouse upsert",
+			driver:   "clickhouse",
+			upsert:   true,
+			keys:     2,
+			expected: `INSERT INTO "metric" ("timestamp","host","value") VALUES(?,?,?),(?,?,?)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &SQL{
+				Driver: tt.driver,
+				Upsert: tt.upsert,
+			}
+			require.Equal(t, tt.expected, plugin.generateInsert("metric", columns, tt.keys, 2))
+		})
+	}
+}
+
+func TestUpsertUnsupportedDriver(t *testing.T) {
+	plugin := &SQL{
+		Driver:         "mssql",
+		DataSourceName: "sqlserver://localhost",
+		Upsert:         true,
+		Log:            testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), `upsert not supported for driver "mssql"`)
+}
+
+func TestDeduplicateRows(t *testing.T) {
+	rows := [][]interface{}{
+		{"ab", "c", 1},
+		{"a", "bc", 2},
+		{"ab", "c", 3},
+	}
+	expected := [][]interface{}{
+		{"ab", "c", 3},
+		{"a", "bc", 2},
+	}
+	require.Equal(t, expected, deduplicateRows(rows, 2))
+}
+
+func TestInsertRowLimit(t *testing.T) {
+	require.Equal(t, maxInsertRows, insertRowLimit(1))
+	require.Equal(t, 2, insertRowLimit(maxInsertParameters/2))
+	require.Equal(t, 1, insertRowLimit(maxInsertParameters))
+
+	// Wide metrics must not stall the insert loop
+	require.Equal(t, 1, insertRowLimit(maxInsertParameters+1))
+	require.Equal(t, 1, insertRowLimit(3*maxInsertParameters))
+}
@@ -4,6 +4,7 @@ package sql
 
 import (
 	gosql "database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -178,3 +181,178 @@ func TestSqliteUpdateScheme(t *testing.T) {
 		sql,
 	)
 }
+
+func TestSqliteBatch(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "db")
+	p := &SQL{
+		Driver:            "sqlite",
+		DataSourceName:    address,
+		Convert:           defaultConvert,
+		TimestampColumn:   "timestamp",
+		ConnectionMaxIdle: 2,
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	// Write more rows than fit into a single statement, interleaving the tables
+	metrics := make([]telegraf.Metric, 0, 2*maxInsertRows+10)
+	for i := range maxInsertRows + 5 {
+		metrics = append(metrics,
+			metric.New("metric_a", map[string]string{"host": "a"}, map[string]interface{}{"value": i}, ts.Add(time.Duration(i)*time.Second)),
+			metric.New("metric_b", map[string]string{}, map[string]interface{}{"value": float64(i)}, ts.Add(time.Duration(i)*time.Second)),
+		)
+	}
+	require.NoError(t, p.Write(metrics))
+
+	db, err := gosql.Open("sqlite", address)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, table := range []string{"metric_a", "metric_b"} {
+		var count, sum int
+		require.NoError(t, db.QueryRow("select count(*), sum(value) from "+table).Scan(&count, &sum))
+		require.Equal(t, maxInsertRows+5, count, table)
+		require.Equal(t, (maxInsertRows+5)*(maxInsertRows+4)/2, sum, table)
+	}
+}
+
+func TestSqliteWideMetric(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "db")
+	p := &SQL{
+		Driver:            "sqlite",
+		DataSourceName:    address,
+		Convert:           defaultConvert,
+		TimestampColumn:   "timestamp",
+		ConnectionMaxIdle: 2,
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	// Metrics with as many columns as parameters allowed in a statement must
+	// be inserted row by row, SQLite does not support more columns
+	fields := make(map[string]interface{}, maxInsertParameters-1)
+	for i := range maxInsertParameters - 1 {
+		fields[fmt.Sprintf("field_%d", i)] = i
+	}
+	metrics := []telegraf.Metric{
+		metric.New("wide", map[string]string{}, fields, ts),
+		metric.New("wide", map[string]string{}, fields, ts.Add(time.Second)),
+	}
+	require.NoError(t, p.Write(metrics))
+
+	db, err := gosql.Open("sqlite", address)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var count int
+	require.NoError(t, db.QueryRow("select count(*) from wide").Scan(&count))
+	require.Equal(t, 2, count)
+}
+
+func TestSqliteUpsert(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "db")
+	p := &SQL{
+		Driver:            "sqlite",
+		DataSourceName:    address,
+		Convert:           defaultConvert,
+		TimestampColumn:   "timestamp",
+		ConnectionMaxIdle: 2,
+		Upsert:            true,
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	// Duplicates within a batch and across batches replace the existing row
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("metric", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, ts),
+		metric.New("metric", map[string]string{"host": "b"}, map[string]interface{}{"value": 2}, ts),
+		metric.New("metric", map[string]string{"host": "a"}, map[string]interface{}{"value": 3}, ts),
+	}))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("metric", map[string]string{"host": "b"}, map[string]interface{}{"value": 4}, ts),
+	}))
+
+	db, err := gosql.Open("sqlite", address)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var sql string
+	require.NoError(t, db.QueryRow("select sql from sqlite_master where name = 'metric'").Scan(&sql))
+	require.Equal(t,
+		`CREATE TABLE "metric"("timestamp" TIMESTAMP,"host" TEXT,"value" INT, PRIMARY KEY ("timestamp","host"))`,
+		sql,
+	)
+
+	rows, err := db.Query("select host, value from metric order by host")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	actual := make(map[string]int)
+	for rows.Next() {
+		var host string
+		var value int
+		require.NoError(t, rows.Scan(&host, &value))
+		actual[host] = value
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, map[string]int{"a": 3, "b": 4}, actual)
+}
+
+func TestSqliteUpsertVaryingTags(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "db")
+	p := &SQL{
+		Driver:              "sqlite",
+		DataSourceName:      address,
+		Convert:             defaultConvert,
+		TimestampColumn:     "timestamp",
+		TableUpdateTemplate: "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+		ConnectionMaxIdle:   2,
+		Upsert:              true,
+		Log:                 testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	// The primary key is created from the first metric, metrics with
+	// additional or missing tags must still be upserted using the key of
+	// the table
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("metric", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, ts),
+	}))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("metric", map[string]string{"host": "a", "region": "eu"}, map[string]interface{}{"value": 2}, ts),
+		metric.New("metric", map[string]string{"region": "us"}, map[string]interface{}{"value": 3}, ts),
+	}))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("metric", map[string]string{"region": "ap"}, map[string]interface{}{"value": 4}, ts),
+	}))
+
+	db, err := gosql.Open("sqlite", address)
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query("select host, coalesce(region, ''), value from metric order by host")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	actual := make(map[string]string)
+	for rows.Next() {
+		var host, region string
+		var value int
+		require.NoError(t, rows.Scan(&host, &region, &value))
+		actual[host] = fmt.Sprintf("%s=%d", region, value)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, map[string]string{"a": "eu=2", "": "ap=4"}, actual)
+}
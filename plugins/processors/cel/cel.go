@@ -0,0 +1,191 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package cel
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type CEL struct {
+	DropExpression string            `toml:"drop_expression"`
+	NameExpression string            `toml:"name_expression"`
+	Tags           map[string]string `toml:"tags"`
+	Fields         map[string]string `toml:"fields"`
+	Log            telegraf.Logger   `toml:"-"`
+
+	drop   cel.Program
+	name   cel.Program
+	tags   []*assignment
+	fields []*assignment
+}
+
+// assignment is a compiled expression computing the value of a tag or field
+type assignment struct {
+	key     string
+	program cel.Program
+}
+
+func (*CEL) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CEL) Init() error {
+	if c.DropExpression == "" && c.NameExpression == "" && len(c.Tags) == 0 && len(c.Fields) == 0 {
+		return errors.New("no expressions configured")
+	}
+
+	env, err := models.NewMetricEnv()
+	if err != nil {
+		return fmt.Errorf("creating environment failed: %w", err)
+	}
+
+	if c.DropExpression != "" {
+		if c.drop, err = compile(env, c.DropExpression, cel.BoolType); err != nil {
+			return fmt.Errorf("compiling drop expression failed: %w", err)
+		}
+	}
+	if c.NameExpression != "" {
+		if c.name, err = compile(env, c.NameExpression, cel.StringType); err != nil {
+			return fmt.Errorf("compiling name expression failed: %w", err)
+		}
+	}
+	if c.tags, err = compileAssignments(env, c.Tags, cel.StringType); err != nil {
+		return fmt.Errorf("compiling tag expression failed: %w", err)
+	}
+	if c.fields, err = compileAssignments(env, c.Fields, nil); err != nil {
+		return fmt.Errorf("compiling field expression failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c *CEL) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		if c.apply(m) {
+			out = append(out, m)
+		} else {
+			m.Drop()
+		}
+	}
+	return out
+}
+
+// apply transforms the metric and returns false if the metric should be
+// dropped. All expressions are evaluated on the incoming metric.
+func (c *CEL) apply(m telegraf.Metric) bool {
+	activation := models.MetricActivation(m)
+
+	if c.drop != nil {
+		result, _, err := c.drop.Eval(activation)
+		if err != nil {
+			c.Log.Errorf("Evaluating drop expression failed: %v", err)
+		} else if drop, ok := result.Value().(bool); ok && drop {
+			return false
+		}
+	}
+
+	var name string
+	if c.name != nil {
+		result, _, err := c.name.Eval(activation)
+		if err != nil {
+			c.Log.Errorf("Evaluating name expression failed: %v", err)
+		} else if v, ok := result.Value().(string); ok {
+			name = v
+		} else {
+			c.Log.Errorf("Invalid type %T for name", result.Value())
+		}
+	}
+
+	tags := make(map[string]string, len(c.tags))
+	for _, a := range c.tags {
+		result, _, err := a.program.Eval(activation)
+		if err != nil {
+			c.Log.Errorf("Evaluating expression for tag %q failed: %v", a.key, err)
+			continue
+		}
+		v, ok := result.Value().(string)
+		if !ok {
+			c.Log.Errorf("Invalid type %T for tag %q", result.Value(), a.key)
+			continue
+		}
+		tags[a.key] = v
+	}
+
+	fields := make(map[string]interface{}, len(c.fields))
+	for _, a := range c.fields {
+		result, _, err := a.program.Eval(activation)
+		if err != nil {
+			c.Log.Errorf("Evaluating expression for field %q failed: %v", a.key, err)
+			continue
+		}
+		switch v := result.Value().(type) {
+		case int64, uint64, float64, string, bool:
+			fields[a.key] = v
+		default:
+			c.Log.Errorf("Invalid type %T for field %q", v, a.key)
+		}
+	}
+
+	// Modify the metric after evaluating all expressions
+	if name != "" {
+		m.SetName(name)
+	}
+	for k, v := range tags {
+		m.AddTag(k, v)
+	}
+	for k, v := range fields {
+		m.AddField(k, v)
+	}
+
+	return true
+}
+
+// compile compiles the expression and checks the result type if given
+func compile(env *cel.Env, expression string, resultType *cel.Type) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if resultType != nil {
+		if t := ast.OutputType(); !t.IsExactType(resultType) && !t.IsExactType(cel.DynType) {
+			return nil, fmt.Errorf("expression needs to return %s but returns %s", resultType, t)
+		}
+	}
+	return env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+}
+
+func compileAssignments(env *cel.Env, expressions map[string]string, resultType *cel.Type) ([]*assignment, error) {
+	keys := make([]string, 0, len(expressions))
+	for k := range expressions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	assignments := make([]*assignment, 0, len(keys))
+	for _, k := range keys {
+		program, err := compile(env, expressions[k], resultType)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", k, err)
+		}
+		assignments = append(assignments, &assignment{key: k, program: program})
+	}
+	return assignments, nil
+}
+
+func init() {
+	processors.Add("cel", func() telegraf.Processor {
+		return &CEL{}
+	})
+}
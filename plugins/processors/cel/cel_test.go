@@ -0,0 +1,215 @@
+package cel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *CEL
+		expected string
+	}{
+		{
+			name:     "no expressions",
+			plugin:   &CEL{},
+			expected: "no expressions configured",
+		},
+		{
+			name:     "invalid drop expression",
+			plugin:   &CEL{DropExpression: "foo("},
+			expected: "compiling drop expression failed",
+		},
+		{
+			name:     "non-boolean drop expression",
+			plugin:   &CEL{DropExpression: `"foo"`},
+			expected: "expression needs to return bool",
+		},
+		{
+			name:     "non-string name expression",
+			plugin:   &CEL{NameExpression: "42"},
+			expected: "expression needs to return string",
+		},
+		{
+			name:     "non-string tag expression",
+			plugin:   &CEL{Tags: map[string]string{"level": "42"}},
+			expected: `compiling tag expression failed: "level"`,
+		},
+		{
+			name:     "invalid field expression",
+			plugin:   &CEL{Fields: map[string]string{"value": "fields.value +"}},
+			expected: `compiling field expression failed: "value"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	input := []telegraf.Metric{
+		metric.New(
+			"temperature",
+			map[string]string{"source": "sensor1", "env": "prod"},
+			map[string]interface{}{"value": 95.0},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"temperature",
+			map[string]string{"source": "sensor2", "env": "test"},
+			map[string]interface{}{"value": 20.0},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"humidity",
+			map[string]string{"source": "sensor1", "env": "prod"},
+			map[string]interface{}{"value": int64(42)},
+			time.Unix(0, 0),
+		),
+	}
+
+	tests := []struct {
+		name     string
+		plugin   *CEL
+		expected []telegraf.Metric
+	}{
+		{
+			name:   "drop",
+			plugin: &CEL{DropExpression: `tags.env == "test"`},
+			expected: []telegraf.Metric{
+				metric.New(
+					"temperature",
+					map[string]string{"source": "sensor1", "env": "prod"},
+					map[string]interface{}{"value": 95.0},
+					time.Unix(0, 0),
+				),
+				metric.New(
+					"humidity",
+					map[string]string{"source": "sensor1", "env": "prod"},
+					map[string]interface{}{"value": int64(42)},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name:   "rename",
+			plugin: &CEL{NameExpression: `tags.env + "_" + name`},
+			expected: []telegraf.Metric{
+				metric.New(
+					"prod_temperature",
+					map[string]string{"source": "sensor1", "env": "prod"},
+					map[string]interface{}{"value": 95.0},
+					time.Unix(0, 0),
+				),
+				metric.New(
+					"test_temperature",
+					map[string]string{"source": "sensor2", "env": "test"},
+					map[string]interface{}{"value": 20.0},
+					time.Unix(0, 0),
+				),
+				metric.New(
+					"prod_humidity",
+					map[string]string{"source": "sensor1", "env": "prod"},
+					map[string]interface{}{"value": int64(42)},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "tags and fields",
+			plugin: &CEL{
+				Tags: map[string]string{
+					"level":  `double(fields.value) > 90.0 ? "critical" : "ok"`,
+					"source": `tags.source.upperAscii()`,
+				},
+				Fields: map[string]string{
+					"value":   `double(fields.value) / 10.0`,
+					"raw":     `fields.value`,
+					"is_prod": `tags.env == "prod"`,
+				},
+			},
+			expected: []telegraf.Metric{
+				metric.New(
+					"temperature",
+					map[string]string{"source": "SENSOR1", "env": "prod", "level": "critical"},
+					map[string]interface{}{"value": 9.5, "raw": 95.0, "is_prod": true},
+					time.Unix(0, 0),
+				),
+				metric.New(
+					"temperature",
+					map[string]string{"source": "SENSOR2", "env": "test", "level": "ok"},
+					map[string]interface{}{"value": 2.0, "raw": 20.0, "is_prod": false},
+					time.Unix(0, 0),
+				),
+				metric.New(
+					"humidity",
+					map[string]string{"source": "SENSOR1", "env": "prod", "level": "ok"},
+					map[string]interface{}{"value": 4.2, "raw": int64(42), "is_prod": true},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "evaluation errors keep metric",
+			plugin: &CEL{
+				DropExpression: `tags.missing == "foo"`,
+				Fields:         map[string]string{"missing": `fields.missing`},
+			},
+			expected: input,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.NoError(t, tt.plugin.Init())
+
+			in := make([]telegraf.Metric, 0, len(input))
+			for _, m := range input {
+				in = append(in, m.Copy())
+			}
+			actual := tt.plugin.Apply(in...)
+			testutil.RequireMetricsEqual(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestTracking(t *testing.T) {
+	input := []telegraf.Metric{
+		metric.New("keep", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0)),
+		metric.New("drop", map[string]string{}, map[string]interface{}{"value": 23}, time.Unix(0, 0)),
+	}
+
+	var delivered []telegraf.DeliveryInfo
+	notify := func(di telegraf.DeliveryInfo) {
+		delivered = append(delivered, di)
+	}
+	tracked := make([]telegraf.Metric, 0, len(input))
+	for _, m := range input {
+		tm, _ := metric.WithTracking(m, notify)
+		tracked = append(tracked, tm)
+	}
+
+	plugin := &CEL{
+		DropExpression: `name == "drop"`,
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	actual := plugin.Apply(tracked...)
+	require.Len(t, actual, 1)
+	require.Len(t, delivered, 1)
+	for _, m := range actual {
+		m.Accept()
+	}
+	require.Len(t, delivered, 2)
+}
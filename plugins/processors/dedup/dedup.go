@@ -4,6 +4,7 @@ package dedup
 import (
 	_ "embed"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -21,6 +22,8 @@ type Dedup struct {
 	FlushTime     time.Time
 	Cache         map[uint64]telegraf.Metric
 	Log           telegraf.Logger `toml:"-"`
+
+	sync.Mutex
 }
 
 // Remove expired items from cache
@@ -51,6 +54,9 @@ func (*Dedup) SampleConfig() string {
 
 // main processing method
 func (d *Dedup) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	d.Lock()
+	defer d.Unlock()
+
 	idx := 0
 	for _, metric := range metrics {
 		id := metric.HashID()
@@ -122,6 +128,9 @@ func (d *Dedup) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
 }
 
 func (d *Dedup) GetState() interface{} {
+	d.Lock()
+	defer d.Unlock()
+
 	s := &serializers_influx.Serializer{}
 	v := make([]telegraf.Metric, 0, len(d.Cache))
 	for _, value := range d.Cache {
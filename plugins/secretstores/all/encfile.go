@@ -0,0 +1,5 @@
+//go:build !custom || secretstores || secretstores.encfile
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/secretstores/encfile" // register plugin
@@ -0,0 +1,77 @@
+package encfile
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	fileVersion = 1
+	saltSize    = 16
+	keySize     = 32
+	nonceSize   = 24
+
+	// Recommended scrypt parameters for interactive logins
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+)
+
+var errDecrypt = errors.New("decrypting secret failed, wrong password or corrupted value")
+
+// deriveKey derives the encryption key from the password and the base64
+// encoded salt using scrypt
+func deriveKey(passwd []byte, salt string) ([]byte, error) {
+	s, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, fmt.Errorf("decoding salt failed: %w", err)
+	}
+	if len(s) < saltSize {
+		return nil, fmt.Errorf("salt too short, expected at least %d bytes", saltSize)
+	}
+	return scrypt.Key(passwd, s, scryptN, scryptR, scryptP, keySize)
+}
+
+// encrypt seals the value with a random nonce using NaCl secretbox and returns
+// the base64 encoded nonce followed by the sealed value
+func encrypt(key, value []byte) (string, error) {
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", fmt.Errorf("generating nonce failed: %w", err)
+	}
+
+	var k [keySize]byte
+	copy(k[:], key)
+	sealed := secretbox.Seal(nonce[:], value, &nonce, &k)
+	return encode(sealed), nil
+}
+
+// decrypt opens a value sealed by encrypt
+func decrypt(key []byte, value string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("decoding secret failed: %w", err)
+	}
+	if len(sealed) < nonceSize+secretbox.Overhead {
+		return nil, errDecrypt
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], sealed[:nonceSize])
+	var k [keySize]byte
+	copy(k[:], key)
+	plain, ok := secretbox.Open(nil, sealed[nonceSize:], &nonce, &k)
+	if !ok {
+		return nil, errDecrypt
+	}
+	return plain, nil
+}
+
+func encode(buf []byte) string {
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,342 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package encfile
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/99designs/keyring"
+	"github.com/awnumar/memguard"
+	"gopkg.in/yaml.v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type EncFile struct {
+	ID       string          `toml:"id"`
+	Path     string          `toml:"path"`
+	Password config.Secret   `toml:"password"`
+	Log      telegraf.Logger `toml:"-"`
+
+	// The password is kept to derive the key for a new salt of the file
+	passwd  *memguard.Enclave
+	key     []byte
+	format  string
+	content *fileContent
+	modTime time.Time
+	size    int64
+	sync.Mutex
+}
+
+// fileContent is the content of the secret file. The secret keys are stored
+// in plain text to allow reviewing changes while the values are encrypted
+// individually.
+type fileContent struct {
+	Version int               `json:"version" yaml:"version"`
+	Salt    string            `json:"salt" yaml:"salt"`
+	Secrets map[string]string `json:"secrets" yaml:"secrets"`
+}
+
+func (*EncFile) SampleConfig() string {
+	return sampleConfig
+}
+
+// Init initializes all internals of the secret-store
+func (e *EncFile) Init() error {
+	defer e.Password.Destroy()
+
+	if e.ID == "" {
+		return errors.New("id missing")
+	}
+	if e.Path == "" {
+		return errors.New("path missing")
+	}
+
+	switch strings.ToLower(filepath.Ext(e.Path)) {
+	case ".yaml", ".yml":
+		e.format = "yaml"
+	default:
+		e.format = "json"
+	}
+
+	passwd, err := e.password()
+	if err != nil {
+		return err
+	}
+	e.passwd = memguard.NewEnclave(passwd)
+
+	// Use the salt of an existing file or create a new one
+	content, err := e.read()
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("generating salt failed: %w", err)
+		}
+		content = &fileContent{
+			Version: fileVersion,
+			Salt:    encode(salt),
+			Secrets: make(map[string]string),
+		}
+	}
+	e.key, err = e.deriveKey(content.Salt)
+	if err != nil {
+		return err
+	}
+
+	// Check the password by decrypting all existing secrets
+	if err := verify(e.key, content); err != nil {
+		return err
+	}
+	e.content = content
+
+	return nil
+}
+
+// Get searches for the given key and return the secret
+func (e *EncFile) Get(key string) ([]byte, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.refresh()
+
+	value, found := e.content.Secrets[key]
+	if !found {
+		return nil, fmt.Errorf("secret %q not found", key)
+	}
+	return decrypt(e.key, value)
+}
+
+// Set sets the given secret for the given key
+func (e *EncFile) Set(key, value string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	if err := e.reload(); err != nil {
+		return err
+	}
+
+	encrypted, err := encrypt(e.key, []byte(value))
+	if err != nil {
+		return err
+	}
+	e.content.Secrets[key] = encrypted
+
+	return e.write()
+}
+
+// List lists all known secret keys
+func (e *EncFile) List() ([]string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.refresh()
+
+	keys := make([]string, 0, len(e.content.Secrets))
+	for k := range e.content.Secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// GetResolver returns a function to resolve the given key. The resolver is
+// dynamic as the secrets are reloaded when the file changes.
+func (e *EncFile) GetResolver(key string) (telegraf.ResolveFunc, error) {
+	resolver := func() ([]byte, bool, error) {
+		s, err := e.Get(key)
+		return s, true, err
+	}
+	return resolver, nil
+}
+
+func (e *EncFile) password() ([]byte, error) {
+	if !e.Password.Empty() {
+		passwd, err := e.Password.Get()
+		if err != nil {
+			return nil, fmt.Errorf("getting password failed: %w", err)
+		}
+		defer passwd.Destroy()
+		return []byte(passwd.String()), nil
+	}
+	if !config.Password.Empty() {
+		passwd, err := config.Password.Get()
+		if err != nil {
+			return nil, fmt.Errorf("getting global password failed: %w", err)
+		}
+		defer passwd.Destroy()
+		return []byte(passwd.String()), nil
+	}
+
+	passwd, err := keyring.TerminalPrompt(fmt.Sprintf("Enter password for secret-store %q", e.ID))
+	if err != nil {
+		return nil, fmt.Errorf("reading password failed: %w", err)
+	}
+	return []byte(passwd), nil
+}
+
+// refresh reloads the file but keeps serving the current secrets if the file
+// cannot be used, e.g. while being written non-atomically
+func (e *EncFile) refresh() {
+	if err := e.reload(); err != nil {
+		e.Log.Warnf("Reloading secret file %q failed, using the previous secrets: %v", e.Path, err)
+	}
+}
+
+// reload reads the file again if it was modified since the last read. A
+// failing file is not read again before it is modified.
+func (e *EncFile) reload() error {
+	stat, err := os.Stat(e.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stat.ModTime().Equal(e.modTime) && stat.Size() == e.size {
+		return nil
+	}
+
+	content, err := e.read()
+	if err == nil && content == nil {
+		return nil
+	}
+
+	// Derive the key for a file recreated with a new salt
+	key := e.key
+	if err == nil && content.Salt != e.content.Salt {
+		key, err = e.deriveKey(content.Salt)
+	}
+	if err == nil {
+		err = verify(key, content)
+	}
+	if err != nil {
+		e.modTime = stat.ModTime()
+		e.size = stat.Size()
+		return err
+	}
+	e.key = key
+	e.content = content
+	return nil
+}
+
+// deriveKey derives the encryption key for the given salt from the password
+func (e *EncFile) deriveKey(salt string) ([]byte, error) {
+	passwd, err := e.passwd.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening password failed: %w", err)
+	}
+	defer passwd.Destroy()
+	return deriveKey(passwd.Bytes(), salt)
+}
+
+// read reads and decodes the secret file, returning nil if the file does
+// not exist yet
+func (e *EncFile) read() (*fileContent, error) {
+	stat, err := os.Stat(e.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := os.ReadFile(e.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var content fileContent
+	if e.format == "yaml" {
+		err = yaml.Unmarshal(buf, &content)
+	} else {
+		err = json.Unmarshal(buf, &content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding secret file %q failed: %w", e.Path, err)
+	}
+	if content.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported version %d of secret file %q", content.Version, e.Path)
+	}
+	if content.Secrets == nil {
+		content.Secrets = make(map[string]string)
+	}
+
+	e.modTime = stat.ModTime()
+	e.size = stat.Size()
+	return &content, nil
+}
+
+// write atomically replaces the secret file with the current content
+func (e *EncFile) write() error {
+	var buf []byte
+	var err error
+	if e.format == "yaml" {
+		buf, err = yaml.Marshal(e.content)
+	} else {
+		buf, err = json.MarshalIndent(e.content, "", "  ")
+		buf = append(buf, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("encoding secret file failed: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(e.Path), filepath.Base(e.Path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		//nolint:errcheck // Ignore the error as writing already failed
+		tmp.Close()
+		return fmt.Errorf("writing temporary file failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary file failed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), e.Path); err != nil {
+		return fmt.Errorf("replacing secret file failed: %w", err)
+	}
+
+	stat, err := os.Stat(e.Path)
+	if err != nil {
+		return err
+	}
+	e.modTime = stat.ModTime()
+	e.size = stat.Size()
+	return nil
+}
+
+// verify checks that all secrets of the content can be decrypted with the key
+func verify(key []byte, content *fileContent) error {
+	for k, v := range content.Secrets {
+		if _, err := decrypt(key, v); err != nil {
+			return fmt.Errorf("secret %q: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// Register the secret-store on load.
+func init() {
+	secretstores.Add("encfile", func(id string) telegraf.SecretStore {
+		return &EncFile{ID: id}
+	})
+}
@@ -0,0 +1,210 @@
+package encfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &EncFile{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *EncFile
+		expected string
+	}{
+		{
+			name:     "invalid id",
+			plugin:   &EncFile{},
+			expected: "id missing",
+		},
+		{
+			name: "missing path",
+			plugin: &EncFile{
+				ID: "test",
+			},
+			expected: "path missing",
+		},
+		{
+			name: "invalid password",
+			plugin: &EncFile{
+				ID:       "test",
+				Path:     filepath.Join(t.TempDir(), "secrets.json"),
+				Password: config.NewSecret([]byte("@{unresolvable:secret}")),
+			},
+			expected: "getting password failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plugin.Init()
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestSetListGet(t *testing.T) {
+	secrets := map[string]string{
+		"a secret":    "I won't tell",
+		"another one": "secret",
+		"foo":         "bar",
+	}
+
+	for _, filename := range []string{"secrets.json", "secrets.yaml"} {
+		t.Run(filename, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), filename)
+
+			plugin := &EncFile{
+				ID:       "test",
+				Path:     path,
+				Password: config.NewSecret([]byte("test")),
+			}
+			require.NoError(t, plugin.Init())
+
+			for k, v := range secrets {
+				require.NoError(t, plugin.Set(k, v))
+			}
+
+			// The values must not be stored in plain text
+			buf, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Contains(t, string(buf), "another one")
+			require.NotContains(t, string(buf), "I won't tell")
+
+			// Check the secrets using a new instance reading the file
+			plugin = &EncFile{
+				ID:       "test",
+				Path:     path,
+				Password: config.NewSecret([]byte("test")),
+			}
+			require.NoError(t, plugin.Init())
+
+			keys, err := plugin.List()
+			require.NoError(t, err)
+			require.Equal(t, []string{"a secret", "another one", "foo"}, keys)
+
+			for k, expected := range secrets {
+				value, err := plugin.Get(k)
+				require.NoError(t, err)
+				require.Equal(t, expected, string(value))
+			}
+
+			_, err = plugin.Get("unknown")
+			require.ErrorContains(t, err, `secret "unknown" not found`)
+		})
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+
+	plugin := &EncFile{
+		ID:       "test",
+		Path:     path,
+		Password: config.NewSecret([]byte("test")),
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Set("foo", "bar"))
+
+	plugin = &EncFile{
+		ID:       "test",
+		Path:     path,
+		Password: config.NewSecret([]byte("wrong")),
+	}
+	require.ErrorContains(t, plugin.Init(), "wrong password")
+}
+
+func TestReloadOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+
+	plugin := &EncFile{
+		ID:       "test",
+		Path:     path,
+		Password: config.NewSecret([]byte("test")),
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Set("token", "old"))
+
+	resolver, err := plugin.GetResolver("token")
+	require.NoError(t, err)
+	value, dynamic, err := resolver()
+	require.NoError(t, err)
+	require.True(t, dynamic)
+	require.Equal(t, "old", string(value))
+
+	// Rotate the secret using another instance, e.g. the secrets command
+	other := &EncFile{
+		ID:       "test",
+		Path:     path,
+		Password: config.NewSecret([]byte("test")),
+	}
+	require.NoError(t, other.Init())
+	require.NoError(t, other.Set("token", "rotated"))
+	require.NoError(t, other.Set("new", "secret"))
+
+	value, _, err = resolver()
+	require.NoError(t, err)
+	require.Equal(t, "rotated", string(value))
+
+	keys, err := plugin.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"new", "token"}, keys)
+}
+
+func TestReloadNewSaltAndBrokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+
+	logger := &testutil.CaptureLogger{}
+	plugin := &EncFile{
+		ID:       "test",
+		Path:     path,
+		Password: config.NewSecret([]byte("test")),
+		Log:      logger,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Set("token", "old"))
+
+	// Recreate the file with a new salt using the same password
+	require.NoError(t, os.Remove(path))
+	other := &EncFile{
+		ID:       "test",
+		Path:     path,
+		Password: config.NewSecret([]byte("test")),
+	}
+	require.NoError(t, other.Init())
+	require.NoError(t, other.Set("token", "recreated"))
+	require.NotEqual(t, plugin.content.Salt, other.content.Salt)
+
+	value, err := plugin.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "recreated", string(value))
+
+	// A partially written file must not affect the secrets in use
+	buf, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, buf[:len(buf)/2], 0600))
+
+	value, err = plugin.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "recreated", string(value))
+	value, err = plugin.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "recreated", string(value))
+	require.Len(t, logger.Warnings(), 1)
+
+	// The completed file is used again
+	require.NoError(t, os.WriteFile(path, buf, 0600))
+	value, err = plugin.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "recreated", string(value))
+	require.Len(t, logger.Warnings(), 1)
+}
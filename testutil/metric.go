@@ -11,112 +11,21 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil/metricdiff"
 )
 
-type metricDiff struct {
-	Measurement string
-	Tags        []*telegraf.Tag
-	Fields      []*telegraf.Field
-	Type        telegraf.ValueType
-	Time        time.Time
-}
+type metricDiff = metricdiff.Metric
 
 type helper interface {
 	Helper()
 }
 
 func lessFunc(lhs, rhs *metricDiff) bool {
-	if lhs.Measurement != rhs.Measurement {
-		return lhs.Measurement < rhs.Measurement
-	}
-
-	for i := 0; ; i++ {
-		if i >= len(lhs.Tags) && i >= len(rhs.Tags) {
-			break
-		} else if i >= len(lhs.Tags) {
-			return true
-		} else if i >= len(rhs.Tags) {
-			return false
-		}
-
-		if lhs.Tags[i].Key != rhs.Tags[i].Key {
-			return lhs.Tags[i].Key < rhs.Tags[i].Key
-		}
-		if lhs.Tags[i].Value != rhs.Tags[i].Value {
-			return lhs.Tags[i].Value < rhs.Tags[i].Value
-		}
-	}
-
-	for i := 0; ; i++ {
-		if i >= len(lhs.Fields) && i >= len(rhs.Fields) {
-			break
-		} else if i >= len(lhs.Fields) {
-			return true
-		} else if i >= len(rhs.Fields) {
-			return false
-		}
-
-		if lhs.Fields[i].Key != rhs.Fields[i].Key {
-			return lhs.Fields[i].Key < rhs.Fields[i].Key
-		}
-
-		if lhs.Fields[i].Value != rhs.Fields[i].Value {
-			ltype := reflect.TypeOf(lhs.Fields[i].Value)
-			rtype := reflect.TypeOf(rhs.Fields[i].Value)
-
-			if ltype.Kind() != rtype.Kind() {
-				return ltype.Kind() < rtype.Kind()
-			}
-
-			switch v := lhs.Fields[i].Value.(type) {
-			case int64:
-				return v < rhs.Fields[i].Value.(int64)
-			case uint64:
-				return v < rhs.Fields[i].Value.(uint64)
-			case float64:
-				return v < rhs.Fields[i].Value.(float64)
-			case string:
-				return v < rhs.Fields[i].Value.(string)
-			case bool:
-				return !v
-			default:
-				panic("unknown type")
-			}
-		}
-	}
-
-	if lhs.Type != rhs.Type {
-		return lhs.Type < rhs.Type
-	}
-
-	if lhs.Time.UnixNano() != rhs.Time.UnixNano() {
-		return lhs.Time.UnixNano() < rhs.Time.UnixNano()
-	}
-
-	return false
+	return metricdiff.Less(lhs, rhs)
 }
 
 func newMetricDiff(telegrafMetric telegraf.Metric) *metricDiff {
-	if telegrafMetric == nil {
-		return nil
-	}
-
-	m := &metricDiff{}
-	m.Measurement = telegrafMetric.Name()
-
-	m.Tags = append(m.Tags, telegrafMetric.TagList()...)
-	sort.Slice(m.Tags, func(i, j int) bool {
-		return m.Tags[i].Key < m.Tags[j].Key
-	})
-
-	m.Fields = append(m.Fields, telegrafMetric.FieldList()...)
-	sort.Slice(m.Fields, func(i, j int) bool {
-		return m.Fields[i].Key < m.Fields[j].Key
-	})
-
-	m.Type = telegrafMetric.Type()
-	m.Time = telegrafMetric.Time()
-	return m
+	return metricdiff.New(telegrafMetric)
 }
 
 func newMetricStructureDiff(telegrafMetric telegraf.Metric) *metricDiff {
@@ -150,46 +59,28 @@ func newMetricStructureDiff(telegrafMetric telegraf.Metric) *metricDiff {
 
 // SortMetrics enables sorting metrics before comparison.
 func SortMetrics() cmp.Option {
-	return cmpopts.SortSlices(lessFunc)
+	return metricdiff.SortMetrics()
 }
 
 // IgnoreTime disables comparison of timestamp.
 func IgnoreTime() cmp.Option {
-	return cmpopts.IgnoreFields(metricDiff{}, "Time")
+	return metricdiff.IgnoreTime()
 }
 
 func IgnoreType() cmp.Option {
-	return cmpopts.IgnoreFields(metricDiff{}, "Type")
+	return metricdiff.IgnoreType()
 }
 
 // IgnoreFields disables comparison of the fields with the given names.
 // The field-names are case-sensitive!
 func IgnoreFields(names ...string) cmp.Option {
-	return cmpopts.IgnoreSliceElements(
-		func(f *telegraf.Field) bool {
-			for _, n := range names {
-				if f.Key == n {
-					return true
-				}
-			}
-			return false
-		},
-	)
+	return metricdiff.IgnoreFields(names...)
 }
 
 // IgnoreTags disables comparison of the tags with the given names.
 // The tag-names are case-sensitive!
 func IgnoreTags(names ...string) cmp.Option {
-	return cmpopts.IgnoreSliceElements(
-		func(f *telegraf.Tag) bool {
-			for _, n := range names {
-				if f.Key == n {
-					return true
-				}
-			}
-			return false
-		},
-	)
+	return metricdiff.IgnoreTags(names...)
 }
 
 // MetricEqual returns true if the metrics are equal.
@@ -234,17 +125,7 @@ func RequireMetricsEqual(t testing.TB, expected, actual []telegraf.Metric, opts
 		x.Helper()
 	}
 
-	lhs := make([]*metricDiff, 0, len(expected))
-	for _, m := range expected {
-		lhs = append(lhs, newMetricDiff(m))
-	}
-	rhs := make([]*metricDiff, 0, len(actual))
-	for _, m := range actual {
-		rhs = append(rhs, newMetricDiff(m))
-	}
-
-	opts = append(opts, cmpopts.EquateNaNs())
-	if diff := cmp.Diff(lhs, rhs, opts...); diff != "" {
+	if diff := metricdiff.Diff(expected, actual, opts...); diff != "" {
 		t.Fatalf("[]telegraf.Metric\n--- expected\n+++ actual\n%s", diff)
 	}
 }
@@ -0,0 +1,180 @@
+// Package metricdiff provides the comparison of metrics used by the test
+// utilities, e.g. testutil.RequireMetricsEqual, without depending on the
+// testing infrastructure.
+package metricdiff
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Metric is the representation of a metric used for comparison
+type Metric struct {
+	Measurement string
+	Tags        []*telegraf.Tag
+	Fields      []*telegraf.Field
+	Type        telegraf.ValueType
+	Time        time.Time
+}
+
+// Less defines an order of metrics used for sorting
+func Less(lhs, rhs *Metric) bool {
+	if lhs.Measurement != rhs.Measurement {
+		return lhs.Measurement < rhs.Measurement
+	}
+
+	for i := 0; ; i++ {
+		if i >= len(lhs.Tags) && i >= len(rhs.Tags) {
+			break
+		} else if i >= len(lhs.Tags) {
+			return true
+		} else if i >= len(rhs.Tags) {
+			return false
+		}
+
+		if lhs.Tags[i].Key != rhs.Tags[i].Key {
+			return lhs.Tags[i].Key < rhs.Tags[i].Key
+		}
+		if lhs.Tags[i].Value != rhs.Tags[i].Value {
+			return lhs.Tags[i].Value < rhs.Tags[i].Value
+		}
+	}
+
+	for i := 0; ; i++ {
+		if i >= len(lhs.Fields) && i >= len(rhs.Fields) {
+			break
+		} else if i >= len(lhs.Fields) {
+			return true
+		} else if i >= len(rhs.Fields) {
+			return false
+		}
+
+		if lhs.Fields[i].Key != rhs.Fields[i].Key {
+			return lhs.Fields[i].Key < rhs.Fields[i].Key
+		}
+
+		if lhs.Fields[i].Value != rhs.Fields[i].Value {
+			ltype := reflect.TypeOf(lhs.Fields[i].Value)
+			rtype := reflect.TypeOf(rhs.Fields[i].Value)
+
+			if ltype.Kind() != rtype.Kind() {
+				return ltype.Kind() < rtype.Kind()
+			}
+
+			switch v := lhs.Fields[i].Value.(type) {
+			case int64:
+				return v < rhs.Fields[i].Value.(int64)
+			case uint64:
+				return v < rhs.Fields[i].Value.(uint64)
+			case float64:
+				return v < rhs.Fields[i].Value.(float64)
+			case string:
+				return v < rhs.Fields[i].Value.(string)
+			case bool:
+				return !v
+			default:
+				panic("unknown type")
+			}
+		}
+	}
+
+	if lhs.Type != rhs.Type {
+		return lhs.Type < rhs.Type
+	}
+
+	if lhs.Time.UnixNano() != rhs.Time.UnixNano() {
+		return lhs.Time.UnixNano() < rhs.Time.UnixNano()
+	}
+
+	return false
+}
+
+// New returns the representation of the given metric used for comparison
+func New(telegrafMetric telegraf.Metric) *Metric {
+	if telegrafMetric == nil {
+		return nil
+	}
+
+	m := &Metric{}
+	m.Measurement = telegrafMetric.Name()
+
+	m.Tags = append(m.Tags, telegrafMetric.TagList()...)
+	sort.Slice(m.Tags, func(i, j int) bool {
+		return m.Tags[i].Key < m.Tags[j].Key
+	})
+
+	m.Fields = append(m.Fields, telegrafMetric.FieldList()...)
+	sort.Slice(m.Fields, func(i, j int) bool {
+		return m.Fields[i].Key < m.Fields[j].Key
+	})
+
+	m.Type = telegrafMetric.Type()
+	m.Time = telegrafMetric.Time()
+	return m
+}
+
+// SortMetrics enables sorting metrics before comparison.
+func SortMetrics() cmp.Option {
+	return cmpopts.SortSlices(Less)
+}
+
+// IgnoreTime disables comparison of timestamp.
+func IgnoreTime() cmp.Option {
+	return cmpopts.IgnoreFields(Metric{}, "Time")
+}
+
+func IgnoreType() cmp.Option {
+	return cmpopts.IgnoreFields(Metric{}, "Type")
+}
+
+// IgnoreFields disables comparison of the fields with the given names.
+// The field-names are case-sensitive!
+func IgnoreFields(names ...string) cmp.Option {
+	return cmpopts.IgnoreSliceElements(
+		func(f *telegraf.Field) bool {
+			for _, n := range names {
+				if f.Key == n {
+					return true
+				}
+			}
+			return false
+		},
+	)
+}
+
+// IgnoreTags disables comparison of the tags with the given names.
+// The tag-names are case-sensitive!
+func IgnoreTags(names ...string) cmp.Option {
+	return cmpopts.IgnoreSliceElements(
+		func(f *telegraf.Tag) bool {
+			for _, n := range names {
+				if f.Key == n {
+					return true
+				}
+			}
+			return false
+		},
+	)
+}
+
+// Diff returns a human-readable report of the differences between the
+// expected and actual metrics or an empty string if the metrics are equal.
+func Diff(expected, actual []telegraf.Metric, opts ...cmp.Option) string {
+	lhs := make([]*Metric, 0, len(expected))
+	for _, m := range expected {
+		lhs = append(lhs, New(m))
+	}
+	rhs := make([]*Metric, 0, len(actual))
+	for _, m := range actual {
+		rhs = append(rhs, New(m))
+	}
+
+	opts = append(opts, cmpopts.EquateNaNs())
+	return cmp.Diff(lhs, rhs, opts...)
+}